@@ -0,0 +1,28 @@
+//go:build !purego
+
+package cmac
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/cpu"
+)
+
+// hasAES reports whether crypto/aes uses hardware instructions, mirroring
+// the checks the standard library makes before selecting its assembly.
+var hasAES = func() bool {
+	switch runtime.GOARCH {
+	case "amd64":
+		return cpu.X86.HasAES && cpu.X86.HasSSE41 && cpu.X86.HasSSSE3
+	case "arm64":
+		return cpu.ARM64.HasAES
+	case "s390x":
+		return cpu.S390X.HasAES && cpu.S390X.HasAESCBC
+	case "ppc64", "ppc64le":
+		// Go requires POWER8 on ppc64, so the assembly is always used,
+		// short of the internal GODEBUG setting that turns it off.
+		return true
+	default:
+		return false
+	}
+}()
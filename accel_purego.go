@@ -0,0 +1,6 @@
+//go:build purego
+
+package cmac
+
+// The standard library's own purego build also avoids its AES assembly.
+const hasAES = false
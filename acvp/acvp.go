@@ -0,0 +1,200 @@
+// Package acvp runs NIST ACVP vector sets for CMAC-AES and CMAC-TDES
+// against package cmac and produces the matching response, as needed for
+// CAVP validation through the ACVP server or its demo environment.
+//
+// Both the bare vector set object and the [{"acvVersion": ...}, {...}]
+// array served by ACVP are accepted; responses are produced in the same
+// shape as the prompt.
+package acvp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/joekir/cmac"
+)
+
+// Version is the ACVP protocol version written in array responses.
+const Version = "1.0"
+
+// Test is one test case of a prompt. Fields are hex encoded as in ACVP.
+type Test struct {
+	TcID    int    `json:"tcId"`
+	Key     string `json:"key,omitempty"`
+	Key1    string `json:"key1,omitempty"`
+	Key2    string `json:"key2,omitempty"`
+	Key3    string `json:"key3,omitempty"`
+	Message string `json:"message"`
+	MAC     string `json:"mac,omitempty"`
+}
+
+// TestGroup is a group of test cases sharing parameters. Lengths are in
+// bits.
+type TestGroup struct {
+	TgID      int    `json:"tgId"`
+	TestType  string `json:"testType"`
+	Direction string `json:"direction"`
+	KeyLen    int    `json:"keyLen,omitempty"`
+	KeyingOpt int    `json:"keyingOption,omitempty"`
+	MsgLen    int    `json:"msgLen"`
+	MACLen    int    `json:"macLen"`
+	Tests     []Test `json:"tests"`
+}
+
+// VectorSet is a CMAC prompt.
+type VectorSet struct {
+	VsID       int         `json:"vsId"`
+	Algorithm  string      `json:"algorithm"`
+	Revision   string      `json:"revision"`
+	IsSample   bool        `json:"isSample,omitempty"`
+	TestGroups []TestGroup `json:"testGroups"`
+}
+
+// TestResult is the response to one test case: the MAC for generation
+// tests, or whether the MAC verified for verification tests.
+type TestResult struct {
+	TcID       int    `json:"tcId"`
+	MAC        string `json:"mac,omitempty"`
+	TestPassed *bool  `json:"testPassed,omitempty"`
+}
+
+// GroupResult holds the responses of one test group.
+type GroupResult struct {
+	TgID  int          `json:"tgId"`
+	Tests []TestResult `json:"tests"`
+}
+
+// Response is the response to a vector set.
+type Response struct {
+	VsID       int           `json:"vsId"`
+	Algorithm  string        `json:"algorithm"`
+	Revision   string        `json:"revision"`
+	TestGroups []GroupResult `json:"testGroups"`
+}
+
+// Parse decodes a prompt in either of its two shapes and reports whether
+// it was wrapped in an array.
+func Parse(prompt []byte) (vs *VectorSet, wrapped bool, err error) {
+	prompt = bytes.TrimSpace(prompt)
+	if len(prompt) > 0 && prompt[0] == '[' {
+		var parts []json.RawMessage
+		if err := json.Unmarshal(prompt, &parts); err != nil {
+			return nil, false, err
+		}
+		if len(parts) != 2 {
+			return nil, false, errors.New("acvp: expected version and vector set")
+		}
+		prompt, wrapped = parts[1], true
+	}
+	vs = new(VectorSet)
+	if err := json.Unmarshal(prompt, vs); err != nil {
+		return nil, false, err
+	}
+	return vs, wrapped, nil
+}
+
+// Run computes the response to vs.
+func Run(vs *VectorSet) (*Response, error) {
+	switch vs.Algorithm {
+	case "CMAC-AES", "CMAC-TDES":
+	default:
+		return nil, fmt.Errorf("acvp: unsupported algorithm %q", vs.Algorithm)
+	}
+	r := &Response{VsID: vs.VsID, Algorithm: vs.Algorithm, Revision: vs.Revision}
+	for _, g := range vs.TestGroups {
+		gr, err := runGroup(vs.Algorithm, &g)
+		if err != nil {
+			return nil, fmt.Errorf("acvp: group %d: %v", g.TgID, err)
+		}
+		r.TestGroups = append(r.TestGroups, gr)
+	}
+	return r, nil
+}
+
+func runGroup(alg string, g *TestGroup) (GroupResult, error) {
+	gr := GroupResult{TgID: g.TgID}
+	if g.Direction != "gen" && g.Direction != "ver" {
+		return gr, fmt.Errorf("unsupported direction %q", g.Direction)
+	}
+	if g.MACLen <= 0 || g.MACLen%8 != 0 {
+		return gr, fmt.Errorf("unsupported MAC length %d", g.MACLen)
+	}
+	for _, t := range g.Tests {
+		c, err := newCipher(alg, &t)
+		if err != nil {
+			return gr, fmt.Errorf("test %d: %v", t.TcID, err)
+		}
+		msg, err := hex.DecodeString(t.Message)
+		if err != nil {
+			return gr, fmt.Errorf("test %d: %v", t.TcID, err)
+		}
+		if g.MACLen > c.BlockSize()*8 {
+			return gr, fmt.Errorf("test %d: MAC length exceeds block size", t.TcID)
+		}
+		tag, err := cmac.SumBitsWithCipher(c, msg, g.MsgLen)
+		if err != nil {
+			return gr, fmt.Errorf("test %d: %v", t.TcID, err)
+		}
+		mac := tag[:g.MACLen/8]
+
+		res := TestResult{TcID: t.TcID}
+		if g.Direction == "gen" {
+			res.MAC = hex.EncodeToString(mac)
+		} else {
+			expected, err := hex.DecodeString(t.MAC)
+			if err != nil {
+				return gr, fmt.Errorf("test %d: %v", t.TcID, err)
+			}
+			passed := subtle.ConstantTimeCompare(mac, expected) == 1
+			res.TestPassed = &passed
+		}
+		gr.Tests = append(gr.Tests, res)
+	}
+	return gr, nil
+}
+
+func newCipher(alg string, t *Test) (cipher.Block, error) {
+	if alg == "CMAC-AES" {
+		key, err := hex.DecodeString(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		return aes.NewCipher(key)
+	}
+	var key []byte
+	for _, k := range []string{t.Key1, t.Key2, t.Key3} {
+		b, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, b...)
+	}
+	return des.NewTripleDESCipher(key)
+}
+
+// RunJSON parses prompt, runs it and encodes the response in the shape of
+// the prompt.
+func RunJSON(prompt []byte) ([]byte, error) {
+	vs, wrapped, err := Parse(prompt)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Run(vs)
+	if err != nil {
+		return nil, err
+	}
+	if !wrapped {
+		return json.MarshalIndent(r, "", "  ")
+	}
+	return json.MarshalIndent([]interface{}{
+		map[string]string{"acvVersion": Version},
+		r,
+	}, "", "  ")
+}
@@ -0,0 +1,81 @@
+package acvp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// A prompt in the shape served by ACVP, with RFC 4493 and SP800-38B
+// example vectors.
+const prompt = `[
+  {"acvVersion": "1.0"},
+  {"vsId": 42, "algorithm": "CMAC-AES", "revision": "1.0", "testGroups": [
+    {"tgId": 1, "testType": "AFT", "direction": "gen", "keyLen": 128, "msgLen": 128, "macLen": 128, "tests": [
+      {"tcId": 1, "key": "2b7e151628aed2a6abf7158809cf4f3c", "message": "6bc1bee22e409f96e93d7e117393172a"}
+    ]},
+    {"tgId": 2, "testType": "AFT", "direction": "ver", "keyLen": 128, "msgLen": 0, "macLen": 64, "tests": [
+      {"tcId": 2, "key": "2b7e151628aed2a6abf7158809cf4f3c", "message": "", "mac": "bb1d6929e9593728"},
+      {"tcId": 3, "key": "2b7e151628aed2a6abf7158809cf4f3c", "message": "", "mac": "bb1d6929e9593729"}
+    ]}
+  ]}
+]`
+
+func TestRunJSON(t *testing.T) {
+	out, err := RunJSON([]byte(prompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(out, &parts); err != nil || len(parts) != 2 {
+		t.Fatalf("response is not a version and vector set array: %s", out)
+	}
+	var r Response
+	if err := json.Unmarshal(parts[1], &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.VsID != 42 || len(r.TestGroups) != 2 {
+		t.Fatalf("unexpected response %+v", r)
+	}
+	if mac := r.TestGroups[0].Tests[0].MAC; mac != "070a16b46b4d4144f79bdd9dd04a287c" {
+		t.Errorf("gen: got %s", mac)
+	}
+	ver := r.TestGroups[1].Tests
+	if !*ver[0].TestPassed || *ver[1].TestPassed {
+		t.Errorf("ver: got %v %v", *ver[0].TestPassed, *ver[1].TestPassed)
+	}
+}
+
+func TestRunTDES(t *testing.T) {
+	vs, wrapped, err := Parse([]byte(`{"vsId": 1, "algorithm": "CMAC-TDES", "revision": "1.0", "testGroups": [
+	  {"tgId": 1, "testType": "AFT", "direction": "gen", "keyingOption": 1, "msgLen": 160, "macLen": 64, "tests": [
+	    {"tcId": 1, "key1": "8aa83bf8cbda1062", "key2": "0bc1bf19fbb6cd58", "key3": "bc313d4a371ca8b5",
+	     "message": "6bc1bee22e409f96e93d7e117393172aae2d8a57"}
+	  ]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped {
+		t.Error("bare vector set reported as wrapped")
+	}
+	r, err := Run(vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac := r.TestGroups[0].Tests[0].MAC; mac != "743ddbe0ce2dc2ed" {
+		t.Errorf("got %s", mac)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, p := range []string{
+		`{"algorithm": "HMAC-SHA-1"}`,
+		`{"algorithm": "CMAC-AES", "testGroups": [{"direction": "gen", "macLen": 256, "msgLen": 0, "tests": [{"key": "2b7e151628aed2a6abf7158809cf4f3c"}]}]}`,
+		`{"algorithm": "CMAC-AES", "testGroups": [{"direction": "gen", "macLen": 128, "msgLen": 8, "tests": [{"key": "2b7e", "message": "00"}]}]}`,
+		`{"algorithm": "CMAC-AES", "testGroups": [{"direction": "gen", "macLen": 128, "msgLen": 16, "tests": [{"key": "2b7e151628aed2a6abf7158809cf4f3c", "message": "00"}]}]}`,
+	} {
+		if _, err := RunJSON([]byte(p)); err == nil {
+			t.Errorf("%s: expected error", p)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"errors"
+)
+
+// DiversifyAN10922 derives a diversified AES key from master as specified
+// in NXP application note AN10922, as used by MIFARE DESFire, Plus and
+// NTAG cards. The diversification input m (typically the card UID
+// followed by an application or system identifier) must be 1 to 31 bytes
+// long. The derived key has the same length as master.
+func DiversifyAN10922(master, m []byte) ([]byte, error) {
+	if len(m) < 1 || len(m) > 31 {
+		return nil, errors.New("cmac: invalid diversification input length")
+	}
+
+	c, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, err
+	}
+	k1, k2 := gensubkeys(c)
+
+	// cm computes the CMAC of const || m, always padded to two blocks.
+	cm := func(constant byte) []byte {
+		var d [32]byte
+		d[0] = constant
+		n := 1 + copy(d[1:], m)
+		k := k1
+		if n < len(d) {
+			d[n] = 0x80
+			k = k2
+		}
+		for i := range k {
+			d[16+i] ^= k[i]
+		}
+
+		x := make([]byte, aes.BlockSize)
+		c.Encrypt(x, d[:16])
+		for i := range x {
+			x[i] ^= d[16+i]
+		}
+		c.Encrypt(x, x)
+		return x
+	}
+
+	switch len(master) {
+	case 16:
+		return cm(0x01), nil
+	case 24:
+		a, b := cm(0x11), cm(0x12)
+		for i := 0; i < 8; i++ {
+			a[8+i] ^= b[i]
+		}
+		return append(a, b[8:]...), nil
+	default:
+		return append(cm(0x41), cm(0x42)...), nil
+	}
+}
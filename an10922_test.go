@@ -0,0 +1,47 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Examples from NXP AN10922 sections 2.2.1, 2.3.1 and 2.4.1.
+var an10922vectors = []struct {
+	master, m, key []byte
+}{
+	{
+		master: unhex("00112233445566778899aabbccddeeff"),
+		m:      unhex("04782e21801d803042f54e585020416275"),
+		key:    unhex("a8dd63a3b89d54b37ca802473fda9175"),
+	},
+	{
+		master: unhex("00112233445566778899aabbccddeeff0102030405060708"),
+		m:      unhex("04782e21801d803042f54e585020416275"),
+		key:    unhex("ce39c8e1cd82d9a7bedbe9d74af59b23176755ee7586e12c"),
+	},
+	{
+		master: unhex("00112233445566778899aabbccddeeff0102030405060708090a0b0c0d0e0f00"),
+		m:      unhex("04782e21801d803042f54e585020416275"),
+		key:    unhex("4fc6eec820b4c54314990b8611662db695e7880982c0001e6067488346100aed"),
+	},
+}
+
+func TestDiversifyAN10922(t *testing.T) {
+	for i, tv := range an10922vectors {
+		k, err := DiversifyAN10922(tv.master, tv.m)
+		if err != nil {
+			t.Fatalf("tv[%d]: DiversifyAN10922() err: %s\n", i, err)
+		}
+		if !bytes.Equal(k, tv.key) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tv.key, k)
+		}
+	}
+
+	master := an10922vectors[0].master
+	if _, err := DiversifyAN10922(master, nil); err == nil {
+		t.Errorf("expected error for empty input")
+	}
+	if _, err := DiversifyAN10922(master, make([]byte, 32)); err == nil {
+		t.Errorf("expected error for 32-byte input")
+	}
+}
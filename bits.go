@@ -0,0 +1,77 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// SumBits appends to b the MAC of the data written so far followed by the
+// n most significant bits of last, for messages whose length in bits is
+// not a multiple of 8, and returns the resulting slice. The padding of
+// SP800-38B is applied after the final bit. n must be between 1 and 7;
+// the remaining bits of last are ignored. Like Sum, it does not change the
+// underlying state, so SumBits is only meaningful as the last step of a
+// computation.
+func (s *State) SumBits(b []byte, last byte, n int) []byte {
+	if n < 1 || n > 7 {
+		panic("cmac: invalid number of trailing bits")
+	}
+	out := s.out[:s.size]
+	padded := last&^(0xff>>n) | 0x80>>n
+
+	cursor := s.cursor
+	if cursor == s.size {
+		// The buffered block is no longer the last one.
+		for i := range out {
+			out[i] = s.x[i] ^ s.buf[i]
+		}
+		s.c.Encrypt(out, out)
+		cursor = 0
+	} else {
+		for i := range out {
+			out[i] = s.x[i]
+		}
+		for i := 0; i < cursor; i++ {
+			out[i] ^= s.buf[i]
+		}
+	}
+
+	out[cursor] ^= padded
+	for i := range out {
+		out[i] ^= s.k2[i]
+	}
+	s.c.Encrypt(out, out)
+	return append(b, out...)
+}
+
+// SumBits returns the AES-CMAC tag of the first bitLen bits of msg under
+// key, subject to the package Policy set with SetPolicy. Bits of msg past
+// bitLen are ignored.
+func SumBits(key, msg []byte, bitLen int) ([16]byte, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return [16]byte{}, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return [16]byte{}, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	return sumBits(c, msg, bitLen, p)
+}
+
+// SumBitsWithCipher is like SumBits for CMAC using c. For ciphers with an
+// 8-byte block only the first 8 bytes of the result are used.
+func SumBitsWithCipher(c cipher.Block, msg []byte, bitLen int) ([16]byte, error) {
+	return sumBits(c, msg, bitLen, currentPolicy())
+}
+
+func sumBits(c cipher.Block, msg []byte, bitLen int, p *Policy) ([16]byte, error) {
+	if bitLen < 0 || bitLen > len(msg)*8 {
+		return [16]byte{}, errors.New("cmac: bit length out of range")
+	}
+	return sum(c, msg[:(bitLen+7)/8], bitLen%8, p)
+}
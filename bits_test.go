@@ -0,0 +1,95 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/des"
+	"testing"
+)
+
+// bitsOracle computes CMAC of the first n bits of msg by padding the whole
+// bit string first and then running the plain CBC-MAC definition.
+func bitsOracle(key, msg []byte, n int) [16]byte {
+	c, _ := aes.NewCipher(key)
+	var k1, k2 [16]byte
+	c.Encrypt(k1[:], k1[:])
+	Dbl(k1[:], k1[:])
+	Dbl(k2[:], k1[:])
+
+	blocks := (n + 127) / 128
+	if blocks == 0 {
+		blocks = 1
+	}
+	m := make([]byte, blocks*16)
+	copy(m, msg[:(n+7)/8])
+	k := k1
+	if n != blocks*128 {
+		m[n/8] &^= 0xff >> (n % 8)
+		m[n/8] |= 0x80 >> (n % 8)
+		k = k2
+	}
+	for i := range k {
+		m[len(m)-16+i] ^= k[i]
+	}
+
+	var x [16]byte
+	for i := 0; i < len(m); i += 16 {
+		for j := range x {
+			x[j] ^= m[i+j]
+		}
+		c.Encrypt(x[:], x[:])
+	}
+	return x
+}
+
+func TestSumBits(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := make([]byte, 50)
+	for i := range msg {
+		msg[i] = byte(i*37 + 11)
+	}
+
+	for n := 0; n <= len(msg)*8; n++ {
+		expected := bitsOracle(key, msg, n)
+		tag, err := SumBits(key, msg, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag != expected {
+			t.Fatalf("%d bits: expected %x got %x", n, expected, tag)
+		}
+
+		// The streaming form, with the last partial byte split off.
+		if r := n % 8; r != 0 {
+			var s State
+			c, _ := aes.NewCipher(key)
+			s.Init(c)
+			s.Write(msg[:n/8])
+			if got := s.SumBits(nil, msg[n/8], r); string(got) != string(expected[:]) {
+				t.Fatalf("%d bits: State.SumBits expected %x got %x", n, expected, got)
+			}
+		}
+	}
+
+	// 128-EIA2 test set 1 of TS 33.401 Annex C.2: 64 bits of parameters
+	// followed by a 58-bit message.
+	m := unhex("38a6f056c00000003332346263393840")
+	tag, _ := SumBits(unhex("2bd6459f82c5b300952c49104881ff48"), m, 122)
+	if expected := "118c6eb8"; string(unhex(expected)) != string(tag[:4]) {
+		t.Errorf("EIA2: expected %s got %x", expected, tag[:4])
+	}
+
+	if _, err := SumBits(key, msg, len(msg)*8+1); err == nil {
+		t.Error("expected error for bit length past the message")
+	}
+}
+
+func TestSumBitsTDEA(t *testing.T) {
+	c, _ := des.NewTripleDESCipher(unhex("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5"))
+	msg := unhex("6bc1bee22e409f96e93d7e117393172aae2d8a57")
+	for n := 8; n <= len(msg)*8; n += 8 {
+		expected, _ := SumWithCipher(c, msg[:n/8])
+		if got, _ := SumBitsWithCipher(c, msg, n); got != expected {
+			t.Errorf("%d bits: expected %x got %x", n, expected, got)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package ble implements the AES-CMAC based functions of the Bluetooth Low
+// Energy Secure Connections cryptographic toolbox (Core Specification
+// Vol 3, Part H, Section 2.2): f4, f5, f6, g2, h6 and h7.
+//
+// All values are most significant octet first, as in the specification
+// and its sample data; values received over the air in little-endian
+// order must be reversed first.
+package ble
+
+import (
+	"encoding/binary"
+
+	"github.com/joekir/cmac"
+)
+
+// Address is a device address: the address type octet (0 for public, 1
+// for random) followed by the 48-bit address.
+type Address [7]byte
+
+var (
+	f5Salt  = [16]byte{0x6c, 0x88, 0x83, 0x91, 0xaa, 0xf5, 0xa5, 0x38, 0x60, 0x37, 0x0b, 0xdb, 0x5a, 0x60, 0x83, 0xbe}
+	f5KeyID = [4]byte{0x62, 0x74, 0x6c, 0x65} // "btle"
+)
+
+func join(parts ...[]byte) []byte {
+	var n int
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, 0, n)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// F4 computes the LE Secure Connections confirm value
+// AES-CMAC_X(U || V || Z) from the public key X coordinates u and v, the
+// key x and the octet z.
+func F4(u, v *[32]byte, x *[16]byte, z byte) ([16]byte, error) {
+	return cmac.Sum(x[:], join(u[:], v[:], []byte{z}))
+}
+
+// F5 computes the MacKey and LTK from the Diffie-Hellman key w, the
+// nonces n1 and n2 and the addresses a1 and a2 of the initiating and
+// responding devices.
+func F5(w *[32]byte, n1, n2 *[16]byte, a1, a2 Address) (macKey, ltk [16]byte, err error) {
+	t, err := cmac.Sum(f5Salt[:], w[:])
+	if err != nil {
+		return
+	}
+	m := join([]byte{0}, f5KeyID[:], n1[:], n2[:], a1[:], a2[:], []byte{0x01, 0x00})
+	if macKey, err = cmac.Sum(t[:], m); err != nil {
+		return
+	}
+	m[0] = 1
+	ltk, err = cmac.Sum(t[:], m)
+	return
+}
+
+// F6 computes the DHKey check value AES-CMAC_W(N1 || N2 || R || IOcap ||
+// A1 || A2) from the MacKey w.
+func F6(w, n1, n2, r *[16]byte, ioCap [3]byte, a1, a2 Address) ([16]byte, error) {
+	return cmac.Sum(w[:], join(n1[:], n2[:], r[:], ioCap[:], a1[:], a2[:]))
+}
+
+// G2 computes the numeric comparison value. The six digits shown to the
+// user are the returned value modulo 1000000.
+func G2(u, v *[32]byte, x, y *[16]byte) (uint32, error) {
+	t, err := cmac.Sum(x[:], join(u[:], v[:], y[:]))
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(t[12:]), nil
+}
+
+// H6 converts the key w to a key of another transport with the 32-bit
+// keyID, as AES-CMAC_W(keyID).
+func H6(w *[16]byte, keyID [4]byte) ([16]byte, error) {
+	return cmac.Sum(w[:], keyID[:])
+}
+
+// H7 converts the key w to a key of another transport with the given
+// salt, as AES-CMAC_SALT(W).
+func H7(salt, w *[16]byte) ([16]byte, error) {
+	return cmac.Sum(salt[:], w[:])
+}
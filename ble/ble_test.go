@@ -0,0 +1,92 @@
+package ble
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func b16(s string) *[16]byte {
+	var b [16]byte
+	copy(b[:], unhex(s))
+	return &b
+}
+
+func b32(s string) *[32]byte {
+	var b [32]byte
+	copy(b[:], unhex(s))
+	return &b
+}
+
+func addr(s string) Address {
+	var a Address
+	copy(a[:], unhex(s))
+	return a
+}
+
+// Sample data from Core Specification Vol 3, Part H, Appendix D.
+var (
+	u  = b32("20b003d2 f297be2c 5e2c83a7 e9f9a5b9 eff49111 acf4fddb cc030148 0e359de6")
+	v  = b32("55188b3d 32f6bb9a 900afcfb eed4e72a 59cb9ac2 f19d7cfb 6b4fdd49 f47fc5fd")
+	x  = b16("d5cb8454 d177733e ffffb2ec 712baeab")
+	n1 = b16("d5cb8454 d177733e ffffb2ec 712baeab")
+	n2 = b16("a6e8e7cc 25a75f6e 216583f7 ff3dc4cf")
+	a1 = addr("00561237 37bfce")
+	a2 = addr("00a71370 2dcfc1")
+)
+
+func check(t *testing.T, name string, got [16]byte, err error, expected string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if e := unhex(expected); string(got[:]) != string(e) {
+		t.Errorf("%s: expected %x got %x", name, e, got)
+	}
+}
+
+func TestF4(t *testing.T) {
+	r, err := F4(u, v, x, 0)
+	check(t, "f4", r, err, "f2c916f1 07a9bd1c f1eda1be a974872d")
+}
+
+func TestF5(t *testing.T) {
+	w := b32("ec0234a3 57c8ad05 341010a6 0a397d9b 99796b13 b4f866f1 868d34f3 73bfa698")
+	mac, ltk, err := F5(w, n1, n2, a1, a2)
+	check(t, "MacKey", mac, err, "2965f176 a1084a02 fd3f6a20 ce636e20")
+	check(t, "LTK", ltk, err, "69867911 69d7cd23 980522b5 94750a38")
+}
+
+func TestF6(t *testing.T) {
+	w := b16("2965f176 a1084a02 fd3f6a20 ce636e20")
+	r := b16("12a3343b b453bb54 08da42d2 0c2d0fc8")
+	e, err := F6(w, n1, n2, r, [3]byte{0x01, 0x01, 0x02}, a1, a2)
+	check(t, "f6", e, err, "e3c47398 9cd0e8c5 d26c0b09 da958f61")
+}
+
+func TestG2(t *testing.T) {
+	n, err := G2(u, v, x, n2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0x2f9ed5ba {
+		t.Errorf("expected 2f9ed5ba got %08x", n)
+	}
+}
+
+func TestH6H7(t *testing.T) {
+	w := b16("ec0234a3 57c8ad05 341010a6 0a397d9b")
+	k, err := H6(w, [4]byte{0x6c, 0x65, 0x62, 0x72})
+	check(t, "h6", k, err, "2d9ae102 e76dc91c e8d3a9e2 80b16399")
+
+	k, err = H7(b16("00000000 00000000 00000000 746d7031"), w)
+	check(t, "h7", k, err, "fb173597 c6a3c0ec d2998c2a 75a57011")
+}
@@ -0,0 +1,62 @@
+package cmac
+
+import (
+	"errors"
+	"hash"
+	"sync"
+)
+
+// Limits on blind index lengths. Indexes shorter than the minimum produce
+// so many false positives that queries become useless, while indexes as
+// long as a full tag make every value uniquely identifiable across rows,
+// which defeats the point of truncation when values have low entropy.
+const (
+	MinBlindIndexSize = 4
+	MaxBlindIndexSize = 12
+)
+
+var blindIndexLabel = []byte("cmac blind index")
+
+// BlindIndex derives fixed-length blind indexes for one database column:
+// truncated AES-CMAC tags of the plaintext values, under a key derived
+// from the index key and the field name, so equal values in the same field
+// can be searched for without decrypting them. A BlindIndex is safe for
+// concurrent use.
+type BlindIndex struct {
+	size int
+
+	mu sync.Mutex
+	h  hash.Hash
+}
+
+// NewBlindIndex returns a BlindIndex for field producing indexes of size
+// bytes, between MinBlindIndexSize and MaxBlindIndexSize. The key must be a
+// dedicated index key, not the key encrypting the column.
+func NewBlindIndex(key []byte, field string, size int) (*BlindIndex, error) {
+	if size < MinBlindIndexSize || size > MaxBlindIndexSize {
+		return nil, errors.New("cmac: blind index size out of range")
+	}
+	if field == "" {
+		return nil, errors.New("cmac: empty blind index field name")
+	}
+
+	fk, err := kdf(key, blindIndexLabel, []byte(field), len(key))
+	if err != nil {
+		return nil, err
+	}
+	h, err := New(fk)
+	if err != nil {
+		return nil, err
+	}
+	return &BlindIndex{size: size, h: h}, nil
+}
+
+// Index returns the blind index of value.
+func (b *BlindIndex) Index(value []byte) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.h.Reset()
+	b.h.Write(value)
+	return b.h.Sum(nil)[:b.size]
+}
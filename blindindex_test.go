@@ -0,0 +1,45 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlindIndex(t *testing.T) {
+	key := make([]byte, 32)
+	email, err := NewBlindIndex(key, "users.email", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, _ := NewBlindIndex(key, "users.phone", 8)
+
+	a := email.Index([]byte("alice@example.com"))
+	if len(a) != 8 {
+		t.Fatalf("index length %d", len(a))
+	}
+	if !bytes.Equal(a, email.Index([]byte("alice@example.com"))) {
+		t.Error("indexes of equal values differ")
+	}
+	if bytes.Equal(a, email.Index([]byte("bob@example.com"))) {
+		t.Error("indexes of different values are equal")
+	}
+	if bytes.Equal(a, phone.Index([]byte("alice@example.com"))) {
+		t.Error("indexes of different fields are equal")
+	}
+
+	// The field key, not the index key, is used for the tag.
+	h, _ := New(key)
+	h.Write([]byte("alice@example.com"))
+	if bytes.Equal(a, h.Sum(nil)[:8]) {
+		t.Error("index computed under the index key directly")
+	}
+
+	for _, n := range []int{MinBlindIndexSize - 1, MaxBlindIndexSize + 1} {
+		if _, err := NewBlindIndex(key, "f", n); err == nil {
+			t.Errorf("size %d: expected error", n)
+		}
+	}
+	if _, err := NewBlindIndex(key, "", 8); err == nil {
+		t.Error("expected error for empty field")
+	}
+}
@@ -0,0 +1,97 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// BlockMap holds AES-CMAC tags over the consecutive fixed-size blocks of a
+// source, rsync style, so that two copies can be compared without
+// transferring them. Each tag also covers the block's index, so blocks
+// can't be reordered undetected.
+type BlockMap struct {
+	BlockSize int
+	Size      int64
+	Tags      [][16]byte
+}
+
+// Range is a byte range of a source.
+type Range struct {
+	Off, Len int64
+}
+
+// NewBlockMap reads r to EOF and returns the tags of its blocks of
+// blockSize bytes; the last block may be shorter.
+func NewBlockMap(key []byte, r io.Reader, blockSize int) (*BlockMap, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("cmac: invalid block map block size")
+	}
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &BlockMap{BlockSize: blockSize}
+	buf := make([]byte, blockSize)
+	var idx [8]byte
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint64(idx[:], i)
+			h.Reset()
+			h.Write(idx[:])
+			h.Write(buf[:n])
+
+			var tag [16]byte
+			h.Sum(tag[:0])
+			m.Tags = append(m.Tags, tag)
+			m.Size += int64(n)
+		}
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			return m, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// Diff returns the byte ranges, relative to the larger of the two sources,
+// in which m and other differ. Adjacent differing blocks are merged into a
+// single range. Both maps must use the same key and block size.
+func (m *BlockMap) Diff(other *BlockMap) ([]Range, error) {
+	if m.BlockSize != other.BlockSize {
+		return nil, errors.New("cmac: block maps use different block sizes")
+	}
+
+	size := m.Size
+	if other.Size > size {
+		size = other.Size
+	}
+	n := len(m.Tags)
+	if len(other.Tags) > n {
+		n = len(other.Tags)
+	}
+
+	var ranges []Range
+	bs := int64(m.BlockSize)
+	for i := 0; i < n; i++ {
+		if i < len(m.Tags) && i < len(other.Tags) && m.Tags[i] == other.Tags[i] {
+			continue
+		}
+
+		off := int64(i) * bs
+		l := bs
+		if off+l > size {
+			l = size - off
+		}
+		if k := len(ranges) - 1; k >= 0 && ranges[k].Off+ranges[k].Len == off {
+			ranges[k].Len += l
+		} else {
+			ranges = append(ranges, Range{Off: off, Len: l})
+		}
+	}
+	return ranges, nil
+}
@@ -0,0 +1,62 @@
+package cmac
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBlockMap(t *testing.T) {
+	key := make([]byte, 16)
+	src := make([]byte, 1000)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	a, err := NewBlockMap(key, bytes.NewReader(src), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Tags) != 10 || a.Size != 1000 {
+		t.Fatalf("got %d tags over %d bytes", len(a.Tags), a.Size)
+	}
+
+	mod := append([]byte(nil), src...)
+	mod[150] ^= 1
+	mod[250] ^= 1
+	mod[720] ^= 1
+	mod = append(mod, 1, 2, 3)
+	b, err := NewBlockMap(key, bytes.NewReader(mod), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranges, err := a.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Range{{100, 200}, {700, 100}, {1000, 3}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected %v got %v", expected, ranges)
+	}
+
+	if ranges, _ := a.Diff(a); len(ranges) != 0 {
+		t.Errorf("expected no differences, got %v", ranges)
+	}
+
+	// Swapped blocks are detected.
+	swapped := append(append([]byte(nil), src[100:200]...), src[:100]...)
+	c, _ := NewBlockMap(key, bytes.NewReader(swapped), 100)
+	d, _ := NewBlockMap(key, bytes.NewReader(src[:200]), 100)
+	if ranges, _ := c.Diff(d); !reflect.DeepEqual(ranges, []Range{{0, 200}}) {
+		t.Errorf("swapped blocks: got %v", ranges)
+	}
+
+	e, _ := NewBlockMap(key, bytes.NewReader(src), 64)
+	if _, err := a.Diff(e); err == nil {
+		t.Error("expected error for different block sizes")
+	}
+	if _, err := NewBlockMap(key, bytes.NewReader(src), 0); err == nil {
+		t.Error("expected error for zero block size")
+	}
+}
@@ -0,0 +1,124 @@
+//go:build !purego
+
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"reflect"
+)
+
+func implementation() string {
+	if hasAES {
+		return "hardware-aes-cbc"
+	}
+	return "generic"
+}
+
+// cbcEncAble is implemented by block ciphers that can CBC-encrypt runs of
+// blocks in a single call, typically hardware-backed ciphers. It is the
+// interface crypto/cipher.NewCBCEncrypter looks for.
+type cbcEncAble interface {
+	NewCBCEncrypter(iv []byte) cipher.BlockMode
+}
+
+// batchSize is the number of bytes handed to a cbcEncAble cipher per call.
+// It is a multiple of both supported block sizes.
+const batchSize = 512
+
+// aesBlockType is the type of the ciphers returned by crypto/aes. Since Go
+// 1.24 they no longer implement cbcEncAble; cipher.NewCBCEncrypter
+// recognizes them instead and uses the assembly for runs of blocks.
+var aesBlockType = func() reflect.Type {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		panic(err)
+	}
+	return reflect.TypeOf(c)
+}()
+
+// cbcMinRun is the shortest run of bytes for which a crypto/aes cipher is
+// driven through CBC mode; below it the setup costs more than it saves.
+const cbcMinRun = 4 * aes.BlockSize
+
+// cbcCache holds a CBC encrypter over the crypto/aes cipher of a hash. It
+// is created once and reused across writes by resetting its IV to the
+// chaining value, so that long writes don't allocate. It lives in the hash
+// rather than in State, which must stay free of pointers.
+type cbcCache struct {
+	c    cipher.Block
+	mode cipher.BlockMode
+	buf  [batchSize]byte
+}
+
+type ivSetter interface {
+	SetIV(iv []byte)
+}
+
+func blocks(s *State, b []byte, cbc **cbcCache) {
+	if cbc != nil && hasAES && len(b) >= cbcMinRun && reflect.TypeOf(s.c) == aesBlockType {
+		if *cbc == nil {
+			*cbc = new(cbcCache)
+		}
+		(*cbc).blocks(s, b)
+		return
+	}
+	if c, ok := s.c.(cbcEncAble); ok {
+		blocksCBC(s, c, b)
+		return
+	}
+	blocksGeneric(s, b)
+}
+
+// blocksCBC chains the full blocks in b through the cipher's own CBC
+// implementation: the CBC-MAC chaining value is the last ciphertext block.
+func blocksCBC(s *State, c cbcEncAble, b []byte) {
+	var buf [batchSize]byte
+	x := s.x[:s.size]
+	mode := c.NewCBCEncrypter(x)
+
+	n := 0
+	for len(b) > 0 {
+		n = copy(buf[:], b)
+		mode.CryptBlocks(buf[:n], buf[:n])
+		b = b[n:]
+	}
+	copy(x, buf[n-s.size:n])
+}
+
+// blocks chains the full blocks in b through the cached encrypter, which is
+// set up for the cipher and chaining value of s first.
+func (m *cbcCache) blocks(s *State, b []byte) {
+	x := s.x[:s.size]
+	if v, ok := m.mode.(ivSetter); ok && m.c == s.c {
+		v.SetIV(x)
+	} else {
+		m.c, m.mode = s.c, cipher.NewCBCEncrypter(s.c, x)
+	}
+
+	n := 0
+	for len(b) > 0 {
+		n = copy(m.buf[:], b)
+		m.mode.CryptBlocks(m.buf[:n], m.buf[:n])
+		b = b[n:]
+	}
+	copy(x, m.buf[n-s.size:n])
+}
+
+// reset zeroes the ciphertext left in the cache and the chaining value held
+// by the encrypter, keeping the encrypter for reuse with its cipher.
+func (m *cbcCache) reset() {
+	wipe(m.buf[:])
+	if v, ok := m.mode.(ivSetter); ok {
+		v.SetIV(m.buf[:m.mode.BlockSize()])
+	} else {
+		m.c, m.mode = nil, nil
+	}
+}
+
+// wipe zeroes the ciphertext left in the cache and drops the encrypter,
+// which holds its own copy of the key schedule.
+func (m *cbcCache) wipe() {
+	wipe(m.buf[:])
+	m.c, m.mode = nil, nil
+}
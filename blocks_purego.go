@@ -0,0 +1,18 @@
+//go:build purego
+
+package cmac
+
+func implementation() string {
+	return "generic"
+}
+
+// cbcCache is unused: purego builds avoid the crypto/aes assembly.
+type cbcCache struct{}
+
+func (m *cbcCache) reset() {}
+
+func (m *cbcCache) wipe() {}
+
+func blocks(s *State, b []byte, cbc **cbcCache) {
+	blocksGeneric(s, b)
+}
@@ -0,0 +1,203 @@
+//go:build !purego
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"hash"
+	"io"
+	"testing"
+)
+
+// batchCipher is a cipher.Block offering multi-block CBC encryption.
+type batchCipher struct {
+	cipher.Block
+	calls int
+}
+
+func (c *batchCipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	return &countingMode{cipher.NewCBCEncrypter(c.Block, iv), c}
+}
+
+type countingMode struct {
+	cipher.BlockMode
+	c *batchCipher
+}
+
+func (m *countingMode) CryptBlocks(dst, src []byte) {
+	m.c.calls++
+	m.BlockMode.CryptBlocks(dst, src)
+}
+
+func TestBatchCipher(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		tv := nistvectors[i]
+		c, _ := aes.NewCipher(tv.key)
+		bc := &batchCipher{Block: c}
+
+		m, err := NewWithCipher(bc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, tc := range tv.cases {
+			m.Write(tc.msg)
+			if mac := m.Sum(nil); !bytes.Equal(mac, tc.mac) {
+				t.Errorf("tv[%d,%d]: expected: %x got %x\n", i, j, tc.mac, mac)
+			}
+			m.Reset()
+		}
+		if bc.calls == 0 {
+			t.Errorf("tv[%d]: batch interface not used", i)
+		}
+	}
+
+	// Runs longer than one batch.
+	key := make([]byte, 16)
+	c, _ := aes.NewCipher(key)
+	msg := make([]byte, 3*batchSize+37)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	a, _ := NewWithCipher(c)
+	b, _ := NewWithCipher(&batchCipher{Block: c})
+	a.Write(msg)
+	b.Write(msg)
+	if x, y := a.Sum(nil), b.Sum(nil); !bytes.Equal(x, y) {
+		t.Errorf("expected: %x got %x\n", x, y)
+	}
+}
+
+func TestAESCBCPath(t *testing.T) {
+	if !hasAES {
+		t.Skip("no hardware AES")
+	}
+	msg := make([]byte, 5*batchSize+cbcMinRun+3)
+	for i := range msg {
+		msg[i] = byte(i * 3)
+	}
+	key := nistvectors[0].key
+	want, _ := Sum(key, msg)
+
+	h, _ := New(key)
+	for i := 0; i < 3; i++ {
+		// Mixed write sizes, some below cbcMinRun, over several resets.
+		rest := msg
+		for _, n := range []int{1, cbcMinRun*2 + 5, 7, batchSize * 3, cbcMinRun - 1} {
+			h.Write(rest[:n])
+			rest = rest[n:]
+		}
+		h.Write(rest)
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Fatalf("round %d: got %x, want %x", i, got, want)
+		}
+		h.Reset()
+	}
+	m := h.(*cmac)
+	if m.cbc == nil || m.cbc.mode == nil {
+		t.Fatal("CBC path not used")
+	}
+	if n := testing.AllocsPerRun(10, func() {
+		h.Reset()
+		h.Write(msg)
+	}); n != 0 {
+		t.Errorf("long writes allocate %v times", n)
+	}
+
+	// Clones get their own encrypter, and Wipe and Close drop it.
+	c, _ := m.cloneHash()
+	if c.(*cmac).cbc != nil {
+		t.Error("clone shares the CBC encrypter")
+	}
+	cbc := m.cbc
+	m.Wipe()
+	if m.cbc != nil || cbc.c != nil || cbc.mode != nil || cbc.buf != [batchSize]byte{} {
+		t.Error("Wipe kept the CBC encrypter")
+	}
+	m.Init(c.(*cmac).c)
+	m.Write(msg)
+	m.Close()
+	if m.cbc != nil {
+		t.Error("Close kept the CBC encrypter")
+	}
+}
+
+func TestAESCBCPathOneShot(t *testing.T) {
+	if !hasAES {
+		t.Skip("no hardware AES")
+	}
+	key := nistvectors[0].key
+	c, _ := aes.NewCipher(key)
+	msg := make([]byte, 2*batchSize+cbcMinRun+5)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+	h, _ := New(key)
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	k, _ := NewKeyedWithCipher(c)
+	p, _ := NewPrefixWithCipher(c, msg[:16])
+	for _, tc := range []struct {
+		name string
+		sum  func() []byte
+		pool *cbcPool
+	}{
+		{"Keyed.Sum", func() []byte { tag, _ := k.Sum(msg); return tag[:] }, &k.cbc},
+		{"Prefix.Sum", func() []byte { tag, _ := p.Sum(nil, msg[16:]); return tag }, &p.cbc},
+	} {
+		// The race detector drops pooled items at random, so look for a
+		// pooled encrypter over a few calls.
+		var cbc *cbcCache
+		for i := 0; i < 10 && cbc == nil; i++ {
+			if got := tc.sum(); !bytes.Equal(got, want) {
+				t.Fatalf("%s: got %x, want %x", tc.name, got, want)
+			}
+			cbc = tc.pool.get()
+		}
+		if cbc == nil || cbc.mode == nil || cbc.c != c {
+			t.Errorf("%s: CBC path not used", tc.name)
+			continue
+		}
+		if cbc.buf != [batchSize]byte{} {
+			t.Errorf("%s: ciphertext left in the pooled cache", tc.name)
+		}
+	}
+
+	// SumWithCipher keeps nothing of the cipher, so it takes the generic
+	// path.
+	if tag, _ := SumWithCipher(c, msg); !bytes.Equal(tag[:], want) {
+		t.Errorf("SumWithCipher: got %x, want %x", tag, want)
+	}
+}
+
+func TestAESCBCPathReadFrom(t *testing.T) {
+	if !hasAES {
+		t.Skip("no hardware AES")
+	}
+	key := nistvectors[0].key
+	msg := make([]byte, 3*batchSize)
+	h, _ := New(key)
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	p, _ := NewPrefix(key, nil)
+	for _, h := range []hash.Hash{h, p.New()} {
+		h.Reset()
+		h.(io.ReaderFrom).ReadFrom(bytes.NewReader(msg))
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("%T: got %x, want %x", h, got, want)
+		}
+		var cbc *cbcCache
+		switch m := h.(type) {
+		case *cmac:
+			cbc = m.cbc
+		case *prefixed:
+			cbc = m.cbc
+		}
+		if cbc == nil || cbc.mode == nil {
+			t.Errorf("%T: CBC path not used by ReadFrom", h)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Command capi exports the cmac package to C. Build it with
+//
+//	go build -buildmode=c-shared -o libcmac.so ./capi
+//
+// or -buildmode=c-archive for a static library; the go tool writes the
+// matching header next to the library. All functions return a negative
+// value on invalid arguments.
+//
+// One-shot:
+//
+//	int cmac_sum(const uint8_t *key, size_t keylen, const uint8_t *msg, size_t msglen, uint8_t *tag);
+//	int cmac_verify(const uint8_t *key, size_t keylen, const uint8_t *msg, size_t msglen, const uint8_t *tag, size_t taglen);
+//
+// Streaming, through an opaque handle that must be released with
+// cmac_free:
+//
+//	uintptr_t cmac_new(const uint8_t *key, size_t keylen);
+//	int cmac_write(uintptr_t h, const uint8_t *msg, size_t msglen);
+//	int cmac_final(uintptr_t h, uint8_t *tag);
+//	int cmac_reset(uintptr_t h);
+//	int cmac_free(uintptr_t h);
+//
+// Unknown, stale and already freed handles are rejected with -1. A handle
+// must not be used from several threads at once. Tags are written as 16
+// bytes.
+package main
+
+// #include <stddef.h>
+// #include <stdint.h>
+import "C"
+
+import (
+	"unsafe"
+)
+
+func bytesOf(p *C.uint8_t, n C.size_t) []byte {
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), int(n))
+}
+
+//export cmac_sum
+func cmac_sum(key *C.uint8_t, keylen C.size_t, msg *C.uint8_t, msglen C.size_t, tag *C.uint8_t) C.int {
+	t, ok := sum(bytesOf(key, keylen), bytesOf(msg, msglen))
+	if !ok {
+		return -1
+	}
+	copy(bytesOf(tag, 16), t[:])
+	return 0
+}
+
+// cmac_verify returns 1 if the tag is valid and 0 if it isn't.
+//
+//export cmac_verify
+func cmac_verify(key *C.uint8_t, keylen C.size_t, msg *C.uint8_t, msglen C.size_t, tag *C.uint8_t, taglen C.size_t) C.int {
+	return C.int(verify(bytesOf(key, keylen), bytesOf(msg, msglen), bytesOf(tag, taglen)))
+}
+
+// cmac_new returns 0 on invalid keys.
+//
+//export cmac_new
+func cmac_new(key *C.uint8_t, keylen C.size_t) C.uintptr_t {
+	return C.uintptr_t(newHandle(bytesOf(key, keylen)))
+}
+
+//export cmac_write
+func cmac_write(h C.uintptr_t, msg *C.uint8_t, msglen C.size_t) C.int {
+	if !write(uintptr(h), bytesOf(msg, msglen)) {
+		return -1
+	}
+	return 0
+}
+
+//export cmac_final
+func cmac_final(h C.uintptr_t, tag *C.uint8_t) C.int {
+	t, ok := final(uintptr(h))
+	if !ok {
+		return -1
+	}
+	copy(bytesOf(tag, 16), t[:])
+	return 0
+}
+
+//export cmac_reset
+func cmac_reset(h C.uintptr_t) C.int {
+	if !reset(uintptr(h)) {
+		return -1
+	}
+	return 0
+}
+
+//export cmac_free
+func cmac_free(h C.uintptr_t) C.int {
+	if !free(uintptr(h)) {
+		return -1
+	}
+	return 0
+}
+
+func main() {}
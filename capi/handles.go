@@ -0,0 +1,100 @@
+//go:build cgo
+
+package main
+
+import (
+	"crypto/subtle"
+	"hash"
+	"io"
+	"sync"
+
+	"github.com/joekir/cmac"
+)
+
+// The exported functions are thin wrappers around these, which keep Go
+// values on the Go side of the boundary.
+
+func sum(key, msg []byte) (tag [16]byte, ok bool) {
+	h, err := cmac.New(key)
+	if err != nil {
+		return tag, false
+	}
+	h.Write(msg)
+	h.Sum(tag[:0])
+	return tag, true
+}
+
+func verify(key, msg, tag []byte) int {
+	t, ok := sum(key, msg)
+	if !ok {
+		return -1
+	}
+	return subtle.ConstantTimeCompare(t[:], tag)
+}
+
+// handles maps the handles given out to C to their hashes. Handles count
+// up from 1 and are never reused, so a stale or freed handle is simply
+// unknown rather than aliasing a live one.
+var handles struct {
+	sync.Mutex
+	next uintptr
+	m    map[uintptr]hash.Hash
+}
+
+func newHandle(key []byte) uintptr {
+	h, err := cmac.New(key)
+	if err != nil {
+		return 0
+	}
+	handles.Lock()
+	defer handles.Unlock()
+	if handles.m == nil {
+		handles.m = make(map[uintptr]hash.Hash)
+	}
+	handles.next++
+	handles.m[handles.next] = h
+	return handles.next
+}
+
+func lookup(h uintptr) (hash.Hash, bool) {
+	handles.Lock()
+	defer handles.Unlock()
+	m, ok := handles.m[h]
+	return m, ok
+}
+
+func write(h uintptr, msg []byte) bool {
+	m, ok := lookup(h)
+	if ok {
+		m.Write(msg)
+	}
+	return ok
+}
+
+func final(h uintptr) (tag [16]byte, ok bool) {
+	m, ok := lookup(h)
+	if ok {
+		m.Sum(tag[:0])
+	}
+	return tag, ok
+}
+
+func reset(h uintptr) bool {
+	m, ok := lookup(h)
+	if ok {
+		m.Reset()
+	}
+	return ok
+}
+
+// free forgets h and wipes its hash.
+func free(h uintptr) bool {
+	handles.Lock()
+	m, ok := handles.m[h]
+	delete(handles.m, h)
+	handles.Unlock()
+	if c, isCloser := m.(io.Closer); isCloser {
+		c.Close()
+	}
+	return ok
+}
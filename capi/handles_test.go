@@ -0,0 +1,88 @@
+//go:build cgo
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// RFC 4493 example 3.
+var (
+	key = unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg = unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	tag = unhex("dfa66747de9ae63030ca32611497c827")
+)
+
+func TestOneShot(t *testing.T) {
+	got, ok := sum(key, msg)
+	if !ok || !bytes.Equal(got[:], tag) {
+		t.Errorf("sum: expected: %x got %x\n", tag, got)
+	}
+	if verify(key, msg, tag) != 1 {
+		t.Error("verify: valid tag rejected")
+	}
+	if verify(key, msg[1:], tag) != 0 {
+		t.Error("verify: invalid tag accepted")
+	}
+	if verify(key[1:], msg, tag) != -1 {
+		t.Error("verify: invalid key accepted")
+	}
+}
+
+func TestHandles(t *testing.T) {
+	if newHandle(nil) != 0 {
+		t.Error("newHandle: invalid key accepted")
+	}
+
+	h := newHandle(key)
+	defer free(h)
+
+	write(h, msg[:20])
+	write(h, msg[20:])
+	got, ok := final(h)
+	if !ok || !bytes.Equal(got[:], tag) {
+		t.Errorf("expected: %x got %x\n", tag, got)
+	}
+
+	reset(h)
+	write(h, msg)
+	if got, _ := final(h); !bytes.Equal(got[:], tag) {
+		t.Errorf("after reset: expected: %x got %x\n", tag, got)
+	}
+}
+
+func TestStaleHandles(t *testing.T) {
+	h := newHandle(key)
+	if !free(h) {
+		t.Fatal("free: live handle rejected")
+	}
+	for _, h := range []uintptr{0, h, h + 1, ^uintptr(0)} {
+		if write(h, msg) {
+			t.Errorf("write: handle %d accepted", h)
+		}
+		if _, ok := final(h); ok {
+			t.Errorf("final: handle %d accepted", h)
+		}
+		if reset(h) {
+			t.Errorf("reset: handle %d accepted", h)
+		}
+		if free(h) {
+			t.Errorf("free: handle %d accepted", h)
+		}
+	}
+	if h2 := newHandle(key); h2 == h {
+		t.Error("newHandle reused a freed handle")
+	} else {
+		free(h2)
+	}
+}
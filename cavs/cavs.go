@@ -0,0 +1,190 @@
+// Package cavs parses the response files (.rsp) of the legacy NIST CAVP
+// CMAC validation, CMACGenAES*.rsp, CMACVerAES*.rsp and their TDES
+// counterparts, and checks their vectors against package cmac. The files
+// are published in cmactestvectors.zip on the CAVP website.
+package cavs
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/joekir/cmac"
+)
+
+// Vector is one test case. Lengths are in bytes, as in the files.
+type Vector struct {
+	// Line is the line the vector starts on.
+	Line  int
+	Count int
+	Klen  int
+	Mlen  int
+	Tlen  int
+	// Key is the AES key, or the three TDES keys concatenated.
+	Key []byte
+	Msg []byte
+	Mac []byte
+	// Verify is set for CMACVer vectors, of which Pass tells the
+	// expected result.
+	Verify bool
+	Pass   bool
+	// Section holds the parameters of the enclosing [name = value]
+	// headers.
+	Section map[string]string
+
+	tdes bool
+}
+
+// Parse reads the vectors of a CMACGen or CMACVer response file.
+func Parse(r io.Reader) ([]Vector, error) {
+	var (
+		vs      []Vector
+		cur     *Vector
+		section = map[string]string{}
+		s       = bufio.NewScanner(r)
+	)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+			section = copyMap(section)
+			name, value := splitField(text[1 : len(text)-1])
+			section[name] = value
+			continue
+		}
+
+		name, value := splitField(text)
+		if name == "" {
+			return nil, fmt.Errorf("cavs: line %d: malformed line", line)
+		}
+		if name == "Count" {
+			vs = append(vs, Vector{Line: line, Section: section})
+			cur = &vs[len(vs)-1]
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("cavs: line %d: %s before Count", line, name)
+		}
+		if err := cur.set(name, value); err != nil {
+			return nil, fmt.Errorf("cavs: line %d: %v", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for i := range vs {
+		if err := vs[i].check(); err != nil {
+			return nil, fmt.Errorf("cavs: line %d: %v", vs[i].Line, err)
+		}
+	}
+	return vs, nil
+}
+
+func splitField(s string) (name, value string) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+}
+
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func (v *Vector) set(name, value string) error {
+	var err error
+	switch name {
+	case "Count":
+		v.Count, err = strconv.Atoi(value)
+	case "Klen":
+		v.Klen, err = strconv.Atoi(value)
+	case "Mlen":
+		v.Mlen, err = strconv.Atoi(value)
+	case "Tlen":
+		v.Tlen, err = strconv.Atoi(value)
+	case "Key", "Key1", "Key2", "Key3":
+		v.tdes = name != "Key"
+		var b []byte
+		b, err = hex.DecodeString(value)
+		v.Key = append(v.Key, b...)
+	case "Msg":
+		v.Msg, err = hex.DecodeString(value)
+	case "Mac":
+		v.Mac, err = hex.DecodeString(value)
+	case "Result":
+		// "P", or "F" followed by the reason of the failure.
+		v.Verify = true
+		v.Pass = strings.HasPrefix(value, "P")
+		if !v.Pass && !strings.HasPrefix(value, "F") {
+			err = fmt.Errorf("invalid result %q", value)
+		}
+	default:
+		// Unknown fields are ignored so that newer files still parse.
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func (v *Vector) check() error {
+	// A zero-length message is written as "Msg = 00".
+	if v.Mlen == 0 {
+		v.Msg = nil
+	}
+	switch {
+	case len(v.Msg) != v.Mlen:
+		return fmt.Errorf("message length %d, expected %d", len(v.Msg), v.Mlen)
+	case len(v.Mac) != v.Tlen:
+		return fmt.Errorf("MAC length %d, expected %d", len(v.Mac), v.Tlen)
+	case v.tdes && len(v.Key) != 24:
+		return fmt.Errorf("TDES key length %d, expected 24", len(v.Key))
+	case !v.tdes && v.Klen != 0 && len(v.Key) != v.Klen:
+		return fmt.Errorf("key length %d, expected %d", len(v.Key), v.Klen)
+	}
+	return nil
+}
+
+// Cipher returns the cipher for the vector's key: AES, or TDES for keys
+// given as Key1, Key2 and Key3.
+func (v *Vector) Cipher() (cipher.Block, error) {
+	if v.tdes {
+		return des.NewTripleDESCipher(v.Key)
+	}
+	return aes.NewCipher(v.Key)
+}
+
+// Check runs the vector against package cmac and returns an error if the
+// result differs from the expected one: a different MAC for CMACGen
+// vectors, or the wrong verification outcome for CMACVer vectors.
+func (v *Vector) Check() error {
+	c, err := v.Cipher()
+	if err != nil {
+		return err
+	}
+	tag, err := cmac.SumWithCipher(c, v.Msg)
+	if err != nil {
+		return err
+	}
+	ok := subtle.ConstantTimeCompare(tag[:v.Tlen], v.Mac) == 1
+	switch {
+	case !v.Verify && !ok:
+		return fmt.Errorf("cavs: line %d: expected MAC %x, got %x", v.Line, v.Mac, tag[:v.Tlen])
+	case v.Verify && ok != v.Pass:
+		return fmt.Errorf("cavs: line %d: verification returned %v, expected %v", v.Line, ok, v.Pass)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cavs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Excerpts in the layout of CMACGenAES128.rsp, CMACVerAES128.rsp and
+// CMACGenTDES3.rsp, with the SP800-38B example vectors.
+const gen = `#  CAVS 11.0
+#  "CMAC Gen" information for "testvectors"
+
+Count = 0
+Klen = 16
+Mlen = 0
+Tlen = 16
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg = 00
+Mac = bb1d6929e95937287fa37d129b756746
+
+Count = 1
+Klen = 16
+Mlen = 16
+Tlen = 4
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg = 6bc1bee22e409f96e93d7e117393172a
+Mac = 070a16b4
+`
+
+const ver = `#  CAVS 11.0
+#  "CMAC Ver" information for "testvectors"
+
+Count = 0
+Klen = 16
+Mlen = 16
+Tlen = 16
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg = 6bc1bee22e409f96e93d7e117393172a
+Mac = 070a16b46b4d4144f79bdd9dd04a287c
+Result = P
+
+Count = 1
+Klen = 16
+Mlen = 16
+Tlen = 16
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg = 6bc1bee22e409f96e93d7e117393172a
+Mac = 070a16b46b4d4144f79bdd9dd04a287d
+Result = F (3 - Tag changed)
+`
+
+const tdes = `[Alg = TDES]
+
+Count = 0
+Mlen = 8
+Tlen = 8
+Key1 = 8aa83bf8cbda1062
+Key2 = 0bc1bf19fbb6cd58
+Key3 = bc313d4a371ca8b5
+Msg = 6bc1bee22e409f96
+Mac = 8e8f293136283797
+`
+
+func TestParse(t *testing.T) {
+	for name, in := range map[string]string{"gen": gen, "ver": ver, "tdes": tdes} {
+		vs, err := Parse(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, v := range vs {
+			if err := v.Check(); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	vs, _ := Parse(strings.NewReader(ver))
+	if len(vs) != 2 || !vs[0].Verify || !vs[0].Pass || vs[1].Pass {
+		t.Errorf("results not parsed: %+v", vs)
+	}
+	vs, _ = Parse(strings.NewReader(gen))
+	if vs[0].Msg != nil || vs[1].Line != 12 {
+		t.Errorf("unexpected vectors: %+v", vs)
+	}
+	vs, _ = Parse(strings.NewReader(tdes))
+	if vs[0].Section["Alg"] != "TDES" {
+		t.Errorf("section not recorded: %+v", vs[0].Section)
+	}
+
+	// A wrong expected MAC is reported.
+	vs, _ = Parse(strings.NewReader(strings.Replace(gen, "Mac = 070a16b4", "Mac = 070a16b5", 1)))
+	if err := vs[1].Check(); err == nil {
+		t.Error("wrong MAC not reported")
+	}
+
+	for _, bad := range []string{
+		"Klen = 16\n",
+		"Count = 0\nMlen = 2\nTlen = 1\nKey = 2b7e151628aed2a6abf7158809cf4f3c\nMsg = 00\nMac = 00\n",
+		"Count = 0\nMsg = zz\n",
+		"Count = 0\nResult = X\n",
+		"garbage\n",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// TestCorpus runs every .rsp file in testdata, where the official files
+// from cmactestvectors.zip can be dropped. None are shipped.
+func TestCorpus(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.rsp"))
+	if len(files) == 0 {
+		t.Skip("no .rsp files in testdata")
+	}
+	for _, f := range files {
+		r, err := os.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs, err := Parse(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		for _, v := range vs {
+			if err := v.Check(); err != nil {
+				t.Errorf("%s: %v", f, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+// Padding selects how CBC-MAC pads the last block of a message. Together
+// with WithLengthPrefix, for padding method 3, the padding methods of
+// ISO/IEC 9797-1 are covered.
+type Padding int
+
+const (
+	// ZeroPadding appends zero bytes up to a block boundary, and a zero
+	// block for an empty message (ISO/IEC 9797-1 padding method 1).
+	// Messages differing only in trailing zeros have the same MAC.
+	ZeroPadding Padding = iota
+	// ISO7816Padding appends a 0x80 byte and then zero bytes up to a block
+	// boundary (ISO/IEC 7816-4, ISO/IEC 9797-1 padding method 2).
+	ISO7816Padding
+)
+
+// CBCMACOption configures NewCBCMAC.
+type CBCMACOption func(*cbcMAC)
+
+// WithPadding selects the padding of the last block. The default is
+// ZeroPadding.
+func WithPadding(p Padding) CBCMACOption {
+	return func(m *cbcMAC) { m.padding = p }
+}
+
+// WithLengthPrefix prepends a block holding the message length in bits,
+// big-endian, before padding the message with zeros, as in ISO/IEC 9797-1
+// padding method 3; an empty message is not padded. It overrides
+// WithPadding. Since the length must be
+// known first, the message is buffered until Sum.
+func WithLengthPrefix() CBCMACOption {
+	return func(m *cbcMAC) { m.lengthPrefix = true }
+}
+
+type cbcMAC struct {
+	c cipher.Block
+	// final, if set, applies the output transformation of MAC algorithm
+	// 3: the last chaining value is decrypted with final and encrypted
+	// with c again.
+	final        cipher.Block
+	size         int
+	padding      Padding
+	lengthPrefix bool
+
+	x, buf [16]byte
+	cursor int
+	// total counts the bytes written, for empty messages.
+	total int64
+	// msg holds the message when lengthPrefix is set.
+	msg []byte
+}
+
+// NewCBCMAC returns a hash.Hash computing the classic CBC-MAC of ISO/IEC
+// 9797-1 MAC algorithm 1 using c, which must have a block size of 8 or 16
+// bytes, subject to the package Policy set with SetPolicy.
+//
+// CBC-MAC is provided for legacy protocols only. Without WithLengthPrefix
+// it is insecure for messages of varying length: given the MACs of two
+// messages, the MAC of their concatenation can be forged. New designs
+// should use CMAC.
+func NewCBCMAC(c cipher.Block, opts ...CBCMACOption) (hash.Hash, error) {
+	if c == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	m := &cbcMAC{c: c, size: c.BlockSize()}
+	if m.size != 8 && m.size != 16 {
+		return nil, errors.New("cmac: invalid block size")
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	if m.padding != ZeroPadding && m.padding != ISO7816Padding {
+		return nil, errors.New("cmac: invalid padding")
+	}
+
+	p := currentPolicy()
+	if err := p.checkBlockSize(m.size); err != nil {
+		return nil, err
+	}
+	return p.wrap(m), nil
+}
+
+// NewRetailMAC returns a hash.Hash computing ISO/IEC 9797-1 MAC algorithm
+// 3, the Retail MAC of ANSI X9.19 used in EMV and GSM: the CBC-MAC under c,
+// with the last chaining value decrypted under c2 and encrypted under c
+// again. The ciphers must share a block size of 8 or 16 bytes; options are
+// as for NewCBCMAC.
+//
+// Like CBC-MAC it is for legacy protocols only; see NewCBCMAC.
+func NewRetailMAC(c, c2 cipher.Block, opts ...CBCMACOption) (hash.Hash, error) {
+	if c == nil || c2 == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	if c2.BlockSize() != c.BlockSize() {
+		return nil, errors.New("cmac: invalid block size")
+	}
+	return NewCBCMAC(c, append(opts, func(m *cbcMAC) { m.final = c2 })...)
+}
+
+// NewRetailMACDES returns NewRetailMAC with single DES keyed by the two
+// halves of a 16-byte key.
+func NewRetailMACDES(key []byte, opts ...CBCMACOption) (hash.Hash, error) {
+	if len(key) != 16 {
+		return nil, errors.New("cmac: Retail MAC requires a 16-byte DES key")
+	}
+	k, err := des.NewCipher(key[:8])
+	if err != nil {
+		return nil, err
+	}
+	k2, err := des.NewCipher(key[8:])
+	if err != nil {
+		return nil, err
+	}
+	return NewRetailMAC(k, k2, opts...)
+}
+
+func (m *cbcMAC) Size() int      { return m.size }
+func (m *cbcMAC) BlockSize() int { return m.size }
+
+func (m *cbcMAC) Reset() {
+	m.x, m.buf = [16]byte{}, [16]byte{}
+	m.cursor, m.total = 0, 0
+	m.msg = m.msg[:0]
+}
+
+func (m *cbcMAC) Write(b []byte) (int, error) {
+	if m.lengthPrefix {
+		m.msg = append(m.msg, b...)
+		return len(b), nil
+	}
+	m.total += int64(len(b))
+	m.write(b)
+	return len(b), nil
+}
+
+// write continues the chain over b. Only full blocks are encrypted, so the
+// last partial block is kept in buf.
+func (m *cbcMAC) write(b []byte) {
+	for _, v := range b {
+		m.buf[m.cursor] = v
+		if m.cursor++; m.cursor == m.size {
+			m.block(m.buf[:m.size])
+			m.cursor = 0
+		}
+	}
+}
+
+func (m *cbcMAC) block(b []byte) {
+	for i := 0; i < m.size; i++ {
+		m.x[i] ^= b[i]
+	}
+	m.c.Encrypt(m.x[:m.size], m.x[:m.size])
+}
+
+func (m *cbcMAC) Sum(b []byte) []byte {
+	d := *m
+	if d.lengthPrefix {
+		var l [16]byte
+		binary.BigEndian.PutUint64(l[d.size-8:d.size], uint64(len(d.msg))*8)
+		d.block(l[:d.size])
+		d.total = int64(len(d.msg))
+		d.write(d.msg)
+		d.padding = ZeroPadding
+	}
+
+	switch {
+	case d.padding == ISO7816Padding:
+		d.buf[d.cursor] = 0x80
+		for i := d.cursor + 1; i < d.size; i++ {
+			d.buf[i] = 0
+		}
+		d.block(d.buf[:d.size])
+	case d.cursor > 0 || d.total == 0 && !d.lengthPrefix:
+		for i := d.cursor; i < d.size; i++ {
+			d.buf[i] = 0
+		}
+		d.block(d.buf[:d.size])
+	}
+	if d.final != nil {
+		d.final.Decrypt(d.x[:d.size], d.x[:d.size])
+		d.c.Encrypt(d.x[:d.size], d.x[:d.size])
+	}
+	return append(b, d.x[:d.size]...)
+}
@@ -0,0 +1,129 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/binary"
+	"testing"
+)
+
+// cbcLast returns the last block of the CBC encryption of msg under a zero
+// IV.
+func cbcLast(c cipher.Block, msg []byte) []byte {
+	out := make([]byte, len(msg))
+	cipher.NewCBCEncrypter(c, make([]byte, c.BlockSize())).CryptBlocks(out, msg)
+	return out[len(out)-c.BlockSize():]
+}
+
+func TestCBCMAC(t *testing.T) {
+	a, _ := aes.NewCipher(unhex("2b7e151628aed2a6abf7158809cf4f3c"))
+	d, _ := des.NewTripleDESCipher(unhex("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5"))
+
+	for _, c := range []cipher.Block{a, d} {
+		bs := c.BlockSize()
+		for _, n := range []int{0, 1, bs - 1, bs, bs + 1, 3 * bs} {
+			msg := nistmsg[:n]
+			zero := make([]byte, (n+bs-1)/bs*bs)
+			if n == 0 {
+				zero = make([]byte, bs)
+			}
+			copy(zero, msg)
+			iso := make([]byte, (n/bs+1)*bs)
+			copy(iso, msg)
+			iso[n] = 0x80
+			prefixed := make([]byte, bs+(n+bs-1)/bs*bs)
+			binary.BigEndian.PutUint16(prefixed[bs-2:], uint16(n*8))
+			copy(prefixed[bs:], msg)
+
+			for _, tt := range []struct {
+				name   string
+				opts   []CBCMACOption
+				padded []byte
+			}{
+				{"zero", nil, zero},
+				{"iso7816", []CBCMACOption{WithPadding(ISO7816Padding)}, iso},
+				{"prefix", []CBCMACOption{WithLengthPrefix()}, prefixed},
+			} {
+				h, err := NewCBCMAC(c, tt.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Write twice, resetting in between, in uneven pieces.
+				h.Write(msg)
+				h.Reset()
+				for i := 0; i < n; i += 3 {
+					j := i + 3
+					if j > n {
+						j = n
+					}
+					h.Write(msg[i:j])
+				}
+				expected := cbcLast(c, tt.padded)
+				if got := h.Sum(nil); !bytes.Equal(got, expected) {
+					t.Errorf("block size %d, len %d, %s: expected %x got %x", bs, n, tt.name, expected, got)
+				}
+				if got := h.Sum(nil); !bytes.Equal(got, expected) {
+					t.Errorf("block size %d, len %d, %s: Sum changed the state", bs, n, tt.name)
+				}
+			}
+		}
+	}
+
+	if _, err := NewCBCMAC(a, WithPadding(Padding(7))); err == nil {
+		t.Error("expected error for invalid padding")
+	}
+	if _, err := NewCBCMAC(nil); err == nil {
+		t.Error("expected error for nil cipher")
+	}
+}
+
+func TestRetailMAC(t *testing.T) {
+	key := unhex("0123456789abcdeffedcba9876543210")
+	k, _ := des.NewCipher(key[:8])
+	ede, _ := des.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+
+	for _, n := range []int{0, 1, 8, 9, 24} {
+		msg := nistmsg[:n]
+		for _, opts := range [][]CBCMACOption{nil, {WithPadding(ISO7816Padding)}, {WithLengthPrefix()}} {
+			h, err := NewRetailMACDES(key, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Write(msg)
+
+			// The output transformation turns the last step into two-key
+			// triple DES.
+			cbc, _ := NewCBCMAC(k, opts...)
+			cbc.Write(msg)
+			x := cbc.Sum(nil)
+			k.Decrypt(x, x)
+			ede.Encrypt(x, x)
+			if tag := h.Sum(nil); !bytes.Equal(tag, x) {
+				t.Errorf("len %d: expected %x got %x", n, x, tag)
+			}
+		}
+	}
+
+	// ANSI X9.19 example.
+	h, _ := NewRetailMACDES(key)
+	h.Write([]byte("Now is the time for all "))
+	if expected := unhex("a1c72e74ea3fa9b6"); !bytes.Equal(h.Sum(nil), expected) {
+		t.Errorf("X9.19: expected %x got %x", expected, h.Sum(nil))
+	}
+
+	// With both halves equal, the transformation cancels out.
+	same := append(append([]byte(nil), key[:8]...), key[:8]...)
+	h, _ = NewRetailMACDES(same)
+	cbc, _ := NewCBCMAC(k)
+	h.Write(nistmsg[:20])
+	cbc.Write(nistmsg[:20])
+	if x, y := h.Sum(nil), cbc.Sum(nil); !bytes.Equal(x, y) {
+		t.Errorf("expected %x got %x", y, x)
+	}
+
+	if _, err := NewRetailMACDES(key[:8]); err == nil {
+		t.Error("expected error for short key")
+	}
+}
@@ -0,0 +1,171 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// ccm implements CCM as defined in NIST SP800-38C and RFC 3610.
+type ccm struct {
+	c         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewCCM returns a cipher.AEAD implementing CCM mode with the given
+// 128-bit block cipher, as used by IEEE 802.15.4, Zigbee and the BLE data
+// channel. The nonce must be 7 to 13 bytes long; a shorter nonce allows
+// longer messages. The tag size must be even and between 4 and 16 bytes,
+// and at least the Policy.MinTagSize of the package Policy.
+func NewCCM(c cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cmac: CCM requires a 128-bit block cipher")
+	}
+	if nonceSize < 7 || nonceSize > 13 {
+		return nil, errors.New("cmac: invalid CCM nonce size")
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, errors.New("cmac: invalid CCM tag size")
+	}
+	if err := currentPolicy().CheckTagSize(tagSize); err != nil {
+		return nil, err
+	}
+	return &ccm{c: c, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (m *ccm) NonceSize() int { return m.nonceSize }
+func (m *ccm) Overhead() int  { return m.tagSize }
+
+// maxLength returns the longest message the length field can encode.
+func (m *ccm) maxLength() uint64 {
+	q := 15 - m.nonceSize
+	if q >= 8 {
+		return 1<<63 - 1
+	}
+	return 1<<(8*uint(q)) - 1
+}
+
+// counter returns the counter block for nonce with the counter set to 0.
+func (m *ccm) counter(nonce []byte) [16]byte {
+	var a [16]byte
+	a[0] = byte(14 - m.nonceSize)
+	copy(a[1:], nonce)
+	return a
+}
+
+// mac computes the CBC-MAC of the formatted nonce, additional data and
+// plaintext, as in SP800-38C appendix A.
+func (m *ccm) mac(nonce, plaintext, additionalData []byte) [16]byte {
+	var x [16]byte
+	q := 15 - m.nonceSize
+
+	x[0] = byte((m.tagSize-2)/2<<3 | (q - 1))
+	if len(additionalData) > 0 {
+		x[0] |= 0x40
+	}
+	copy(x[1:], nonce)
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(plaintext)))
+	copy(x[16-q:], l[8-q:])
+	m.c.Encrypt(x[:], x[:])
+
+	if len(additionalData) > 0 {
+		var hdr [10]byte
+		var n int
+		switch a := uint64(len(additionalData)); {
+		case a < 1<<16-1<<8:
+			binary.BigEndian.PutUint16(hdr[:], uint16(a))
+			n = 2
+		case a <= 1<<32-1:
+			hdr[0], hdr[1] = 0xff, 0xfe
+			binary.BigEndian.PutUint32(hdr[2:], uint32(a))
+			n = 6
+		default:
+			hdr[0], hdr[1] = 0xff, 0xff
+			binary.BigEndian.PutUint64(hdr[2:], a)
+			n = 10
+		}
+		m.cbc(&x, hdr[:n], additionalData)
+	}
+	if len(plaintext) > 0 {
+		m.cbc(&x, plaintext, nil)
+	}
+	return x
+}
+
+// cbc continues the CBC-MAC in x over a followed by b, zero padded to a
+// full block.
+func (m *ccm) cbc(x *[16]byte, a, b []byte) {
+	i := 0
+	for _, s := range [][]byte{a, b} {
+		for _, v := range s {
+			x[i] ^= v
+			if i++; i == 16 {
+				m.c.Encrypt(x[:], x[:])
+				i = 0
+			}
+		}
+	}
+	if i > 0 {
+		m.c.Encrypt(x[:], x[:])
+	}
+}
+
+func (m *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != m.nonceSize {
+		panic("cmac: incorrect nonce length given to CCM")
+	}
+	if uint64(len(plaintext)) > m.maxLength() {
+		panic("cmac: message too large for CCM")
+	}
+
+	tag := m.mac(nonce, plaintext, additionalData)
+	ctr := m.counter(nonce)
+	var s0 [16]byte
+	m.c.Encrypt(s0[:], ctr[:])
+	for i := range tag {
+		tag[i] ^= s0[i]
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+m.tagSize)
+	ctr[15] = 1
+	cipher.NewCTR(m.c, ctr[:]).XORKeyStream(out, plaintext)
+	copy(out[len(plaintext):], tag[:m.tagSize])
+	return ret
+}
+
+func (m *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != m.nonceSize {
+		panic("cmac: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < m.tagSize || uint64(len(ciphertext)-m.tagSize) > m.maxLength() {
+		return nil, errors.New("cmac: CCM message authentication failed")
+	}
+	tag := ciphertext[len(ciphertext)-m.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-m.tagSize]
+
+	ctr := m.counter(nonce)
+	var s0 [16]byte
+	m.c.Encrypt(s0[:], ctr[:])
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr[15] = 1
+	cipher.NewCTR(m.c, ctr[:]).XORKeyStream(out, ciphertext)
+
+	expected := m.mac(nonce, out, additionalData)
+	for i := range expected {
+		expected[i] ^= s0[i]
+	}
+	if subtle.ConstantTimeCompare(expected[:m.tagSize], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errors.New("cmac: CCM message authentication failed")
+	}
+	return ret, nil
+}
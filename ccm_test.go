@@ -0,0 +1,87 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+var ccmvectors = []struct {
+	key, nonce, ad, pt, ct string
+	tagSize                int
+}{
+	// NIST SP800-38C appendix C.1, example 1.
+	{"404142434445464748494a4b4c4d4e4f", "10111213141516", "0001020304050607", "20212223", "7162015b4dac255d", 4},
+	// RFC 3610 section 8, packet vector #1.
+	{"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf", "00000003020100a0a1a2a3a4a5", "0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+		"588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0", 8},
+}
+
+func TestCCM(t *testing.T) {
+	for i, tv := range ccmvectors {
+		c, _ := aes.NewCipher(unhex(tv.key))
+		nonce := unhex(tv.nonce)
+		a, err := NewCCM(c, len(nonce), tv.tagSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ad, pt, expected := unhex(tv.ad), unhex(tv.pt), unhex(tv.ct)
+
+		ct := a.Seal(nil, nonce, pt, ad)
+		if !bytes.Equal(ct, expected) {
+			t.Errorf("tv[%d]: Seal: expected: %x got %x\n", i, expected, ct)
+		}
+		out, err := a.Open(nil, nonce, ct, ad)
+		if err != nil {
+			t.Fatalf("tv[%d]: %s", i, err)
+		}
+		if !bytes.Equal(out, pt) {
+			t.Errorf("tv[%d]: Open: expected: %x got %x\n", i, pt, out)
+		}
+
+		ct[0] ^= 1
+		if _, err := a.Open(nil, nonce, ct, ad); err == nil {
+			t.Errorf("tv[%d]: modified ciphertext accepted", i)
+		}
+		if _, err := a.Open(nil, nonce, expected, ad[1:]); err == nil {
+			t.Errorf("tv[%d]: modified additional data accepted", i)
+		}
+	}
+}
+
+func TestCCMRoundTrip(t *testing.T) {
+	c, _ := aes.NewCipher(make([]byte, 16))
+	a, err := NewCCM(c, 7, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, 7)
+	for _, n := range []int{0, 1, 15, 16, 17, 300} {
+		for _, m := range []int{0, 1, 14, 15, 16, 65280} {
+			pt, ad := make([]byte, n), make([]byte, m)
+			ct := a.Seal(nil, nonce, pt, ad)
+			if len(ct) != n+16 {
+				t.Fatalf("unexpected ciphertext length %d", len(ct))
+			}
+			if out, err := a.Open(nil, nonce, ct, ad); err != nil || !bytes.Equal(out, pt) {
+				t.Errorf("pt %d ad %d: round trip failed: %v", n, m, err)
+			}
+		}
+	}
+
+	for _, args := range [][2]int{{6, 8}, {14, 8}, {12, 5}, {12, 2}, {12, 18}} {
+		if _, err := NewCCM(c, args[0], args[1]); err == nil {
+			t.Errorf("expected error for nonce size %d and tag size %d", args[0], args[1])
+		}
+	}
+
+	SetPolicy(&Policy{MinTagSize: 8})
+	defer SetPolicy(nil)
+	if _, err := NewCCM(c, 13, 4); err == nil {
+		t.Error("4-byte tag accepted under an 8-byte minimum")
+	}
+	if _, err := NewCCM(c, 13, 8); err != nil {
+		t.Errorf("8-byte tag: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package chaskey implements the Chaskey MAC of Mouha et al., a lightweight
+// permutation-based MAC for 32-bit microcontrollers whose mode of operation
+// mirrors CMAC. Both the original 8-round Chaskey and the 12-round
+// Chaskey-12 are provided.
+package chaskey
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+// Size is the size of a Chaskey tag in bytes.
+const Size = 16
+
+// BlockSize is the block size of Chaskey in bytes.
+const BlockSize = 16
+
+type digest struct {
+	rounds    int
+	k, k1, k2 [4]uint32
+	v         [4]uint32
+	buf       [BlockSize]byte
+	cursor    int
+}
+
+func timesTwo(in [4]uint32) [4]uint32 {
+	c := uint32(0x87) & -(in[3] >> 31)
+	return [4]uint32{
+		in[0]<<1 ^ c,
+		in[1]<<1 | in[0]>>31,
+		in[2]<<1 | in[1]>>31,
+		in[3]<<1 | in[2]>>31,
+	}
+}
+
+func newDigest(key []byte, rounds int) (hash.Hash, error) {
+	if len(key) != 16 {
+		return nil, errors.New("chaskey: invalid key size")
+	}
+
+	d := &digest{rounds: rounds}
+	for i := range d.k {
+		d.k[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	d.k1 = timesTwo(d.k)
+	d.k2 = timesTwo(d.k1)
+	d.Reset()
+	return d, nil
+}
+
+// New returns a hash.Hash computing the original 8-round Chaskey with a
+// 16-byte key.
+func New(key []byte) (hash.Hash, error) {
+	return newDigest(key, 8)
+}
+
+// New12 returns a hash.Hash computing Chaskey-12, the 12-round variant
+// recommended by the designers for new applications.
+func New12(key []byte) (hash.Hash, error) {
+	return newDigest(key, 12)
+}
+
+func (d *digest) permute(v *[4]uint32) {
+	v0, v1, v2, v3 := v[0], v[1], v[2], v[3]
+	for i := 0; i < d.rounds; i++ {
+		v0 += v1
+		v1 = bits.RotateLeft32(v1, 5) ^ v0
+		v0 = bits.RotateLeft32(v0, 16)
+		v2 += v3
+		v3 = bits.RotateLeft32(v3, 8) ^ v2
+		v0 += v3
+		v3 = bits.RotateLeft32(v3, 13) ^ v0
+		v2 += v1
+		v1 = bits.RotateLeft32(v1, 7) ^ v2
+		v2 = bits.RotateLeft32(v2, 16)
+	}
+	v[0], v[1], v[2], v[3] = v0, v1, v2, v3
+}
+
+func (d *digest) absorb(v *[4]uint32, b []byte) {
+	for i := range v {
+		v[i] ^= binary.LittleEndian.Uint32(b[4*i:])
+	}
+}
+
+func (d *digest) Write(b []byte) (int, error) {
+	totLen := len(b)
+
+	n := copy(d.buf[d.cursor:], b)
+	d.cursor += n
+	b = b[n:]
+
+	for len(b) > 0 {
+		d.absorb(&d.v, d.buf[:])
+		d.permute(&d.v)
+
+		d.cursor = copy(d.buf[:], b)
+		b = b[d.cursor:]
+	}
+
+	return totLen, nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	v := d.v
+	l := &d.k1
+
+	last := d.buf
+	if d.cursor < BlockSize {
+		last[d.cursor] = 0x01
+		for i := d.cursor + 1; i < BlockSize; i++ {
+			last[i] = 0
+		}
+		l = &d.k2
+	}
+
+	d.absorb(&v, last[:])
+	for i := range v {
+		v[i] ^= l[i]
+	}
+	d.permute(&v)
+
+	var tag [Size]byte
+	for i := range v {
+		binary.LittleEndian.PutUint32(tag[4*i:], v[i]^l[i])
+	}
+	return append(b, tag[:]...)
+}
+
+// Verify reports whether tag is the MAC of the data written so far, using
+// a constant-time comparison. Only full-length tags are accepted. It does
+// not change the underlying state. Hashes returned by New and New12 thus
+// satisfy cmac.MAC through a type assertion.
+func (d *digest) Verify(tag []byte) bool {
+	var t [Size]byte
+	return subtle.ConstantTimeCompare(d.Sum(t[:0]), tag) == 1
+}
+
+func (d *digest) Reset() {
+	d.v = d.k
+	d.buf = [BlockSize]byte{}
+	d.cursor = 0
+}
+
+func (d *digest) Size() int      { return Size }
+func (d *digest) BlockSize() int { return BlockSize }
@@ -0,0 +1,120 @@
+// Package ckdf implements an extract-then-expand key derivation function
+// shaped like HKDF (RFC 5869) with AES-CMAC in place of HMAC, for
+// platforms that accelerate AES but not SHA-2.
+//
+// Extract is AES-CMAC-PRF-128 of RFC 4615: the salt is used as the
+// AES-128 key, after being compressed with AES-CMAC under the zero key if
+// it is not 16 bytes long. Expand computes
+//
+//	T(0) = empty
+//	T(i) = AES-CMAC(PRK, T(i-1) || info || i)
+//
+// with i a single byte, exactly like HKDF-Expand.
+//
+// The construction has limits that HKDF does not. The PRK, and so the
+// strength of all derived keys, is 128 bits. Expand produces at most 255
+// blocks, 4080 bytes. Most importantly, CMAC is a PRF but not a proven
+// randomness extractor: Extract is suitable for input keying material that
+// is already close to uniform, such as a Diffie-Hellman shared secret
+// hashed elsewhere or a random key, but should not be relied on to
+// condense low-entropy or attacker-influenced input.
+package ckdf
+
+import (
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/joekir/cmac"
+)
+
+// Size is the size of a pseudorandom key from Extract, in bytes.
+const Size = 16
+
+// MaxOutput is the largest number of bytes Expand can produce.
+const MaxOutput = 255 * Size
+
+// Extract returns a pseudorandom key from secret and salt. An empty salt
+// is the same as 16 zero bytes.
+func Extract(salt, secret []byte) ([]byte, error) {
+	key := make([]byte, Size)
+	if len(salt) == Size {
+		copy(key, salt)
+	} else if len(salt) > 0 {
+		t, err := cmac.Sum(key, salt)
+		if err != nil {
+			return nil, err
+		}
+		copy(key, t[:])
+	}
+	t, err := cmac.Sum(key, secret)
+	if err != nil {
+		return nil, err
+	}
+	return t[:], nil
+}
+
+type expander struct {
+	h       hash.Hash
+	info    []byte
+	counter byte
+	prev    []byte
+	buf     []byte
+}
+
+// Expand returns a Reader from which up to MaxOutput bytes of keying
+// material for info can be read, using the 16-byte pseudorandom key prk.
+// Reads beyond MaxOutput return an error.
+func Expand(prk, info []byte) (io.Reader, error) {
+	if len(prk) != Size {
+		return nil, errors.New("ckdf: invalid pseudorandom key size")
+	}
+	h, err := cmac.New(prk)
+	if err != nil {
+		return nil, err
+	}
+	return &expander{h: h, info: append([]byte(nil), info...)}, nil
+}
+
+func (e *expander) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(e.buf) == 0 {
+			if e.counter == 255 {
+				return n, errors.New("ckdf: entropy limit reached")
+			}
+			e.counter++
+			e.h.Reset()
+			e.h.Write(e.prev)
+			e.h.Write(e.info)
+			e.h.Write([]byte{e.counter})
+			e.prev = e.h.Sum(e.prev[:0])
+			e.buf = e.prev
+		}
+		c := copy(p[n:], e.buf)
+		e.buf = e.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Key derives an n-byte key from secret, salt and info with Extract and
+// Expand.
+func Key(secret, salt, info []byte, n int) ([]byte, error) {
+	if n < 0 || n > MaxOutput {
+		return nil, errors.New("ckdf: invalid output length")
+	}
+	prk, err := Extract(salt, secret)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Expand(prk, info)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, n)
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package ckdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestExtract(t *testing.T) {
+	// RFC 4615 section 4.
+	msg := unhex("000102030405060708090a0b0c0d0e0f10111213")
+	tests := []struct{ key, prf string }{
+		{"000102030405060708090a0b0c0d0e0fedcb", "84a348a4a45d235babfffc0d2b4da09a"},
+		{"000102030405060708090a0b0c0d0e0f", "980ae87b5f4c9c5214f5b6a8455e4c2d"},
+		{"00010203040506070809", "290d9e112edb09ee141fcf64c0b72f3d"},
+	}
+	for _, tt := range tests {
+		prk, err := Extract(unhex(tt.key), msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := unhex(tt.prf); !bytes.Equal(prk, expected) {
+			t.Errorf("key %s: expected %x got %x", tt.key, expected, prk)
+		}
+	}
+
+	a, _ := Extract(nil, msg)
+	b, _ := Extract(make([]byte, 16), msg)
+	if !bytes.Equal(a, b) {
+		t.Error("empty salt differs from zero salt")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	prk := unhex("980ae87b5f4c9c5214f5b6a8455e4c2d")
+	info := []byte("info")
+	r, err := Expand(prk, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 40)
+	// Read in uneven pieces.
+	io.ReadFull(r, out[:5])
+	io.ReadFull(r, out[5:])
+
+	var expected, prev []byte
+	h, _ := cmac.New(prk)
+	for i := byte(1); i <= 3; i++ {
+		h.Reset()
+		h.Write(prev)
+		h.Write(info)
+		h.Write([]byte{i})
+		prev = h.Sum(nil)
+		expected = append(expected, prev...)
+	}
+	if !bytes.Equal(out, expected[:40]) {
+		t.Errorf("expected %x got %x", expected[:40], out)
+	}
+
+	r, _ = Expand(prk, info)
+	if n, err := io.ReadFull(r, make([]byte, MaxOutput+1)); err == nil || n != MaxOutput {
+		t.Errorf("read %d bytes past the limit, err %v", n, err)
+	}
+	if _, err := Expand(prk[:8], info); err == nil {
+		t.Error("expected error for short key")
+	}
+
+	k, err := Key([]byte("secret"), []byte("salt"), info, 32)
+	if err != nil || len(k) != 32 {
+		t.Errorf("Key: %x, %v", k, err)
+	}
+}
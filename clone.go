@@ -0,0 +1,39 @@
+package cmac
+
+import (
+	"errors"
+	"hash"
+)
+
+// cloneHash returns a copy of m with the same running state, so that MACs
+// of several messages sharing a prefix can branch off after the prefix.
+// A hash built by NewWithCipherFactory gets a new cipher from the factory;
+// otherwise the copy shares the cipher of m, as hashes from the same
+// NewWithCipher cipher do.
+func (m *cmac) cloneHash() (hash.Hash, error) {
+	d := *m
+	d.cbc = nil
+	if m.factory != nil {
+		c, err := m.factory()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || c.BlockSize() != m.size {
+			return nil, errors.New("cmac: cipher factory changed block size")
+		}
+		d.c = c
+	}
+	return &d, nil
+}
+
+func (l *limited) cloneHash() (hash.Hash, error) {
+	c, ok := l.Hash.(interface{ cloneHash() (hash.Hash, error) })
+	if !ok {
+		return nil, errors.New("cmac: hash cannot be cloned")
+	}
+	h, err := c.cloneHash()
+	if err != nil {
+		return nil, err
+	}
+	return &limited{Hash: h, n: l.n, max: l.max}, nil
+}
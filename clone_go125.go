@@ -0,0 +1,24 @@
+//go:build go1.25
+
+package cmac
+
+import "hash"
+
+// Clone implements hash.Cloner. It returns an independent copy of the
+// hash with the same running state. Hashes from NewWithCipherFactory get a
+// new cipher from their factory; the others share their cipher.
+func (m *cmac) Clone() (hash.Cloner, error) {
+	h, err := m.cloneHash()
+	if err != nil {
+		return nil, err
+	}
+	return h.(hash.Cloner), nil
+}
+
+func (l *limited) Clone() (hash.Cloner, error) {
+	h, err := l.cloneHash()
+	if err != nil {
+		return nil, err
+	}
+	return h.(hash.Cloner), nil
+}
@@ -0,0 +1,25 @@
+//go:build go1.25
+
+package cmac
+
+import (
+	"bytes"
+	"hash"
+	"testing"
+)
+
+func TestCloner(t *testing.T) {
+	h, err := New(nistvectors[0].key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(nistmsg[:16])
+	c, err := h.(hash.Cloner).Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write(nistmsg[16:40])
+	if !bytes.Equal(c.Sum(nil), nistvectors[0].cases[2].mac) {
+		t.Error("clone computes the wrong MAC")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !go1.25
+
+package cmac
+
+import "hash"
+
+// Clone returns an independent copy of the hash with the same running
+// state. Hashes from NewWithCipherFactory get a new cipher from their
+// factory; the others share their cipher. From Go 1.25 on, Clone
+// implements hash.Cloner instead.
+func (m *cmac) Clone() (hash.Hash, error) {
+	return m.cloneHash()
+}
+
+func (l *limited) Clone() (hash.Hash, error) {
+	return l.cloneHash()
+}
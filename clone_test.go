@@ -0,0 +1,65 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	for _, tv := range nistvectors {
+		for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+			h, err := newWithCipher(mustCipher(t, tv), p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Write(nistmsg[:20])
+			c, err := h.(interface{ cloneHash() (hash.Hash, error) }).cloneHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Branching one copy leaves the other untouched.
+			want := h.Sum(nil)
+			c.Write([]byte("suffix"))
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Error("writing to the clone changed the original")
+			}
+			h.Write([]byte("suffix"))
+			if got, want := c.Sum(nil), h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("clone computes %x, want %x", got, want)
+			}
+		}
+	}
+}
+
+func TestCloneFactory(t *testing.T) {
+	tv := nistvectors[0]
+	calls := 0
+	h, err := NewWithCipherFactory(func() (cipher.Block, error) {
+		calls++
+		return tv.cipher(tv.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(nistmsg[:40])
+	c, err := h.(interface{ cloneHash() (hash.Hash, error) }).cloneHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || c.(*cmac).c == h.(*cmac).c {
+		t.Errorf("clone did not get its own cipher (%d factory calls)", calls)
+	}
+	if !bytes.Equal(c.Sum(nil), tv.cases[2].mac) {
+		t.Error("clone computes the wrong MAC")
+	}
+
+	fail := errors.New("no handle left")
+	h.(*cmac).factory = func() (cipher.Block, error) { return nil, fail }
+	if _, err := h.(interface{ cloneHash() (hash.Hash, error) }).cloneHash(); err != fail {
+		t.Errorf("got %v, want the factory error", err)
+	}
+}
@@ -14,28 +14,41 @@ const (
 	_Rb64  = 0x1b
 )
 
-func shifted(x []byte) []byte {
-	d := make([]byte, len(x))
-	copy(d, x)
-	for i := range d {
-		if i > 0 {
-			d[i-1] |= d[i] >> 7
-		}
-		d[i] <<= 1
+// dbl sets d to x multiplied by u in GF(2^n), where n is len(x)*8 and rb
+// is the field's reduction constant. d and x may be the same slice.
+func dbl(d, x []byte, rb byte) {
+	msb := x[0] >> 7
+	for i := 0; i < len(x)-1; i++ {
+		d[i] = x[i]<<1 | x[i+1]>>7
 	}
-	return d
+	d[len(x)-1] = x[len(x)-1]<<1 ^ byte(subtle.ConstantTimeSelect(int(msb), int(rb), 0))
 }
 
-func gensubkey(c cipher.Block, l []byte, rb byte) []byte {
-	sk := shifted(l)
-	sk[len(sk)-1] ^= byte(subtle.ConstantTimeSelect(int(l[0]>>7), int(rb), 0))
-	return sk
+// Dbl sets dst to x doubled in GF(2^128) or GF(2^64), depending on whether
+// x is 16 or 8 bytes long, with the reduction constants of SP800-38B (0x87
+// and 0x1b). This is the doubling used for CMAC subkeys, S2V and PMAC. dst
+// must be at least as long as x and may be the same slice; Dbl panics for
+// any other length of x. It runs in constant time.
+func Dbl(dst, x []byte) {
+	if len(dst) < len(x) {
+		panic("cmac: output smaller than input")
+	}
+	switch len(x) {
+	case 16:
+		dbl(dst, x, _Rb128)
+	case 8:
+		dbl(dst, x, _Rb64)
+	default:
+		panic("cmac: invalid block size")
+	}
 }
 
-func gensubkeys(c cipher.Block) ([]byte, []byte) {
+// subkeys computes the CMAC subkeys for c into k1 and k2, which must be
+// c.BlockSize() bytes long.
+func subkeys(c cipher.Block, k1, k2 []byte) {
 	var rb byte
 
-	switch c.BlockSize() {
+	switch len(k1) {
 	case 16:
 		rb = _Rb128
 	case 8:
@@ -45,120 +58,274 @@ func gensubkeys(c cipher.Block) ([]byte, []byte) {
 
 	}
 
-	l := make([]byte, c.BlockSize())
-	c.Encrypt(l, l)
+	for i := range k1 {
+		k1[i] = 0
+	}
+	c.Encrypt(k1, k1)
 
-	k1 := gensubkey(c, l, rb)
-	return k1, gensubkey(c, k1, rb)
+	dbl(k1, k1, rb)
+	dbl(k2, k1, rb)
 }
 
-type cmac struct {
+func gensubkeys(c cipher.Block) ([]byte, []byte) {
+	k1 := make([]byte, c.BlockSize())
+	k2 := make([]byte, c.BlockSize())
+	subkeys(c, k1, k2)
+	return k1, k2
+}
+
+// State is the state of a CMAC computation. It holds no pointers other
+// than the underlying cipher.Block, so it can be embedded in other structs
+// and initialized, used and reset without allocating. A State must be
+// initialized with Init before use.
+type State struct {
 	c      cipher.Block
-	k1, k2 []byte
-	buf, x []byte
+	size   int
+	k1, k2 [16]byte
+	buf, x [16]byte
 	cursor int
+	// out is where Sum computes the tag. Only the cipher sees it, so the
+	// caller's slice doesn't escape.
+	out [16]byte
 }
 
-func newcmac(c cipher.Block) *cmac {
-	k1, k2 := gensubkeys(c)
-	buf := make([]byte, c.BlockSize())
-	x := make([]byte, c.BlockSize())
-	m := &cmac{c: c, k1: k1, k2: k2, buf: buf, x: x}
-	m.Reset()
-	return m
+// Init initializes s to compute CMAC using the given cipher.Block. The
+// block cipher should have a block length of 8 or 16 bytes.
+func (s *State) Init(c cipher.Block) error {
+	if c == nil {
+		return errors.New("cmac: nil cipher")
+	}
+
+	size := c.BlockSize()
+	switch size {
+	case 8, 16:
+	default:
+		return errors.New("cmac: invalid blocksize")
+	}
+	if c.BlockSize() != size {
+		return errors.New("cmac: cipher block size changed between calls")
+	}
+
+	*s = State{c: c, size: size}
+	subkeys(c, s.k1[:s.size], s.k2[:s.size])
+	return nil
 }
 
-func (m *cmac) Write(b []byte) (int, error) {
+// Write adds more data to the running MAC. It never returns an error.
+func (s *State) Write(b []byte) (int, error) {
+	return s.write(b, nil)
+}
+
+// write is Write, with a place to cache a CBC encrypter for long runs of
+// blocks if cbc is not nil.
+func (s *State) write(b []byte, cbc **cbcCache) (int, error) {
 	totLen := len(b)
+	buf, x := s.buf[:s.size], s.x[:s.size]
 
-	n := copy(m.buf[m.cursor:], b)
-	m.cursor += n
+	n := copy(buf[s.cursor:], b)
+	s.cursor += n
 	b = b[n:]
+	if len(b) == 0 {
+		return totLen, nil
+	}
 
-	for len(b) > 0 {
-		for i := range m.buf {
-			m.buf[i] ^= m.x[i]
-		}
-		m.c.Encrypt(m.x, m.buf)
+	// The buffer is full and more data follows, so it isn't the last
+	// block. For 8-byte blocks the upper halves of x and buf stay zero, so
+	// XORing all 16 bytes is fine.
+	xor16(&s.x, &s.buf)
+	s.c.Encrypt(x, x)
 
-		m.cursor = copy(m.buf, b)
-		b = b[m.cursor:]
+	// Process all remaining full blocks but the last, which has to stay
+	// buffered until Sum.
+	if n := (len(b) - 1) / s.size * s.size; n > 0 {
+		blocks(s, b[:n], cbc)
+		b = b[n:]
 	}
+	s.cursor = copy(buf, b)
 
 	return totLen, nil
 }
 
-func (m *cmac) Sum(b []byte) []byte {
-	n := len(b)
-	// I'm not sure why we need to do this: the second argument of
-	// 	append(b, make([]byte, m.c.BlockSize())...)
-	// shouldn't escape, so I'm not sure why it ends up getting heap
-	// allocated (as of Go 1.4.2 at least).
-	switch m.c.BlockSize() {
-	case 8:
-		b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
-	case 16:
-		b = append(b, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
-	default:
-		panic("unexpected block size")
-	}
-	scratch := b[n:]
-
-	if m.cursor == m.c.BlockSize() {
-		for i := range scratch {
-			scratch[i] = m.buf[i] ^ m.k1[i]
-		}
+// blocksGeneric chains the full blocks in b into the running MAC one block
+// at a time.
+func blocksGeneric(s *State, b []byte) {
+	if s.size == 16 {
+		chain16(s.c, &s.x, b)
 	} else {
-		for i := 0; i < m.cursor; i++ {
-			scratch[i] = m.buf[i] ^ m.k2[i]
-		}
-		scratch[m.cursor] = 0x80 ^ m.k2[m.cursor]
-		for i := m.cursor + 1; i < len(m.buf); i++ {
-			scratch[i] = m.k2[i]
-		}
+		chain8(s.c, (*[8]byte)(s.x[:8]), b)
 	}
+}
+
+// chain16 and chain8 are blocksGeneric for each block size. Working on
+// arrays of constant length lets the compiler drop the bounds checks, and
+// the XOR is done a word at a time.
 
-	for i := range scratch {
-		scratch[i] ^= m.x[i]
+func chain16(c cipher.Block, x *[16]byte, b []byte) {
+	for len(b) >= 16 {
+		xor16(x, (*[16]byte)(b[:16]))
+		c.Encrypt(x[:], x[:])
+		b = b[16:]
 	}
-	m.c.Encrypt(scratch, scratch)
+}
 
-	return b
+func chain8(c cipher.Block, x *[8]byte, b []byte) {
+	for len(b) >= 8 {
+		xor8(x, (*[8]byte)(b[:8]))
+		c.Encrypt(x[:], x[:])
+		b = b[8:]
+	}
 }
 
-func (m *cmac) Reset() {
-	for i := 0; i < m.c.BlockSize(); i++ {
-		m.buf[i] = 0
-		m.x[i] = 0
+// Sum appends the current MAC to b and returns the resulting slice. It
+// does not change the underlying state, and does not allocate if b has
+// room for the MAC.
+func (s *State) Sum(b []byte) []byte {
+	// As in Write, the bytes past the block size are zero throughout.
+	s.out = s.x
+	if s.cursor == s.size {
+		xor16(&s.out, &s.buf)
+		xor16(&s.out, &s.k1)
+	} else {
+		var last [16]byte
+		copy(last[:], s.buf[:s.cursor])
+		last[s.cursor] = 0x80
+		xor16(&s.out, &last)
+		xor16(&s.out, &s.k2)
 	}
-	m.cursor = 0
+	out := s.out[:s.size]
+	s.c.Encrypt(out, out)
+	return append(b, out...)
+}
+
+// Verify reports whether tag is the MAC of the data written so far, using
+// a constant-time comparison. Only full-length tags are accepted. It does
+// not change the underlying state.
+//
+// Hashes returned by New and NewWithCipher have the same method, which can
+// be reached with a type assertion to interface{ Verify([]byte) bool }.
+func (s *State) Verify(tag []byte) bool {
+	var t [16]byte
+	return subtle.ConstantTimeCompare(s.Sum(t[:0]), tag) == 1
+}
+
+// Reset resets the State to its initial, keyed state.
+func (s *State) Reset() {
+	s.buf = [16]byte{}
+	s.x = [16]byte{}
+	s.cursor = 0
+}
+
+// Size returns the length of the MAC, which is the cipher's block size.
+func (s *State) Size() int {
+	return s.size
+}
+
+// BlockSize returns the cipher's block size.
+func (s *State) BlockSize() int {
+	return s.size
+}
+
+type cmac struct {
+	State
+
+	// factory, if set, created the cipher of this instance and is used
+	// for any copies of it.
+	factory func() (cipher.Block, error)
+	// cbc is allocated on the first long write with a crypto/aes cipher.
+	cbc *cbcCache
 }
 
-func (m *cmac) Size() int {
-	return m.c.BlockSize()
+func (m *cmac) Write(b []byte) (int, error) {
+	return m.write(b, &m.cbc)
+}
+
+// Implementation returns the name of the code path used to process
+// message blocks for AES-CMAC from New: "hardware-aes-cbc" when crypto/aes
+// uses hardware AES instructions, in which case long runs of blocks go
+// through its multi-block CBC encryption, and "generic" when every block
+// is encrypted and chained on its own. Builds with the purego tag always
+// use "generic". Ciphers passed to NewWithCipher that offer multi-block
+// CBC encryption are driven through it instead.
+func Implementation() string {
+	return implementation()
 }
 
-func (m *cmac) BlockSize() int {
-	return m.c.BlockSize()
+// HasAcceleration reports whether AES-CMAC from New runs on hardware AES
+// instructions, so deployments can check at startup that they didn't lose
+// AES-NI or its equivalent.
+func HasAcceleration() bool {
+	return hasAES
 }
 
-// New returns a hash.Hash computing AES-CMAC.
+// New returns a hash.Hash computing AES-CMAC, subject to the package
+// Policy set with SetPolicy.
 func New(key []byte) (hash.Hash, error) {
+	return newAES(key, currentPolicy())
+}
+
+// NewWithCipher returns a hash.Hash computing CMAC using the given
+// cipher.Block, subject to the package Policy set with SetPolicy. The block
+// cipher should have a block length of 8 or 16 bytes. An error is returned
+// for a nil cipher or one whose BlockSize is not constant.
+func NewWithCipher(c cipher.Block) (hash.Hash, error) {
+	return newWithCipher(c, currentPolicy())
+}
+
+// NewWithCipherFactory returns a hash.Hash computing CMAC using a
+// cipher.Block created by newCipher for this instance alone. This suits
+// backends whose ciphers are not safe for concurrent use, such as some HSM
+// or kernel handles: every hash built from the same factory gets its own
+// cipher.
+func NewWithCipherFactory(newCipher func() (cipher.Block, error)) (hash.Hash, error) {
+	if newCipher == nil {
+		return nil, errors.New("cmac: nil cipher factory")
+	}
+	c, err := newCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	p := currentPolicy()
+	m, err := newCMAC(c, p)
+	if err != nil {
+		return nil, err
+	}
+	m.factory = newCipher
+	return p.wrap(m), nil
+}
+
+func newAES(key []byte, p *Policy) (hash.Hash, error) {
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewWithCipher(c)
+	return newWithCipher(c, p)
 }
 
-// NewWithCipher returns a hash.Hash computing CMAC using the given
-// cipher.Block. The block cipher should have a block length of 8 or 16 bytes.
-func NewWithCipher(c cipher.Block) (hash.Hash, error) {
-	switch c.BlockSize() {
-	case 8, 16:
-		return newcmac(c), nil
-	default:
-		return nil, errors.New("cmac: invalid blocksize")
+func newWithCipher(c cipher.Block, p *Policy) (hash.Hash, error) {
+	m, err := newCMAC(c, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(m), nil
+}
+
+// newCMAC returns the hash for c before the Policy wrapper is applied.
+func newCMAC(c cipher.Block, p *Policy) (*cmac, error) {
+	m := &cmac{}
+	if err := m.Init(c); err != nil {
+		return nil, err
+	}
+	if err := p.checkBlockSize(m.size); err != nil {
+		return nil, err
 	}
+	return m, nil
 }
@@ -0,0 +1,49 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// Size96 is the tag size of AES-CMAC-96 in bytes.
+const Size96 = 12
+
+// New96 returns a hash.Hash computing AES-CMAC-96 as specified for IPsec
+// in RFC 4494: AES-CMAC with a 128-bit key, truncated to its first 96
+// bits. It is subject to the package Policy set with SetPolicy.
+func New96(key []byte) (hash.Hash, error) {
+	if len(key) != 16 {
+		return nil, errors.New("cmac: AES-CMAC-96 requires a 128-bit key")
+	}
+	p := currentPolicy()
+	if err := p.CheckTagSize(Size96); err != nil {
+		return nil, err
+	}
+	h, err := newAES(key, p)
+	if err != nil {
+		return nil, err
+	}
+	return &truncated{Hash: h, size: Size96}, nil
+}
+
+// truncated is a hash.Hash whose tags are the first size bytes of those of
+// the underlying hash.
+type truncated struct {
+	hash.Hash
+	size int
+}
+
+func (t *truncated) Size() int { return t.size }
+
+func (t *truncated) Sum(b []byte) []byte {
+	var full [16]byte
+	return append(b, t.Hash.Sum(full[:0])[:t.size]...)
+}
+
+// Verify reports whether tag is the truncated MAC of the data written so
+// far, using a constant-time comparison.
+func (t *truncated) Verify(tag []byte) bool {
+	var full [16]byte
+	return subtle.ConstantTimeCompare(t.Sum(full[:0]), tag) == 1
+}
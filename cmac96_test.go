@@ -0,0 +1,49 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNew96(t *testing.T) {
+	// RFC 4494 section 4.
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	tests := []struct {
+		n   int
+		tag string
+	}{
+		{0, "bb1d6929e95937287fa37d12"},
+		{16, "070a16b46b4d4144f79bdd9d"},
+		{40, "dfa66747de9ae63030ca3261"},
+		{64, "51f0bebf7e3b9d92fc497417"},
+	}
+
+	h, err := New96(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Size() != Size96 {
+		t.Errorf("expected size %d, got %d", Size96, h.Size())
+	}
+	for _, tt := range tests {
+		h.Reset()
+		h.Write(msg[:tt.n])
+		expected := unhex(tt.tag)
+		if tag := h.Sum([]byte{0xff}); !bytes.Equal(tag[1:], expected) || tag[0] != 0xff {
+			t.Errorf("len %d: expected %x got %x", tt.n, expected, tag[1:])
+		}
+		if !h.(interface{ Verify([]byte) bool }).Verify(expected) {
+			t.Errorf("len %d: Verify rejected valid tag", tt.n)
+		}
+	}
+
+	if _, err := New96(make([]byte, 32)); err == nil {
+		t.Error("expected error for 256-bit key")
+	}
+	SetPolicy(&Policy{MinTagSize: 16})
+	defer SetPolicy(nil)
+	if _, err := New96(key); err == nil {
+		t.Error("expected error under a policy requiring full tags")
+	}
+}
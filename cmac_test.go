@@ -2,9 +2,20 @@ package cmac
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
 	"testing"
 )
 
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
 func TestGenSubkeys(t *testing.T) {
 	for i, tv := range nistvectors {
 		c, err := tv.cipher(tv.key)
@@ -88,6 +99,16 @@ func TestChunkedWrites(t *testing.T) {
 	}
 }
 
+func TestImplementation(t *testing.T) {
+	impl := Implementation()
+	if impl != "generic" && impl != "hardware-aes-cbc" {
+		t.Errorf("unexpected implementation %q", impl)
+	}
+	if HasAcceleration() != (impl == "hardware-aes-cbc") {
+		t.Errorf("HasAcceleration() = %v with implementation %q", HasAcceleration(), impl)
+	}
+}
+
 func benchmarkHash(b *testing.B, size int64) {
 	buf := make([]byte, size)
 	h, _ := New(make([]byte, 128/8))
@@ -106,3 +127,174 @@ func benchmarkHash(b *testing.B, size int64) {
 
 func BenchmarkHash1K(b *testing.B) { benchmarkHash(b, 1<<10) }
 func BenchmarkHash1M(b *testing.B) { benchmarkHash(b, 1<<20) }
+
+func TestState(t *testing.T) {
+	for i, tv := range nistvectors {
+		c, err := tv.cipher(tv.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var s State
+		if err := s.Init(c); err != nil {
+			t.Fatalf("tv[%d]: Init() err: %s\n", i, err)
+		}
+		for j, tc := range tv.cases {
+			s.Write(tc.msg)
+			mac := s.Sum(nil)
+			if !bytes.Equal(mac, tc.mac) {
+				t.Errorf("tv[%d,%d]: expected: %x got %x\n", i, j, tc.mac, mac)
+			}
+			s.Reset()
+		}
+	}
+}
+
+func TestStateAllocs(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, 100)
+	sum := make([]byte, 0, 16)
+
+	s := new(State)
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Init(c)
+		s.Write(msg)
+		s.Sum(sum)
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocs, got %v", allocs)
+	}
+
+	// The tag is computed inside the State, so a stack buffer doesn't
+	// escape to the cipher.
+	tag := s.Sum(nil)
+	allocs = testing.AllocsPerRun(100, func() {
+		var b [16]byte
+		s.Sum(b[:0])
+		s.Verify(tag)
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocs for Sum into a stack buffer and Verify, got %v", allocs)
+	}
+}
+
+// badCipher is a cipher.Block whose BlockSize returns successive values
+// from sizes.
+type badCipher struct {
+	sizes []int
+}
+
+func (c *badCipher) BlockSize() int {
+	n := c.sizes[0]
+	if len(c.sizes) > 1 {
+		c.sizes = c.sizes[1:]
+	}
+	return n
+}
+
+func (c *badCipher) Encrypt(dst, src []byte) { copy(dst, src) }
+func (c *badCipher) Decrypt(dst, src []byte) { copy(dst, src) }
+
+func TestConstructorValidation(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("New(nil): expected error")
+	}
+	if _, err := New(make([]byte, 15)); err == nil {
+		t.Error("New(15-byte key): expected error")
+	}
+	if _, err := NewWithCipher(nil); err == nil {
+		t.Error("NewWithCipher(nil): expected error")
+	}
+
+	for _, sizes := range [][]int{{0}, {-1}, {1}, {32}, {16, 8}, {8, 16}, {16, 32}} {
+		if _, err := NewWithCipher(&badCipher{sizes: sizes}); err == nil {
+			t.Errorf("NewWithCipher(block sizes %v): expected error", sizes)
+		}
+	}
+	if _, err := NewWithCipher(&badCipher{sizes: []int{8}}); err != nil {
+		t.Errorf("NewWithCipher(constant block size 8): %v", err)
+	}
+}
+
+func TestNewWithCipherFactory(t *testing.T) {
+	tv := nistvectors[0]
+	var ciphers []cipher.Block
+	factory := func() (cipher.Block, error) {
+		c, err := aes.NewCipher(tv.key)
+		ciphers = append(ciphers, c)
+		return c, err
+	}
+
+	for i := 0; i < 2; i++ {
+		m, err := NewWithCipherFactory(factory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Write(tv.cases[2].msg)
+		if mac := m.Sum(nil); !bytes.Equal(mac, tv.cases[2].mac) {
+			t.Errorf("expected: %x got %x\n", tv.cases[2].mac, mac)
+		}
+	}
+	if len(ciphers) != 2 || ciphers[0] == ciphers[1] {
+		t.Errorf("expected one cipher per instance, got %d", len(ciphers))
+	}
+
+	if _, err := NewWithCipherFactory(nil); err == nil {
+		t.Error("expected error for nil factory")
+	}
+	if _, err := NewWithCipherFactory(func() (cipher.Block, error) { return aes.NewCipher(nil) }); err == nil {
+		t.Error("expected factory error to be returned")
+	}
+
+	// The factory is kept under a policy that wraps the hash.
+	SetPolicy(&Policy{MaxMessageSize: 1 << 20})
+	defer SetPolicy(nil)
+	ciphers = nil
+	m, err := NewWithCipherFactory(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, ok := m.(*limited)
+	if !ok {
+		t.Fatalf("got %T, want the policy wrapper", m)
+	}
+	if l.Hash.(*cmac).factory == nil {
+		t.Fatal("factory dropped by the policy wrapper")
+	}
+	m.Write(tv.cases[2].msg[:20])
+	c, err := l.cloneHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write(tv.cases[2].msg[20:])
+	if mac := c.Sum(nil); !bytes.Equal(mac, tv.cases[2].mac) {
+		t.Errorf("clone: expected: %x got %x\n", tv.cases[2].mac, mac)
+	}
+	if len(ciphers) != 2 {
+		t.Errorf("clone under policy made %d factory calls, want 2", len(ciphers))
+	}
+}
+
+func TestExportedDbl(t *testing.T) {
+	for i, tv := range nistvectors {
+		k1 := make([]byte, len(tv.c0))
+		Dbl(k1, tv.c0)
+		if !bytes.Equal(k1, tv.k1) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tv.k1, k1)
+		}
+		Dbl(k1, k1)
+		if !bytes.Equal(k1, tv.k2) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tv.k2, k1)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid length")
+		}
+	}()
+	Dbl(make([]byte, 12), make([]byte, 12))
+}
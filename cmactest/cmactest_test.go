@@ -0,0 +1,45 @@
+package cmactest_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/joekir/cmac"
+	"github.com/joekir/cmac/cmactest"
+)
+
+func TestVectors(t *testing.T) {
+	for _, v := range cmactest.Vectors {
+		c, err := v.NewCipher(v.Key)
+		if err != nil {
+			t.Fatalf("%s: %v", v.Name, err)
+		}
+
+		l := make([]byte, c.BlockSize())
+		c.Encrypt(l, l)
+		if !bytes.Equal(l, v.L) {
+			t.Errorf("%s: L = %x, want %x", v.Name, l, v.L)
+		}
+		k1 := make([]byte, len(l))
+		cmac.Dbl(k1, l)
+		if !bytes.Equal(k1, v.K1) {
+			t.Errorf("%s: K1 = %x, want %x", v.Name, k1, v.K1)
+		}
+		k2 := make([]byte, len(l))
+		cmac.Dbl(k2, k1)
+		if !bytes.Equal(k2, v.K2) {
+			t.Errorf("%s: K2 = %x, want %x", v.Name, k2, v.K2)
+		}
+
+		for _, tc := range v.Cases {
+			h, err := cmac.NewWithCipher(c)
+			if err != nil {
+				t.Fatalf("%s: %v", v.Name, err)
+			}
+			h.Write(tc.Msg)
+			if got := h.Sum(nil); !bytes.Equal(got, tc.Tag) {
+				t.Errorf("%s/%d: got %x, want %x", v.Name, len(tc.Msg), got, tc.Tag)
+			}
+		}
+	}
+}
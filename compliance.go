@@ -0,0 +1,242 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// ComplianceResult is the outcome of a single known-answer test or
+// cross-check.
+type ComplianceResult struct {
+	Suite  string `json:"suite"`
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ComplianceReport records which of the embedded known-answer tests and
+// implementation cross-checks passed on the running build and platform. It
+// is meant to be serialized with encoding/json and kept as release
+// evidence.
+type ComplianceReport struct {
+	Module         string             `json:"module"`
+	Version        string             `json:"version,omitempty"`
+	GoVersion      string             `json:"go_version"`
+	GOOS           string             `json:"goos"`
+	GOARCH         string             `json:"goarch"`
+	Implementation string             `json:"implementation"`
+	Acceleration   bool               `json:"hardware_acceleration"`
+	FIPS           bool               `json:"fips_policy"`
+	Time           time.Time          `json:"time"`
+	Passed         bool               `json:"passed"`
+	Results        []ComplianceResult `json:"results"`
+}
+
+type complianceCheck struct {
+	suite, name string
+	run         func() error
+}
+
+// RunCompliance runs every embedded known-answer test and cross-check and
+// returns the report. Checks run under the current Policy, so a restrictive
+// policy shows up as failures rather than being silently skipped.
+func RunCompliance() *ComplianceReport {
+	r := &ComplianceReport{
+		Module:         "github.com/joekir/cmac",
+		GoVersion:      runtime.Version(),
+		GOOS:           runtime.GOOS,
+		GOARCH:         runtime.GOARCH,
+		Implementation: Implementation(),
+		Acceleration:   HasAcceleration(),
+		Time:           time.Now().UTC(),
+		Passed:         true,
+	}
+	if p := currentPolicy(); p != nil {
+		r.FIPS = p.FIPS
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range append(bi.Deps, &bi.Main) {
+			if m.Path == r.Module {
+				r.Version = m.Version
+			}
+		}
+	}
+
+	for _, c := range complianceChecks() {
+		res := ComplianceResult{Suite: c.suite, Name: c.name, Passed: true}
+		if err := c.run(); err != nil {
+			res.Passed, res.Error = false, err.Error()
+			r.Passed = false
+		}
+		r.Results = append(r.Results, res)
+	}
+	return r
+}
+
+func complianceHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func expectBytes(got, want []byte) error {
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("expected %x, got %x", want, got)
+	}
+	return nil
+}
+
+// Example vectors from NIST SP800-38B appendix D. The AES-128 cases are
+// also the RFC 4493 section 4 examples.
+const sp800msg = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+type sp800vector struct {
+	name   string
+	cipher func([]byte) (cipher.Block, error)
+	key    string
+	lens   []int
+	macs   []string
+}
+
+var sp800vectors = []sp800vector{
+	{"AES-128", aes.NewCipher, "2b7e151628aed2a6abf7158809cf4f3c", []int{0, 16, 40, 64}, []string{
+		"bb1d6929e95937287fa37d129b756746", "070a16b46b4d4144f79bdd9dd04a287c",
+		"dfa66747de9ae63030ca32611497c827", "51f0bebf7e3b9d92fc49741779363cfe"}},
+	{"AES-192", aes.NewCipher, "8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b", []int{0, 16, 40, 64}, []string{
+		"d17ddf46adaacde531cac483de7a9367", "9e99a7bf31e710900662f65e617c5184",
+		"8a1de5be2eb31aad089a82e6ee908b0e", "a1d5df0eed790f794d77589659f39a11"}},
+	{"AES-256", aes.NewCipher, "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", []int{0, 16, 40, 64}, []string{
+		"028962f61b7bf89efc6b551f4667d983", "28a7023f452e8f82bd4bf28d8c37c35c",
+		"aaf3d8f1de5640c232f5b169b9c911e6", "e1992190549f6ed5696a2c056c315410"}},
+	{"TDEA-3", des.NewTripleDESCipher, "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5", []int{0, 8, 20, 32}, []string{
+		"b7a688e122ffaf95", "8e8f293136283797", "743ddbe0ce2dc2ed", "33e6b1092400eae5"}},
+	{"TDEA-2", des.NewTripleDESCipher, "4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5", []int{0, 8, 20, 32}, []string{
+		"bd2ebf9a3ba00361", "4ff2ab813c53ce83", "62dd1b471902bd4e", "31b1e431dabc4eb8"}},
+}
+
+// genericBlock hides any batch interface of the wrapped cipher so that
+// the generic block loop is used.
+type genericBlock struct{ cipher.Block }
+
+func complianceChecks() []complianceCheck {
+	var checks []complianceCheck
+	msg := complianceHex(sp800msg)
+
+	for _, v := range sp800vectors {
+		v := v
+		for i := range v.lens {
+			n, mac := v.lens[i], complianceHex(v.macs[i])
+			checks = append(checks, complianceCheck{"SP800-38B", fmt.Sprintf("%s/%d", v.name, n), func() error {
+				c, err := v.cipher(complianceHex(v.key))
+				if err != nil {
+					return err
+				}
+				h, err := NewWithCipher(c)
+				if err != nil {
+					return err
+				}
+				h.Write(msg[:n])
+				return expectBytes(h.Sum(nil), mac)
+			}})
+		}
+	}
+
+	checks = append(checks,
+		complianceCheck{"SP800-108", "counter/AES-128", func() error {
+			// Computed with the OpenSSL KBKDF implementation.
+			out, err := kdf(make([]byte, 16), []byte("L"), []byte("C"), 33)
+			if err != nil {
+				return err
+			}
+			return expectBytes(out, complianceHex("7b2810024d3d595060086d1b259a17052f4d9c37bf3975b7c035d3779b2e699a5e"))
+		}},
+		complianceCheck{"RFC 5297", "A.1", func() error {
+			a, err := NewSIV(complianceHex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"), 0)
+			if err != nil {
+				return err
+			}
+			ad := complianceHex("101112131415161718191a1b1c1d1e1f2021222324252627")
+			want := complianceHex("85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+			if err := expectBytes(a.Seal(nil, nil, complianceHex("112233445566778899aabbccddee"), ad), want); err != nil {
+				return err
+			}
+			_, err = a.Open(nil, nil, want, ad)
+			return err
+		}},
+		complianceCheck{"AN10922", "AES-128", func() error {
+			k, err := DiversifyAN10922(complianceHex("00112233445566778899aabbccddeeff"), complianceHex("04782e21801d803042f54e585020416275"))
+			if err != nil {
+				return err
+			}
+			return expectBytes(k, complianceHex("a8dd63a3b89d54b37ca802473fda9175"))
+		}},
+		complianceCheck{"cross-check", "optimized/generic", crossCheckBlocks},
+		complianceCheck{"cross-check", "one-shot/chunked", crossCheckChunks},
+	)
+	return checks
+}
+
+// crossCheckBlocks compares the block processing selected for this
+// platform against the generic loop over messages spanning several
+// batches.
+func crossCheckBlocks() error {
+	c, err := aes.NewCipher(complianceHex("2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		return err
+	}
+	msg := make([]byte, 2000)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+
+	var fast, slow State
+	if err := fast.Init(c); err != nil {
+		return err
+	}
+	if err := slow.Init(genericBlock{c}); err != nil {
+		return err
+	}
+	for _, n := range []int{0, 1, 16, 17, 511, 512, 513, len(msg)} {
+		fast.Reset()
+		slow.Reset()
+		fast.Write(msg[:n])
+		slow.Write(msg[:n])
+		if err := expectBytes(fast.Sum(nil), slow.Sum(nil)); err != nil {
+			return fmt.Errorf("%d bytes: %v", n, err)
+		}
+	}
+	return nil
+}
+
+// crossCheckChunks compares writing a message in one call against writing
+// it in uneven pieces.
+func crossCheckChunks() error {
+	h, err := New(complianceHex("2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		return err
+	}
+	msg := make([]byte, 1000)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	h.Reset()
+	for i, n := 0, 1; i < len(msg); i, n = i+n, n%31+1 {
+		if i+n > len(msg) {
+			n = len(msg) - i
+		}
+		h.Write(msg[i : i+n])
+	}
+	return expectBytes(h.Sum(nil), want)
+}
@@ -0,0 +1,37 @@
+package cmac
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRunCompliance(t *testing.T) {
+	r := RunCompliance()
+	for _, res := range r.Results {
+		if !res.Passed {
+			t.Errorf("%s %s: %s", res.Suite, res.Name, res.Error)
+		}
+	}
+	if !r.Passed || len(r.Results) < 20 {
+		t.Errorf("unexpected report: passed %v with %d results", r.Passed, len(r.Results))
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ComplianceReport
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Results) != len(r.Results) || decoded.Implementation != Implementation() {
+		t.Error("report does not round-trip through JSON")
+	}
+
+	// A policy that forbids TDEA is reported, not hidden.
+	SetPolicy(&Policy{BlockSizes: []int{16}})
+	defer SetPolicy(nil)
+	if RunCompliance().Passed {
+		t.Error("expected failures under a restrictive policy")
+	}
+}
@@ -0,0 +1,38 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"io"
+)
+
+// CopyWithMAC copies from src to dst like io.Copy and returns the number
+// of bytes copied along with the AES-CMAC tag of the copied data. On error
+// the tag is nil.
+func CopyWithMAC(dst io.Writer, src io.Reader, key []byte) (n int64, tag []byte, err error) {
+	h, err := New(key)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Tee the source rather than the destination so that dst can still
+	// use its io.ReaderFrom.
+	n, err = io.Copy(dst, io.TeeReader(src, h))
+	if err != nil {
+		return n, nil, err
+	}
+	return n, h.Sum(nil), nil
+}
+
+// VerifyCopy copies from src to dst like CopyWithMAC and returns
+// ErrInvalidTag if the AES-CMAC tag of the copied data is not tag. The data
+// has already been written to dst when the tag is checked, so callers must
+// discard it on error.
+func VerifyCopy(dst io.Writer, src io.Reader, key, tag []byte) (int64, error) {
+	n, t, err := CopyWithMAC(dst, src, key)
+	if err != nil {
+		return n, err
+	}
+	if subtle.ConstantTimeCompare(t, tag) != 1 {
+		return n, ErrInvalidTag
+	}
+	return n, nil
+}
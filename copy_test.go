@@ -0,0 +1,38 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCopyWithMAC(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	expected := unhex("dfa66747de9ae63030ca32611497c827")
+
+	var dst bytes.Buffer
+	n, tag, err := CopyWithMAC(&dst, iotest.OneByteReader(bytes.NewReader(msg)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(msg)) || !bytes.Equal(dst.Bytes(), msg) {
+		t.Errorf("copied %d bytes: %x", n, dst.Bytes())
+	}
+	if !bytes.Equal(tag, expected) {
+		t.Errorf("expected %x, got %x", expected, tag)
+	}
+
+	if _, err := VerifyCopy(&bytes.Buffer{}, bytes.NewReader(msg), key, expected); err != nil {
+		t.Error(err)
+	}
+	if _, err := VerifyCopy(&bytes.Buffer{}, bytes.NewReader(msg[1:]), key, expected); err != ErrInvalidTag {
+		t.Errorf("expected %v, got %v", ErrInvalidTag, err)
+	}
+
+	boom := errors.New("boom")
+	if _, tag, err := CopyWithMAC(&bytes.Buffer{}, iotest.ErrReader(boom), key); err != boom || tag != nil {
+		t.Errorf("expected %v and no tag, got %v, %x", boom, err, tag)
+	}
+}
@@ -0,0 +1,152 @@
+package cose
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The CBOR subset needed for COSE_Mac0 and COSE_Mac: definite-length
+// integers, byte and text strings, arrays, maps, tags and null.
+
+const (
+	majorUint   = 0
+	majorNeg    = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+
+	simpleNull = 22
+)
+
+var errMalformed = errors.New("cose: malformed CBOR")
+
+func appendHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= 0xff:
+		return append(b, m|24, byte(n))
+	case n <= 0xffff:
+		return append(b, m|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		return append(b, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	b = append(b, m|27, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(b[len(b)-8:], n)
+	return b
+}
+
+func appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendHead(b, majorNeg, uint64(-1-v))
+	}
+	return appendHead(b, majorUint, uint64(v))
+}
+
+func appendBytes(b, s []byte) []byte {
+	return append(appendHead(b, majorBytes, uint64(len(s))), s...)
+}
+
+func appendText(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+// item is a decoded CBOR data item. Arrays and byte strings hold their
+// contents, maps their keys and values alternately, and tags their single
+// content item.
+type item struct {
+	major byte
+	n     uint64 // argument: value, length or tag number
+	bytes []byte
+	items []item
+	null  bool
+}
+
+// decode parses one data item from b and returns it with the remaining
+// input. Nesting is limited to keep hostile input from exhausting the
+// stack.
+func decode(b []byte, depth int) (item, []byte, error) {
+	if len(b) == 0 || depth > 16 {
+		return item{}, nil, errMalformed
+	}
+	it := item{major: b[0] >> 5}
+	info := b[0] & 0x1f
+	b = b[1:]
+	switch {
+	case info < 24:
+		it.n = uint64(info)
+	case info <= 27:
+		l := 1 << (info - 24)
+		if len(b) < l {
+			return item{}, nil, errMalformed
+		}
+		for _, c := range b[:l] {
+			it.n = it.n<<8 | uint64(c)
+		}
+		b = b[l:]
+	default:
+		// Indefinite lengths and reserved values.
+		return item{}, nil, errMalformed
+	}
+
+	switch it.major {
+	case majorUint, majorNeg:
+	case majorBytes, majorText:
+		if uint64(len(b)) < it.n {
+			return item{}, nil, errMalformed
+		}
+		it.bytes, b = b[:it.n], b[it.n:]
+	case majorArray, majorMap, majorTag:
+		count := it.n
+		if it.major == majorMap {
+			count *= 2
+		} else if it.major == majorTag {
+			count = 1
+		}
+		if count > uint64(len(b)) {
+			return item{}, nil, errMalformed
+		}
+		for i := uint64(0); i < count; i++ {
+			var sub item
+			var err error
+			if sub, b, err = decode(b, depth+1); err != nil {
+				return item{}, nil, err
+			}
+			it.items = append(it.items, sub)
+		}
+	case majorSimple:
+		if info != simpleNull {
+			return item{}, nil, errMalformed
+		}
+		it.null = true
+	}
+	return it, b, nil
+}
+
+// intValue returns the value of an integer item.
+func (it item) intValue() (int64, bool) {
+	if it.n > 1<<63-1 {
+		return 0, false
+	}
+	switch it.major {
+	case majorUint:
+		return int64(it.n), true
+	case majorNeg:
+		return -1 - int64(it.n), true
+	}
+	return 0, false
+}
+
+// lookup returns the value of the integer key k in a map item.
+func (it item) lookup(k int64) (item, bool) {
+	for i := 0; i+1 < len(it.items); i += 2 {
+		if v, ok := it.items[i].intValue(); ok && v == k {
+			return it.items[i+1], true
+		}
+	}
+	return item{}, false
+}
@@ -0,0 +1,52 @@
+package cose
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	// Examples from RFC 8949 Appendix A.
+	for _, tt := range []struct {
+		hex string
+		b   []byte
+	}{
+		{"00", appendInt(nil, 0)},
+		{"17", appendInt(nil, 23)},
+		{"1818", appendInt(nil, 24)},
+		{"1903e8", appendInt(nil, 1000)},
+		{"1a000f4240", appendInt(nil, 1000000)},
+		{"1b000000e8d4a51000", appendInt(nil, 1000000000000)},
+		{"3863", appendInt(nil, -100)},
+		{"4401020304", appendBytes(nil, []byte{1, 2, 3, 4})},
+		{"6449455446", appendText(nil, "IETF")},
+	} {
+		if got := hex.EncodeToString(tt.b); got != tt.hex {
+			t.Errorf("expected %s got %s", tt.hex, got)
+		}
+		it, rest, err := decode(tt.b, 0)
+		if err != nil || len(rest) != 0 {
+			t.Errorf("%s: decode: %v", tt.hex, err)
+		}
+		if it.major == majorUint || it.major == majorNeg {
+			if _, ok := it.intValue(); !ok {
+				t.Errorf("%s: not an integer", tt.hex)
+			}
+		}
+	}
+
+	it, _, err := decode([]byte{0xa2, 0x01, 0x02, 0x03, 0x82, 0x04, 0x05}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := it.lookup(3); !ok || v.major != majorArray || len(v.items) != 2 {
+		t.Errorf("lookup: %+v %v", v, ok)
+	}
+
+	for _, bad := range []string{"", "18", "42ff", "9f", "5f", "f4", "8201", "c1"} {
+		b, _ := hex.DecodeString(bad)
+		if _, _, err := decode(b, 0); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package cose builds and verifies COSE_Mac0 and COSE_Mac messages (RFC
+// 9052) with the AES-MAC algorithms of RFC 9053, using the CBC-MAC of
+// package cmac. COSE registers no CMAC algorithm; AES-MAC is CBC-MAC with
+// a zero IV and zero padding, which is safe here because MAC_structure is
+// a CBOR array whose encoding is prefix-free.
+//
+// Only what MACed tokens need is implemented: the algorithm and key ID
+// header parameters, and direct-key recipients for COSE_Mac.
+package cose
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/joekir/cmac"
+)
+
+// Algorithm is a COSE algorithm identifier.
+type Algorithm int64
+
+// AES-MAC algorithms of RFC 9053 section 3.2, named by key and tag size.
+const (
+	AESMAC128_64  Algorithm = 14
+	AESMAC256_64  Algorithm = 15
+	AESMAC128_128 Algorithm = 25
+	AESMAC256_128 Algorithm = 26
+)
+
+// CBOR tags of the message types.
+const (
+	TagMac0 = 17
+	TagMac  = 97
+)
+
+// Header parameter labels and the direct key management algorithm.
+const (
+	headerAlg = 1
+	headerKid = 4
+	algDirect = -6
+)
+
+// ErrInvalidMAC is returned when a message's tag does not verify.
+var ErrInvalidMAC = errors.New("cose: invalid MAC")
+
+func (a Algorithm) sizes() (key, tag int, err error) {
+	switch a {
+	case AESMAC128_64:
+		return 16, 8, nil
+	case AESMAC256_64:
+		return 32, 8, nil
+	case AESMAC128_128:
+		return 16, 16, nil
+	case AESMAC256_128:
+		return 32, 16, nil
+	}
+	return 0, 0, errors.New("cose: unsupported algorithm")
+}
+
+// mac computes the tag of MAC_structure [context, protected, externalAAD,
+// payload].
+func mac(a Algorithm, key []byte, context string, protected, externalAAD, payload []byte) ([]byte, error) {
+	keySize, tagSize, err := a.sizes()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, errors.New("cose: key size does not match algorithm")
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	h, err := cmac.NewCBCMAC(c, cmac.WithPadding(cmac.ZeroPadding))
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendHead(b, majorArray, 4)
+	b = appendText(b, context)
+	b = appendBytes(b, protected)
+	b = appendBytes(b, externalAAD)
+	b = appendBytes(b, payload)
+	h.Write(b)
+	return h.Sum(nil)[:tagSize], nil
+}
+
+func protectedHeader(a Algorithm) []byte {
+	b := appendHead(nil, majorMap, 1)
+	b = appendInt(b, headerAlg)
+	return appendInt(b, int64(a))
+}
+
+func unprotectedHeader(b []byte, kid []byte) []byte {
+	if kid == nil {
+		return appendHead(b, majorMap, 0)
+	}
+	b = appendHead(b, majorMap, 1)
+	b = appendInt(b, headerKid)
+	return appendBytes(b, kid)
+}
+
+// Mac0 returns a tagged COSE_Mac0 message MACing payload and externalAAD
+// with key. If kid is not nil it is sent as an unprotected key ID.
+func Mac0(a Algorithm, key, kid, payload, externalAAD []byte) ([]byte, error) {
+	protected := protectedHeader(a)
+	tag, err := mac(a, key, "MAC0", protected, externalAAD, payload)
+	if err != nil {
+		return nil, err
+	}
+	b := appendHead(nil, majorTag, TagMac0)
+	b = appendHead(b, majorArray, 4)
+	b = appendBytes(b, protected)
+	b = unprotectedHeader(b, kid)
+	b = appendBytes(b, payload)
+	return appendBytes(b, tag), nil
+}
+
+// Mac returns a tagged COSE_Mac message with a single recipient using the
+// key directly, identified by kid.
+func Mac(a Algorithm, key, kid, payload, externalAAD []byte) ([]byte, error) {
+	protected := protectedHeader(a)
+	tag, err := mac(a, key, "MAC", protected, externalAAD, payload)
+	if err != nil {
+		return nil, err
+	}
+	b := appendHead(nil, majorTag, TagMac)
+	b = appendHead(b, majorArray, 5)
+	b = appendBytes(b, protected)
+	b = appendHead(b, majorMap, 0)
+	b = appendBytes(b, payload)
+	b = appendBytes(b, tag)
+
+	// [h'', {1: -6, 4: kid}, h'']
+	b = appendHead(b, majorArray, 1)
+	b = appendHead(b, majorArray, 3)
+	b = appendBytes(b, nil)
+	if kid == nil {
+		b = appendHead(b, majorMap, 1)
+	} else {
+		b = appendHead(b, majorMap, 2)
+	}
+	b = appendInt(b, headerAlg)
+	b = appendInt(b, algDirect)
+	if kid != nil {
+		b = appendInt(b, headerKid)
+		b = appendBytes(b, kid)
+	}
+	return appendBytes(b, nil), nil
+}
+
+// Message is a verified COSE_Mac0 or COSE_Mac message.
+type Message struct {
+	Algorithm Algorithm
+	// KeyID is the key ID of the message or, for COSE_Mac, of its first
+	// recipient, or nil if there is none.
+	KeyID   []byte
+	Payload []byte
+}
+
+// Parse decodes a COSE_Mac0 or COSE_Mac message, tagged or not, without
+// verifying it, so that the key can be chosen by its key ID. The result
+// must not be trusted before Verify succeeds. Detached payloads are not
+// supported.
+func Parse(msg []byte) (*Message, error) {
+	_, m, _, _, err := parse(msg)
+	return m, err
+}
+
+func parse(msg []byte) (context string, m *Message, protected, tag []byte, err error) {
+	it, rest, err := decode(msg, 0)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	if len(rest) != 0 {
+		return "", nil, nil, nil, errMalformed
+	}
+
+	var want uint64
+	if it.major == majorTag {
+		if it.n != TagMac0 && it.n != TagMac {
+			return "", nil, nil, nil, errors.New("cose: not a COSE_Mac0 or COSE_Mac message")
+		}
+		want = it.n
+		it = it.items[0]
+	}
+	if it.major != majorArray || (len(it.items) != 4 && len(it.items) != 5) {
+		return "", nil, nil, nil, errMalformed
+	}
+	context = "MAC0"
+	if len(it.items) == 5 {
+		context = "MAC"
+	}
+	if want == TagMac0 && context != "MAC0" || want == TagMac && context != "MAC" {
+		return "", nil, nil, nil, errMalformed
+	}
+
+	p, u, payload, t := it.items[0], it.items[1], it.items[2], it.items[3]
+	if p.major != majorBytes || u.major != majorMap || t.major != majorBytes {
+		return "", nil, nil, nil, errMalformed
+	}
+	if payload.major != majorBytes {
+		return "", nil, nil, nil, errors.New("cose: detached payloads are not supported")
+	}
+	ph, rest, err := decode(p.bytes, 0)
+	if err != nil || len(rest) != 0 || ph.major != majorMap {
+		return "", nil, nil, nil, errMalformed
+	}
+	algItem, ok := ph.lookup(headerAlg)
+	if !ok {
+		return "", nil, nil, nil, errors.New("cose: missing protected algorithm")
+	}
+	alg, ok := algItem.intValue()
+	if !ok {
+		return "", nil, nil, nil, errMalformed
+	}
+
+	m = &Message{Algorithm: Algorithm(alg), Payload: payload.bytes}
+	if k, ok := u.lookup(headerKid); ok && k.major == majorBytes {
+		m.KeyID = k.bytes
+	}
+	if context == "MAC" {
+		r := it.items[4]
+		if r.major != majorArray || len(r.items) == 0 {
+			return "", nil, nil, nil, errMalformed
+		}
+		first := r.items[0]
+		if first.major != majorArray || len(first.items) != 3 || first.items[1].major != majorMap {
+			return "", nil, nil, nil, errMalformed
+		}
+		if k, ok := first.items[1].lookup(headerKid); ok && k.major == majorBytes && m.KeyID == nil {
+			m.KeyID = k.bytes
+		}
+	}
+	return context, m, p.bytes, t.bytes, nil
+}
+
+// Verify checks a COSE_Mac0 or COSE_Mac message against key and
+// externalAAD and returns it. The algorithm is taken from the protected
+// header and must be one of the AES-MAC algorithms.
+func Verify(key, msg, externalAAD []byte) (*Message, error) {
+	context, m, protected, tag, err := parse(msg)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := mac(m.Algorithm, key, context, protected, externalAAD, m.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expected, tag) != 1 {
+		return nil, ErrInvalidMAC
+	}
+	return m, nil
+}
@@ -0,0 +1,98 @@
+package cose
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	key128  = unhex("000102030405060708090a0b0c0d0e0f")
+	key256  = unhex("849b57219dae48de646d07dbb533566e976686457c1491be3a76dcea6c427188")
+	payload = []byte("This is the content.")
+)
+
+func TestMac0(t *testing.T) {
+	msg, err := Mac0(AESMAC128_64, key128, []byte("our-secret"), payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ["MAC0", << {1: 14} >>, h'', payload], zero padded and CBC-MACed.
+	tbm := unhex("84" + "644d414330" + "43a1010e" + "40" + "54" + hex.EncodeToString(payload))
+	c, _ := aes.NewCipher(key128)
+	h, _ := cmac.NewCBCMAC(c)
+	h.Write(tbm)
+	tag := h.Sum(nil)[:8]
+
+	expected := unhex("d1" + "84" + "43a1010e" + "a1" + "04" + "4a" + hex.EncodeToString([]byte("our-secret")) +
+		"54" + hex.EncodeToString(payload) + "48" + hex.EncodeToString(tag))
+	if !bytes.Equal(msg, expected) {
+		t.Errorf("expected %x\n     got %x", expected, msg)
+	}
+
+	m, err := Verify(key128, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Algorithm != AESMAC128_64 || string(m.KeyID) != "our-secret" || !bytes.Equal(m.Payload, payload) {
+		t.Errorf("unexpected message %+v", m)
+	}
+
+	if _, err := Verify(key128, msg, []byte("aad")); err != ErrInvalidMAC {
+		t.Errorf("external AAD not bound: %v", err)
+	}
+	msg[len(msg)-1] ^= 1
+	if _, err := Verify(key128, msg, nil); err != ErrInvalidMAC {
+		t.Errorf("modified tag: %v", err)
+	}
+	if _, err := Verify(key256, msg, nil); err == nil {
+		t.Error("key of the wrong size accepted")
+	}
+}
+
+func TestMac(t *testing.T) {
+	for _, a := range []Algorithm{AESMAC128_64, AESMAC256_64, AESMAC128_128, AESMAC256_128} {
+		key := key128
+		if k, _, _ := a.sizes(); k == 32 {
+			key = key256
+		}
+		msg, err := Mac(a, key, []byte("kid"), payload, []byte("aad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err := Parse(msg); err != nil || string(p.KeyID) != "kid" {
+			t.Errorf("%d: Parse: %+v %v", a, p, err)
+		}
+		m, err := Verify(key, msg, []byte("aad"))
+		if err != nil {
+			t.Fatalf("%d: %v", a, err)
+		}
+		if m.Algorithm != a || !bytes.Equal(m.Payload, payload) {
+			t.Errorf("%d: unexpected message %+v", a, m)
+		}
+
+		// The context string separates the two message types.
+		mac0, _ := Mac0(a, key, nil, payload, []byte("aad"))
+		_, _, _, tag0, _ := parse(mac0)
+		_, _, _, tag, _ := parse(msg)
+		if bytes.Equal(tag0, tag) {
+			t.Errorf("%d: COSE_Mac and COSE_Mac0 tags collide", a)
+		}
+	}
+
+	if _, err := Mac(Algorithm(5), key128, nil, payload, nil); err == nil {
+		t.Error("HMAC algorithm accepted")
+	}
+}
@@ -0,0 +1,155 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"sync"
+)
+
+// Errors returned by CounterVerifier.Verify.
+var (
+	ErrInvalidTag = errors.New("cmac: invalid tag")
+	ErrReplay     = errors.New("cmac: replayed or too old counter")
+)
+
+// counterTag computes the tag over the 8-byte big-endian counter followed
+// by msg.
+func counterTag(h hash.Hash, counter uint64, msg []byte) []byte {
+	var c [8]byte
+	binary.BigEndian.PutUint64(c[:], counter)
+	h.Reset()
+	h.Write(c[:])
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// CounterSigner binds a monotonically increasing counter into the AES-CMAC
+// tag of every message, CMAC(key, counter || msg), with the counter
+// encoded as 8 bytes big-endian. The counter must be sent along with the
+// message. A CounterSigner is safe for concurrent use.
+type CounterSigner struct {
+	mu   sync.Mutex
+	h    hash.Hash
+	next uint64
+	done bool
+}
+
+// NewCounterSigner returns a CounterSigner whose first message uses the
+// counter value next. To survive restarts, callers must persist the
+// counter and resume above any value already used with the same key.
+func NewCounterSigner(key []byte, next uint64) (*CounterSigner, error) {
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &CounterSigner{h: h, next: next}, nil
+}
+
+// Sign returns the counter assigned to msg and its tag. It fails once the
+// counter space is exhausted.
+func (s *CounterSigner) Sign(msg []byte) (uint64, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return 0, nil, errors.New("cmac: message counter exhausted")
+	}
+	c := s.next
+	if s.next++; s.next == 0 {
+		s.done = true
+	}
+	return c, counterTag(s.h, c, msg), nil
+}
+
+// replayWindow is a sliding window over recently accepted sequence
+// numbers, kept as a ring of 64-bit words as described in RFC 6479. One
+// word more than needed is kept so that advancing the window only ever
+// clears whole words.
+type replayWindow struct {
+	size    uint64
+	bits    []uint64
+	highest uint64
+	seen    bool
+}
+
+func newReplayWindow(size int) replayWindow {
+	return replayWindow{size: uint64(size), bits: make([]uint64, (size+63)/64+1)}
+}
+
+func (w *replayWindow) pos(c uint64) (word int, bit uint64) {
+	i := c % (uint64(len(w.bits)) * 64)
+	return int(i / 64), 1 << (i % 64)
+}
+
+func (w *replayWindow) check(c uint64) bool {
+	switch {
+	case !w.seen || c > w.highest:
+		return true
+	case w.highest-c >= w.size:
+		return false
+	default:
+		word, bit := w.pos(c)
+		return w.bits[word]&bit == 0
+	}
+}
+
+func (w *replayWindow) update(c uint64) {
+	if !w.seen || c > w.highest {
+		// Clear the words between the old and the new highest value.
+		var from uint64
+		if w.seen {
+			from = w.highest/64 + 1
+		} else {
+			from = c / 64
+		}
+		n := uint64(len(w.bits))
+		for i := from; i <= c/64 && i-from < n; i++ {
+			w.bits[i%n] = 0
+		}
+		w.highest, w.seen = c, true
+	}
+	word, bit := w.pos(c)
+	w.bits[word] |= bit
+}
+
+// CounterVerifier checks tags produced by a CounterSigner and rejects
+// counters it has already accepted or that have fallen out of its window.
+// A CounterVerifier is safe for concurrent use.
+type CounterVerifier struct {
+	mu sync.Mutex
+	h  hash.Hash
+	w  replayWindow
+}
+
+// NewCounterVerifier returns a verifier accepting counters up to window
+// (between 1 and 64) below the highest counter seen, to tolerate
+// reordering.
+func NewCounterVerifier(key []byte, window int) (*CounterVerifier, error) {
+	if window < 1 || window > 64 {
+		return nil, errors.New("cmac: replay window must be between 1 and 64")
+	}
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &CounterVerifier{h: h, w: newReplayWindow(window)}, nil
+}
+
+// Verify checks the tag of msg sent with counter. It returns ErrInvalidTag
+// if the tag does not match and ErrReplay if the counter was already used
+// or is too old. Only messages with valid tags advance the window.
+func (v *CounterVerifier) Verify(counter uint64, msg, tag []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.w.check(counter) {
+		return ErrReplay
+	}
+	if subtle.ConstantTimeCompare(counterTag(v.h, counter, msg), tag) != 1 {
+		return ErrInvalidTag
+	}
+	v.w.update(counter)
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmac
+
+import (
+	"testing"
+)
+
+func TestCounterMAC(t *testing.T) {
+	key := make([]byte, 16)
+	s, err := NewCounterSigner(key, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewCounterVerifier(key, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type signed struct {
+		c   uint64
+		tag []byte
+	}
+	var msgs []signed
+	for i := 0; i < 8; i++ {
+		c, tag, err := s.Sign([]byte("cmd"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != uint64(i+1) {
+			t.Fatalf("expected counter %d, got %d", i+1, c)
+		}
+		msgs = append(msgs, signed{c, tag})
+	}
+
+	check := func(i int, want error) {
+		t.Helper()
+		if err := v.Verify(msgs[i].c, []byte("cmd"), msgs[i].tag); err != want {
+			t.Errorf("counter %d: expected %v got %v", msgs[i].c, want, err)
+		}
+	}
+
+	check(2, nil)
+	check(2, ErrReplay)
+	check(0, nil) // reordered, within the window
+	check(6, nil)
+	check(1, ErrReplay) // outside the window
+	check(4, nil)
+	check(4, ErrReplay)
+	check(7, nil)
+
+	// A bad tag does not consume the counter.
+	if err := v.Verify(msgs[5].c, []byte("other"), msgs[5].tag); err != ErrInvalidTag {
+		t.Errorf("expected %v got %v", ErrInvalidTag, err)
+	}
+	check(5, nil)
+
+	if _, err := NewCounterVerifier(key, 65); err == nil {
+		t.Error("expected error for oversized window")
+	}
+}
+
+func TestCounterExhausted(t *testing.T) {
+	s, _ := NewCounterSigner(make([]byte, 16), 1<<64-1)
+	if _, _, err := s.Sign(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Sign(nil); err == nil {
+		t.Error("expected error after counter wrap")
+	}
+}
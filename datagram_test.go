@@ -0,0 +1,73 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDatagram(t *testing.T) {
+	key := make([]byte, 16)
+	s, err := NewDatagramSealer(key, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewDatagramOpener(key, 8, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dgs [][]byte
+	for i := 0; i < 600; i++ {
+		d, err := s.Seal(nil, []byte("telemetry"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d) != 8+len("telemetry")+8 {
+			t.Fatalf("unexpected datagram size %d", len(d))
+		}
+		dgs = append(dgs, d)
+	}
+
+	check := func(i int, want error) {
+		t.Helper()
+		p, seq, err := o.Open(dgs[i])
+		if err != want {
+			t.Fatalf("datagram %d: expected %v got %v", i, want, err)
+		}
+		if err == nil && (seq != uint64(i) || !bytes.Equal(p, []byte("telemetry"))) {
+			t.Fatalf("datagram %d: got seq %d payload %q", i, seq, p)
+		}
+	}
+
+	check(10, nil)
+	check(10, ErrReplay)
+	check(0, nil)
+	check(300, nil)
+	check(100, ErrReplay) // outside the window
+	check(101, nil)
+	check(101, ErrReplay)
+	check(299, nil)
+	check(599, nil) // skips more than the whole bitmap
+	check(300, ErrReplay)
+	check(500, nil)
+	check(500, ErrReplay)
+	check(399, ErrReplay)
+	check(400, nil)
+
+	bad := append([]byte(nil), dgs[550]...)
+	bad[9] ^= 1
+	if _, _, err := o.Open(bad); err != ErrInvalidTag {
+		t.Errorf("expected %v got %v", ErrInvalidTag, err)
+	}
+	check(550, nil)
+
+	if _, _, err := o.Open(dgs[1][:15]); err != ErrInvalidTag {
+		t.Errorf("expected %v for short datagram, got %v", ErrInvalidTag, err)
+	}
+	if _, err := NewDatagramSealer(key, 3); err == nil {
+		t.Error("expected error for short tag")
+	}
+	if _, err := NewDatagramOpener(key, 8, 0); err == nil {
+		t.Error("expected error for empty window")
+	}
+}
@@ -0,0 +1,197 @@
+// Package desfire implements the CMAC based secure messaging of MIFARE
+// DESFire EV1 and EV2 cards: the rolling CMAC IV of EV1, and the session
+// keys, command counter and MAC truncation of EV2 (also used by NTAG 424
+// DNA).
+package desfire
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/joekir/cmac"
+)
+
+// MACSize is the length of the truncated MAC of both generations.
+const MACSize = 8
+
+// ErrInvalidMAC is returned when a response MAC does not verify.
+var ErrInvalidMAC = errors.New("desfire: invalid MAC")
+
+// chainedMAC computes CMAC starting from the chaining value iv instead of
+// zero, which is what EV1 does with its session IV.
+type chainedMAC struct {
+	c      cipher.Block
+	k1, k2 []byte
+}
+
+func newChainedMAC(c cipher.Block) (*chainedMAC, error) {
+	n := c.BlockSize()
+	if n != 8 && n != 16 {
+		return nil, errors.New("desfire: invalid block size")
+	}
+	m := &chainedMAC{c: c, k1: make([]byte, n), k2: make([]byte, n)}
+	c.Encrypt(m.k1, m.k1)
+	cmac.Dbl(m.k1, m.k1)
+	cmac.Dbl(m.k2, m.k1)
+	return m, nil
+}
+
+// sum overwrites iv with the CMAC of msg chained from iv.
+func (m *chainedMAC) sum(iv, msg []byte) {
+	n := len(iv)
+	for len(msg) > n {
+		for i := range iv {
+			iv[i] ^= msg[i]
+		}
+		m.c.Encrypt(iv, iv)
+		msg = msg[n:]
+	}
+	k := m.k1
+	if len(msg) < n {
+		k = m.k2
+	}
+	for i := range iv {
+		var b byte
+		switch {
+		case i < len(msg):
+			b = msg[i]
+		case i == len(msg):
+			b = 0x80
+		}
+		iv[i] ^= b ^ k[i]
+	}
+	m.c.Encrypt(iv, iv)
+}
+
+// EV1 is the secure messaging state of an EV1 session authenticated with
+// AES or 3K3DES. Every command and response sent in plain or MACed
+// communication mode must pass through it, in order, to keep the IV in
+// step with the card.
+type EV1 struct {
+	m  *chainedMAC
+	iv []byte
+}
+
+// NewEV1 returns the state for a session whose session key cipher is c,
+// with the all-zero IV set by authentication.
+func NewEV1(c cipher.Block) (*EV1, error) {
+	m, err := newChainedMAC(c)
+	if err != nil {
+		return nil, err
+	}
+	return &EV1{m: m, iv: make([]byte, c.BlockSize())}, nil
+}
+
+// Command updates the IV with the command code and its parameters and
+// data as sent, and returns the MAC to append in MACed communication mode:
+// the first 8 bytes of the new IV.
+func (s *EV1) Command(cmd []byte) []byte {
+	s.m.sum(s.iv, cmd)
+	return append([]byte(nil), s.iv[:MACSize]...)
+}
+
+// Response updates the IV with a response without a MAC, which the card
+// computes over its data followed by the status code.
+func (s *EV1) Response(status byte, data []byte) {
+	s.m.sum(s.iv, append(append([]byte(nil), data...), status))
+}
+
+// VerifyResponse updates the IV with a response and reports whether mac
+// is its MAC.
+func (s *EV1) VerifyResponse(status byte, data, mac []byte) error {
+	s.Response(status, data)
+	if subtle.ConstantTimeCompare(s.iv[:MACSize], mac) != 1 {
+		return ErrInvalidMAC
+	}
+	return nil
+}
+
+// Truncate returns the EV2 truncation of a 16-byte CMAC: its bytes with
+// odd indexes, S1 || S3 || ... || S15.
+func Truncate(t []byte) [MACSize]byte {
+	var m [MACSize]byte
+	for i := range m {
+		m[i] = t[2*i+1]
+	}
+	return m
+}
+
+// SessionKeysEV2 derives SesAuthENCKey and SesAuthMACKey of an EV2 or
+// NTAG 424 DNA session from the authentication key and the random numbers
+// RndA and RndB exchanged by AuthenticateEV2First.
+func SessionKeysEV2(key, rndA, rndB []byte) (enc, mac [16]byte, err error) {
+	if len(rndA) != 16 || len(rndB) != 16 {
+		return enc, mac, errors.New("desfire: random numbers must be 16 bytes")
+	}
+	sv := [32]byte{2: 0x00, 3: 0x01, 4: 0x00, 5: 0x80}
+	copy(sv[6:8], rndA[0:2])
+	for i := 0; i < 6; i++ {
+		sv[8+i] = rndA[2+i] ^ rndB[i]
+	}
+	copy(sv[14:24], rndB[6:16])
+	copy(sv[24:32], rndA[8:16])
+
+	sv[0], sv[1] = 0xa5, 0x5a
+	if enc, err = cmac.Sum(key, sv[:]); err != nil {
+		return
+	}
+	sv[0], sv[1] = 0x5a, 0xa5
+	mac, err = cmac.Sum(key, sv[:])
+	return
+}
+
+// EV2 is the secure messaging state of an EV2 session: the transaction
+// identifier and the command counter, which advances with every response.
+type EV2 struct {
+	c   cipher.Block
+	ti  [4]byte
+	ctr uint16
+}
+
+// NewEV2 returns the state for a session with the given SesAuthMACKey and
+// transaction identifier TI, with the command counter at zero.
+func NewEV2(macKey []byte, ti [4]byte) (*EV2, error) {
+	c, err := aes.NewCipher(macKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EV2{c: c, ti: ti}, nil
+}
+
+// Counter returns the current command counter.
+func (s *EV2) Counter() uint16 {
+	return s.ctr
+}
+
+func (s *EV2) mac(code byte, header, data []byte) [MACSize]byte {
+	var pre [7]byte
+	pre[0] = code
+	binary.LittleEndian.PutUint16(pre[1:], s.ctr)
+	copy(pre[3:], s.ti[:])
+	msg := append(append(pre[:], header...), data...)
+
+	t, _ := cmac.SumWithCipher(s.c, msg)
+	return Truncate(t[:])
+}
+
+// CommandMAC returns the truncated MAC of a command, computed over
+// Cmd || CmdCtr || TI || CmdHeader || CmdData.
+func (s *EV2) CommandMAC(cmd byte, header, data []byte) [MACSize]byte {
+	return s.mac(cmd, header, data)
+}
+
+// VerifyResponse advances the command counter and reports whether mac is
+// the MAC of the response, computed over RC || CmdCtr || TI || RespData.
+// The counter advances even if the MAC is invalid, since the card has
+// already counted the command.
+func (s *EV2) VerifyResponse(rc byte, data, mac []byte) error {
+	s.ctr++
+	m := s.mac(rc, nil, data)
+	if subtle.ConstantTimeCompare(m[:], mac) != 1 {
+		return ErrInvalidMAC
+	}
+	return nil
+}
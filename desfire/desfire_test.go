@@ -0,0 +1,135 @@
+package desfire
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestChainedMAC(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	a, _ := aes.NewCipher(key)
+	d, _ := des.NewTripleDESCipher(unhex("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5"))
+	msg := make([]byte, 40)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	for _, c := range []cipher.Block{a, d} {
+		m, err := newChainedMAC(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := c.BlockSize()
+		for l := 0; l <= len(msg); l++ {
+			// A zero IV gives plain CMAC.
+			iv := make([]byte, n)
+			m.sum(iv, msg[:l])
+			tag, _ := cmac.SumWithCipher(c, msg[:l])
+			if !bytes.Equal(iv, tag[:n]) {
+				t.Fatalf("block %d len %d: expected %x got %x", n, l, tag[:n], iv)
+			}
+
+			// Chaining from an IV is CMAC with the IV folded into the
+			// first block, for messages of more than one block.
+			if l > n {
+				m.sum(iv, msg[:l])
+				folded := append([]byte(nil), msg[:l]...)
+				for i := 0; i < n; i++ {
+					folded[i] ^= tag[i]
+				}
+				expected, _ := cmac.SumWithCipher(c, folded)
+				if !bytes.Equal(iv, expected[:n]) {
+					t.Fatalf("block %d len %d chained: expected %x got %x", n, l, expected[:n], iv)
+				}
+			}
+		}
+	}
+}
+
+func TestEV1(t *testing.T) {
+	c, _ := aes.NewCipher(unhex("000102030405060708090a0b0c0d0e0f"))
+	s, err := NewEV1(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := unhex("bd00000000000000") // ReadData
+	mac := s.Command(cmd)
+	iv, _ := cmac.SumWithCipher(c, cmd)
+	if !bytes.Equal(mac, iv[:8]) {
+		t.Errorf("command MAC: expected %x got %x", iv[:8], mac)
+	}
+
+	// The card MACs the data and status chained from the command's CMAC.
+	data := unhex("00112233445566778899aabbccddeeff0102")
+	m, _ := newChainedMAC(c)
+	expected := append([]byte(nil), iv[:]...)
+	m.sum(expected, append(append([]byte(nil), data...), 0x00))
+	if err := s.VerifyResponse(0x00, data, expected[:8]); err != nil {
+		t.Error(err)
+	}
+	if err := s.VerifyResponse(0x00, data, expected[:8]); err != ErrInvalidMAC {
+		t.Errorf("replayed MAC accepted: %v", err)
+	}
+}
+
+func TestSessionKeysEV2(t *testing.T) {
+	// AN12196 section 6.6 worked example.
+	enc, mac, err := SessionKeysEV2(make([]byte, 16),
+		unhex("13c5db8a5930439fc3def9a4c675360f"), unhex("b9e2fc789b64bf237cccaa20ec7e6e48"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := unhex("1309c877509e5a215007ff0ed19ca564"); !bytes.Equal(enc[:], e) {
+		t.Errorf("SesAuthENCKey: expected %x got %x", e, enc)
+	}
+	if e := unhex("4c6626f5e72ea694202139295c7a7fc7"); !bytes.Equal(mac[:], e) {
+		t.Errorf("SesAuthMACKey: expected %x got %x", e, mac)
+	}
+}
+
+func TestEV2(t *testing.T) {
+	macKey := unhex("4c6626f5e72ea694202139295c7a7fc7")
+	ti := [4]byte{0x9d, 0x00, 0xc4, 0xdf}
+	s, err := NewEV2(macKey, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.CommandMAC(0xf5, []byte{0x02}, nil)
+	tag, _ := cmac.Sum(macKey, unhex("f500009d00c4df02"))
+	if m != Truncate(tag[:]) {
+		t.Errorf("command MAC: expected %x got %x", Truncate(tag[:]), m)
+	}
+
+	data := unhex("0040eeee000100d1fe001f00004400004400002000006a0000")
+	tag, _ = cmac.Sum(macKey, append(unhex("0001009d00c4df"), data...))
+	mt := Truncate(tag[:])
+	if err := s.VerifyResponse(0x00, data, mt[:]); err != nil {
+		t.Error(err)
+	}
+	if s.Counter() != 1 {
+		t.Errorf("expected counter 1, got %d", s.Counter())
+	}
+	if err := s.VerifyResponse(0x00, data, mt[:]); err != ErrInvalidMAC {
+		t.Errorf("MAC with stale counter accepted: %v", err)
+	}
+
+	if got := Truncate(unhex("000102030405060708090a0b0c0d0e0f")); got != [8]byte{1, 3, 5, 7, 9, 11, 13, 15} {
+		t.Errorf("Truncate: got %x", got)
+	}
+}
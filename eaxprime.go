@@ -0,0 +1,95 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// EAXPrimeTagSize is the length of an EAX' authentication tag.
+const EAXPrimeTagSize = 4
+
+// EAXPrime implements EAX', the EAX variant of ANSI C12.22 for smart meter
+// messaging. It departs from EAX in ways that make it unsuitable as a
+// cipher.AEAD: the nonce and the header are a single cleartext, the tags
+// are 32 bits, and the OMAC tweaks are replaced by starting the two CMAC
+// computations from the subkeys D and Q instead of encrypting a tweak
+// block. The cleartext must be unique per message under a key; C12.22
+// ensures this through its invocation IDs.
+type EAXPrime struct {
+	c    cipher.Block
+	d, q [16]byte
+}
+
+// NewEAXPrime returns EAX' using c, which must have a 128-bit block.
+func NewEAXPrime(c cipher.Block) (*EAXPrime, error) {
+	if c == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cmac: EAX' requires a 128-bit block cipher")
+	}
+	e := &EAXPrime{c: c}
+	subkeys(c, e.d[:], e.q[:])
+	return e, nil
+}
+
+// omac computes CMAC of m with the chaining value starting at iv.
+func (e *EAXPrime) omac(iv *[16]byte, m []byte) [16]byte {
+	s := State{c: e.c, size: 16, k1: e.d, k2: e.q, x: *iv}
+	s.Write(m)
+	var t [16]byte
+	s.Sum(t[:0])
+	return t
+}
+
+// tag returns N' and the tag for ciphertext. Without ciphertext, as in
+// authentication-only messages, the tag is taken from N' alone.
+func (e *EAXPrime) tag(cleartext, ciphertext []byte) (n [16]byte, tag [EAXPrimeTagSize]byte) {
+	n = e.omac(&e.d, cleartext)
+	t := n
+	if len(ciphertext) > 0 {
+		c := e.omac(&e.q, ciphertext)
+		for i := range t {
+			t[i] ^= c[i]
+		}
+	}
+	copy(tag[:], t[16-EAXPrimeTagSize:])
+	return
+}
+
+// ctr returns the CTR mode stream for N', with bits 31 and 15 cleared.
+func (e *EAXPrime) ctr(n [16]byte) cipher.Stream {
+	n[12] &= 0x7f
+	n[14] &= 0x7f
+	return cipher.NewCTR(e.c, n[:])
+}
+
+// Seal encrypts and authenticates plaintext, authenticates cleartext, and
+// appends the result, the ciphertext followed by the tag, to dst.
+func (e *EAXPrime) Seal(dst, cleartext, plaintext []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext)+EAXPrimeTagSize)
+	n := e.omac(&e.d, cleartext)
+	e.ctr(n).XORKeyStream(out, plaintext)
+	_, tag := e.tag(cleartext, out[:len(plaintext)])
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+// Open authenticates ciphertext and cleartext and, if successful, appends
+// the decrypted plaintext to dst.
+func (e *EAXPrime) Open(dst, cleartext, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < EAXPrimeTagSize {
+		return nil, errors.New("cmac: EAX' message authentication failed")
+	}
+	tag := ciphertext[len(ciphertext)-EAXPrimeTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-EAXPrimeTagSize]
+
+	n, expected := e.tag(cleartext, ciphertext)
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		return nil, errors.New("cmac: EAX' message authentication failed")
+	}
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	e.ctr(n).XORKeyStream(out, ciphertext)
+	return ret, nil
+}
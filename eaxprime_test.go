@@ -0,0 +1,76 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+// chainedFrom computes CMAC of m with the chaining value starting at iv
+// using the standard construction: prefixing the block that encrypts to
+// iv has the same effect.
+func chainedFrom(c cipher.Block, iv, m []byte) []byte {
+	prefix := make([]byte, 16)
+	c.Decrypt(prefix, iv)
+	h, _ := NewWithCipher(c)
+	h.Write(prefix)
+	h.Write(m)
+	return h.Sum(nil)
+}
+
+func TestEAXPrime(t *testing.T) {
+	c, _ := aes.NewCipher(unhex("01020304050607080102030405060708"))
+	e, err := NewEAXPrime(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, k2 := gensubkeys(c)
+	cleartext := unhex("a20e0c0b607c86f7540116007bc175a8a60d00000000000000000000000000")
+	plaintext := unhex("1234567890abcdef1234")
+
+	sealed := e.Seal(nil, cleartext, plaintext)
+	if len(sealed) != len(plaintext)+EAXPrimeTagSize {
+		t.Fatalf("unexpected length %d", len(sealed))
+	}
+
+	n := chainedFrom(c, k1, cleartext)
+	ctr := append([]byte(nil), n...)
+	ctr[12] &= 0x7f
+	ctr[14] &= 0x7f
+	ct := make([]byte, len(plaintext))
+	cipher.NewCTR(c, ctr).XORKeyStream(ct, plaintext)
+	if !bytes.Equal(sealed[:len(ct)], ct) {
+		t.Errorf("ciphertext: expected %x got %x", ct, sealed[:len(ct)])
+	}
+	cm := chainedFrom(c, k2, ct)
+	var tag []byte
+	for i := 12; i < 16; i++ {
+		tag = append(tag, n[i]^cm[i])
+	}
+	if !bytes.Equal(sealed[len(ct):], tag) {
+		t.Errorf("tag: expected %x got %x", tag, sealed[len(ct):])
+	}
+
+	out, err := e.Open(nil, cleartext, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, plaintext) {
+		t.Errorf("Open: expected %x got %x", plaintext, out)
+	}
+	cleartext[0] ^= 1
+	if _, err := e.Open(nil, cleartext, sealed); err == nil {
+		t.Error("modified cleartext accepted")
+	}
+	cleartext[0] ^= 1
+
+	// Authentication-only messages are tagged with N' alone.
+	sealed = e.Seal(nil, cleartext, nil)
+	if !bytes.Equal(sealed, n[12:]) {
+		t.Errorf("empty plaintext: expected %x got %x", n[12:], sealed)
+	}
+	if _, err := e.Open(nil, cleartext, sealed); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package eia2 implements the 3GPP integrity algorithm 128-EIA2 (LTE) and
+// 128-NIA2 (5G), which is AES-CMAC over the COUNT, BEARER and DIRECTION
+// parameters followed by a message whose length is given in bits,
+// truncated to 32 bits (TS 33.401 Annex B.2.3, TS 33.501 Annex D.3.1.3).
+package eia2
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/joekir/cmac"
+)
+
+// Size is the length of a MAC-I or NAS-MAC in bytes.
+const Size = 4
+
+// Direction is the transmission direction.
+type Direction byte
+
+// Transmission directions.
+const (
+	Uplink   Direction = 0
+	Downlink Direction = 1
+)
+
+// MAC computes the 32-bit MAC of the first length bits of msg. bearer is
+// the 5-bit bearer identity. Bits of msg beyond length are ignored.
+func MAC(key []byte, count uint32, bearer byte, dir Direction, msg []byte, length int) ([Size]byte, error) {
+	var mac [Size]byte
+	if len(key) != 16 {
+		return mac, errors.New("eia2: key must be 16 bytes")
+	}
+	if bearer > 0x1f || dir > 1 {
+		return mac, errors.New("eia2: invalid bearer or direction")
+	}
+	if length < 0 || length > len(msg)*8 {
+		return mac, errors.New("eia2: length out of range")
+	}
+
+	n := (length + 7) / 8
+	m := make([]byte, 8+n)
+	binary.BigEndian.PutUint32(m, count)
+	m[4] = bearer<<3 | byte(dir)<<2
+	copy(m[8:], msg[:n])
+
+	t, err := cmac.SumBits(key, m, 64+length)
+	if err != nil {
+		return mac, err
+	}
+	copy(mac[:], t[:])
+	return mac, nil
+}
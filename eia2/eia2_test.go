@@ -0,0 +1,40 @@
+package eia2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test sets from TS 33.401 Annex C.2.
+func TestMAC(t *testing.T) {
+	tests := []struct {
+		key    string
+		count  uint32
+		bearer byte
+		dir    Direction
+		length int
+		msg    string
+		mac    string
+	}{
+		{"2bd6459f82c5b300952c49104881ff48", 0x38a6f056, 0x18, 0, 58, "3332346263393840", "118c6eb8"},
+		{"d3c5d592327fb11c4035c6680af8c6d1", 0x398a59b4, 0x1a, 1, 64, "484583d5afe082ae", "b93787e6"},
+	}
+	for i, tt := range tests {
+		mac, err := MAC(unhex(tt.key), tt.count, tt.bearer, tt.dir, unhex(tt.msg), tt.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mac[:], unhex(tt.mac)) {
+			t.Errorf("test set %d: expected %s got %x", i+1, tt.mac, mac)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package emv implements the MAC computations of EMV secure messaging
+// (EMV Book 2, Annex A1.2 and A1.3, and section 8): the common session key
+// derivation, the secure messaging MAC for issuer script commands, and the
+// ARPC of issuer authentication method 2.
+//
+// Keys are double-length TDES keys (16 bytes), for which the MAC is ISO/IEC
+// 9797-1 MAC algorithm 3 with DES and the mandatory padding method 2, or
+// AES-128 keys, for which it is CMAC. MACs are 8 bytes long.
+package emv
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+
+	"github.com/joekir/cmac"
+)
+
+// Scheme is the block cipher of a key.
+type Scheme int
+
+// Supported schemes.
+const (
+	TDES Scheme = iota
+	AES
+)
+
+// MACSize is the length of a secure messaging MAC in bytes.
+const MACSize = 8
+
+// ARPCSize is the length of an ARPC computed with method 2.
+const ARPCSize = 4
+
+func newCipher(s Scheme, key []byte) (cipher.Block, error) {
+	if len(key) != 16 {
+		return nil, errors.New("emv: keys must be 16 bytes")
+	}
+	switch s {
+	case TDES:
+		return des.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+	case AES:
+		return aes.NewCipher(key)
+	}
+	return nil, errors.New("emv: unknown scheme")
+}
+
+// SessionKey derives a session key from the ICC master key mk and the
+// application transaction counter with the EMV common session key
+// derivation: the encryption under mk of ATC || F0 || 00... and, for TDES,
+// of ATC || 0F || 00... for the right half.
+func SessionKey(s Scheme, mk []byte, atc uint16) ([]byte, error) {
+	c, err := newCipher(s, mk)
+	if err != nil {
+		return nil, err
+	}
+	n := c.BlockSize()
+	r := make([]byte, 16)
+	sk := make([]byte, 16)
+
+	binary.BigEndian.PutUint16(r, atc)
+	r[2] = 0xf0
+	c.Encrypt(sk, r[:n])
+	if n == 8 {
+		r[2] = 0x0f
+		c.Encrypt(sk[8:], r[:n])
+	}
+	return sk, nil
+}
+
+// MAC returns the 8-byte secure messaging MAC of msg under the session
+// key sk.
+func MAC(s Scheme, sk, msg []byte) ([]byte, error) {
+	switch s {
+	case TDES:
+		if len(sk) != 16 {
+			return nil, errors.New("emv: keys must be 16 bytes")
+		}
+		h, err := cmac.NewRetailMACDES(sk, cmac.WithPadding(cmac.ISO7816Padding))
+		if err != nil {
+			return nil, err
+		}
+		h.Write(msg)
+		return h.Sum(nil), nil
+	case AES:
+		c, err := newCipher(s, sk)
+		if err != nil {
+			return nil, err
+		}
+		t, err := cmac.SumWithCipher(c, msg)
+		if err != nil {
+			return nil, err
+		}
+		return t[:MACSize], nil
+	}
+	return nil, errors.New("emv: unknown scheme")
+}
+
+// ScriptCommand returns an issuer script command with its secure
+// messaging MAC appended. header holds CLA, INS, P1 and P2, where CLA
+// indicates secure messaging (for example 84); data is the plaintext or
+// enciphered command data. The MAC covers CLA || INS || P1 || P2 || Lc ||
+// ATC || ARQC || data, where Lc includes the MAC.
+func ScriptCommand(s Scheme, sk []byte, header [4]byte, data []byte, atc uint16, arqc [8]byte) ([]byte, error) {
+	if len(data)+MACSize > 0xff {
+		return nil, errors.New("emv: command data too long")
+	}
+	apdu := append(header[:], byte(len(data)+MACSize))
+
+	in := make([]byte, 0, len(apdu)+10+len(data))
+	in = append(in, apdu...)
+	in = append(in, byte(atc>>8), byte(atc))
+	in = append(in, arqc[:]...)
+	in = append(in, data...)
+	mac, err := MAC(s, sk, in)
+	if err != nil {
+		return nil, err
+	}
+
+	apdu = append(apdu, data...)
+	return append(apdu, mac...), nil
+}
+
+// ARPC computes the authorisation response cryptogram of ARPC method 2,
+// the first 4 bytes of the MAC under the session key sk of ARQC || CSU ||
+// proprietary authentication data.
+func ARPC(s Scheme, sk []byte, arqc [8]byte, csu [4]byte, propData []byte) ([]byte, error) {
+	if len(propData) > 8 {
+		return nil, errors.New("emv: proprietary authentication data too long")
+	}
+	in := append(append(arqc[:], csu[:]...), propData...)
+	mac, err := MAC(s, sk, in)
+	if err != nil {
+		return nil, err
+	}
+	return mac[:ARPCSize], nil
+}
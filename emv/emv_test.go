@@ -0,0 +1,97 @@
+package emv
+
+import (
+	"bytes"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var mk = unhex("0123456789abcdeffedcba9876543210")
+
+func TestSessionKey(t *testing.T) {
+	sk, err := SessionKey(TDES, mk, 0x0042)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := des.NewTripleDESCipher(append(append([]byte(nil), mk...), mk[:8]...))
+	expected := make([]byte, 16)
+	c.Encrypt(expected, unhex("0042f00000000000"))
+	c.Encrypt(expected[8:], unhex("00420f0000000000"))
+	if !bytes.Equal(sk, expected) {
+		t.Errorf("TDES: expected %x got %x", expected, sk)
+	}
+
+	sk, _ = SessionKey(AES, mk, 0x0042)
+	expected, _ = encryptAES(mk, unhex("0042f000000000000000000000000000"))
+	if !bytes.Equal(sk, expected) {
+		t.Errorf("AES: expected %x got %x", expected, sk)
+	}
+
+	if _, err := SessionKey(TDES, mk[:8], 1); err == nil {
+		t.Error("expected error for single-length key")
+	}
+}
+
+func encryptAES(key, in []byte) ([]byte, error) {
+	c, err := newCipher(AES, key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 16)
+	c.Encrypt(out, in)
+	return out, nil
+}
+
+func TestMAC(t *testing.T) {
+	// Padding method 2 is zero padding after an extra 80 byte.
+	msg := unhex("4e6f77206973207468652074696d6520666f7220616c6c2080")
+	key := unhex("0123456789abcdeffedcba9876543210")
+	mac, err := MAC(TDES, key, msg[:len(msg)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := cmac.NewRetailMACDES(key)
+	h.Write(append(msg, 0, 0, 0, 0, 0, 0, 0))
+	if expected := h.Sum(nil); !bytes.Equal(mac, expected) {
+		t.Errorf("TDES: expected %x got %x", expected, mac)
+	}
+
+	mac, _ = MAC(AES, key, msg)
+	tag, _ := cmac.Sum(key, msg)
+	if !bytes.Equal(mac, tag[:8]) {
+		t.Errorf("AES: expected %x got %x", tag[:8], mac)
+	}
+}
+
+func TestScriptCommand(t *testing.T) {
+	sk, _ := SessionKey(TDES, mk, 0x0042)
+	arqc := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	data := unhex("9f5c0101")
+
+	// PUT DATA with secure messaging.
+	apdu, err := ScriptCommand(TDES, sk, [4]byte{0x84, 0xda, 0x9f, 0x5c}, data, 0x0042, arqc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac, _ := MAC(TDES, sk, unhex("84da9f5c0c"+"0042"+"0102030405060708"+"9f5c0101"))
+	if expected := append(unhex("84da9f5c0c9f5c0101"), mac...); !bytes.Equal(apdu, expected) {
+		t.Errorf("expected %x got %x", expected, apdu)
+	}
+
+	arpc, _ := ARPC(AES, sk, arqc, [4]byte{0x00, 0x82, 0x00, 0x00}, nil)
+	mac, _ = MAC(AES, sk, unhex("0102030405060708"+"00820000"))
+	if !bytes.Equal(arpc, mac[:4]) {
+		t.Errorf("ARPC: expected %x got %x", mac[:4], arpc)
+	}
+}
@@ -0,0 +1,80 @@
+// Package fira implements the AES-CMAC based key derivation of FiRa UWB
+// secure ranging: the digest of the session configuration and the keys and
+// IV derived from the session key for the scrambled timestamp sequence
+// (STS) and for payload privacy.
+//
+// All derivations use the SP800-108 counter mode KDF with AES-CMAC, a
+// 32-bit counter before the fixed input and the recommended fixed input
+// layout, as implemented by package kbkdf.
+package fira
+
+import (
+	"encoding/binary"
+
+	"github.com/joekir/cmac"
+	"github.com/joekir/cmac/kbkdf"
+)
+
+// Labels of the derived keys.
+const (
+	LabelAuthenticationIV  = "DerivedAuthenticationIV"
+	LabelAuthenticationKey = "DerivedAuthenticationKey"
+	LabelDataPrivacyKey    = "DataPrivacyKey"
+	LabelPrivacyKey        = "PrivacyKey"
+)
+
+// KDF derives n bytes from key with label and context.
+func KDF(key []byte, label string, context []byte, n int) ([]byte, error) {
+	return kbkdf.Counter(key, kbkdf.FixedInput([]byte(label), context, n), n, nil)
+}
+
+// ConfigDigest returns the AES-CMAC under the all-zero key of the encoded
+// UWB session configuration, which is the context of every derivation and
+// binds the keys to the configuration both devices agreed on.
+func ConfigDigest(config []byte) ([16]byte, error) {
+	return cmac.Sum(make([]byte, 16), config)
+}
+
+// Keys are the keys and IV derived from a session key.
+type Keys struct {
+	// AuthenticationIV is the IV of the STS generator.
+	AuthenticationIV [16]byte
+	// AuthenticationKey is the key of the STS generator.
+	AuthenticationKey [16]byte
+	// DataPrivacyKey protects the payload of ranging frames.
+	DataPrivacyKey [16]byte
+	// PrivacyKey protects the header IEs of ranging frames.
+	PrivacyKey [16]byte
+}
+
+// DeriveKeys derives the keys of a ranging session from the 128-bit
+// session key (for provisioned STS, the session or sub-session key) and
+// the configuration digest.
+func DeriveKeys(sessionKey []byte, digest [16]byte) (*Keys, error) {
+	var k Keys
+	for _, d := range []struct {
+		label string
+		out   *[16]byte
+	}{
+		{LabelAuthenticationIV, &k.AuthenticationIV},
+		{LabelAuthenticationKey, &k.AuthenticationKey},
+		{LabelDataPrivacyKey, &k.DataPrivacyKey},
+		{LabelPrivacyKey, &k.PrivacyKey},
+	} {
+		b, err := KDF(sessionKey, d.label, digest[:], 16)
+		if err != nil {
+			return nil, err
+		}
+		copy(d.out[:], b)
+	}
+	return &k, nil
+}
+
+// STSV returns the STS generator input V for a ranging round: the upper
+// 96 bits of the authentication IV followed by its lower 32 bits plus the
+// STS index, modulo 2^32.
+func STSV(iv [16]byte, stsIndex uint32) [16]byte {
+	v := iv
+	binary.BigEndian.PutUint32(v[12:], binary.BigEndian.Uint32(iv[12:])+stsIndex)
+	return v
+}
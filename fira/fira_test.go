@@ -0,0 +1,71 @@
+package fira
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+	"github.com/joekir/cmac/kbkdf"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestDeriveKeys(t *testing.T) {
+	config := unhex("0003000901000000000000000000")
+	digest, err := ConfigDigest(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := cmac.Sum(make([]byte, 16), config); digest != expected {
+		t.Errorf("digest: expected %x got %x", expected, digest)
+	}
+
+	sk := unhex("000102030405060708090a0b0c0d0e0f")
+	k, err := DeriveKeys(sk, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []struct {
+		label string
+		got   [16]byte
+	}{
+		{"DerivedAuthenticationIV", k.AuthenticationIV},
+		{"DerivedAuthenticationKey", k.AuthenticationKey},
+		{"DataPrivacyKey", k.DataPrivacyKey},
+		{"PrivacyKey", k.PrivacyKey},
+	} {
+		// [1]32 || label || 00 || digest || [128]32
+		h, _ := cmac.New(sk)
+		h.Write([]byte{0, 0, 0, 1})
+		h.Write([]byte(d.label))
+		h.Write([]byte{0})
+		h.Write(digest[:])
+		h.Write([]byte{0, 0, 0, 0x80})
+		if expected := h.Sum(nil); !bytes.Equal(d.got[:], expected) {
+			t.Errorf("%s: expected %x got %x", d.label, expected, d.got)
+		}
+		if b, _ := kbkdf.Counter(sk, kbkdf.FixedInput([]byte(d.label), digest[:], 16), 16, nil); !bytes.Equal(b, d.got[:]) {
+			t.Errorf("%s: does not match kbkdf", d.label)
+		}
+	}
+
+	if _, err := DeriveKeys(sk[:8], digest); err == nil {
+		t.Error("expected error for short session key")
+	}
+}
+
+func TestSTSV(t *testing.T) {
+	var iv [16]byte
+	copy(iv[:], unhex("00112233445566778899aabbfffffff0"))
+	v := STSV(iv, 0x20)
+	if expected := unhex("00112233445566778899aabb00000010"); !bytes.Equal(v[:], expected) {
+		t.Errorf("expected %x got %x", expected, v)
+	}
+}
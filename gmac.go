@@ -0,0 +1,81 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+)
+
+// gmac computes GMAC, GCM authenticating only additional data, by way of
+// crypto/cipher's GCM. That implementation does not stream, so the message
+// is buffered until Sum.
+type gmac struct {
+	aead  cipher.AEAD
+	nonce []byte
+	msg   []byte
+	// tag is set by the first Sum. From then on the hash is spent: the
+	// nonce must not authenticate another message.
+	tag []byte
+}
+
+// NewGMAC returns a hash.Hash computing AES-GMAC of NIST SP800-38D with the
+// given key and nonce, subject to the package Policy set with SetPolicy.
+// The nonce should be 12 bytes long.
+//
+// A key and nonce pair must never be used for two different messages:
+// doing so reveals the authentication key. The hash is therefore single
+// use: after the first Sum, Write returns an error, Reset does nothing and
+// further calls to Sum return the same tag. Create a new hash, with a new
+// nonce, for every message. The message is held in memory until Sum.
+func NewGMAC(key, nonce []byte) (hash.Hash, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	if len(nonce) == 0 {
+		return nil, errors.New("cmac: GMAC requires a nonce")
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(c, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(&gmac{aead: aead, nonce: append([]byte(nil), nonce...)}), nil
+}
+
+func (g *gmac) Size() int      { return 16 }
+func (g *gmac) BlockSize() int { return 16 }
+
+// Reset discards the buffered message. Once a tag has been computed it
+// does nothing: the hash stays spent, since a new message under the same
+// nonce would reveal the authentication key.
+func (g *gmac) Reset() {
+	if g.tag == nil {
+		g.msg = g.msg[:0]
+	}
+}
+
+func (g *gmac) Write(b []byte) (int, error) {
+	if g.tag != nil {
+		return 0, errGMACReuse
+	}
+	g.msg = append(g.msg, b...)
+	return len(b), nil
+}
+
+var errGMACReuse = errors.New("cmac: GMAC hash written after Sum; the nonce must not be reused")
+
+func (g *gmac) Sum(b []byte) []byte {
+	if g.tag == nil {
+		g.tag = g.aead.Seal(nil, g.nonce, nil, g.msg)
+		g.msg = nil
+	}
+	return append(b, g.tag...)
+}
@@ -1,3 +1,5 @@
 module github.com/joekir/cmac
 
-go 1.12
+go 1.17
+
+require golang.org/x/sys v0.1.0
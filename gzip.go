@@ -0,0 +1,74 @@
+package cmac
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"hash"
+	"io"
+)
+
+// WithGzip makes the stream helpers MAC the decompressed content of gzip
+// data rather than its compressed bytes, so that a tag covers the logical
+// content independently of how it was compressed. SumReader,
+// SumReaderContext, VerifyReaderContext and SumFile decompress their
+// input. NewWriter and NewTagWriter compress what is written to them and
+// MAC it as written; NewReader decompresses the data before its tag and
+// returns the content, checking the tag against it. WithMaxSize and
+// WithProgress count decompressed bytes, so WithMaxSize also bounds the
+// work done for a gzip bomb.
+//
+// To MAC both forms, MAC the stream once with and once without WithGzip.
+func WithGzip() StreamOption {
+	return func(c *streamConfig) {
+		c.gzip = true
+	}
+}
+
+// decompress returns r, or a reader of its decompressed content under
+// WithGzip.
+func (c *streamConfig) decompress(r io.Reader) (io.Reader, error) {
+	if !c.gzip {
+		return r, nil
+	}
+	return gzip.NewReader(r)
+}
+
+// gzipTagReader is NewReader under WithGzip. t strips the tag off the
+// compressed stream without MACing it; the decompressed content is MACed
+// instead and checked against that tag.
+type gzipTagReader struct {
+	t   *tagReader
+	zr  *gzip.Reader
+	h   hash.Hash
+	err error
+}
+
+func (g *gzipTagReader) Read(p []byte) (int, error) {
+	if g.err != nil {
+		return 0, g.err
+	}
+	if g.zr == nil {
+		if g.zr, g.err = gzip.NewReader(g.t); g.err != nil {
+			return 0, g.err
+		}
+	}
+	n, err := g.zr.Read(p)
+	// MAC the content before handing it out, so that nothing past a size
+	// limit is returned.
+	if _, herr := g.h.Write(p[:n]); herr != nil {
+		g.err = herr
+		return 0, herr
+	}
+	if err == io.EOF {
+		// The gzip reader reads up to the end of the compressed data, so
+		// t has seen the tag.
+		var sum [16]byte
+		if subtle.ConstantTimeCompare(g.h.Sum(sum[:0]), g.t.tag()) != 1 {
+			err = ErrInvalidTag
+		}
+	}
+	if err != nil {
+		g.err = err
+	}
+	return n, err
+}
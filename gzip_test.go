@@ -0,0 +1,134 @@
+package cmac
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+func gzipped(t *testing.T, b []byte, level int) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, level)
+	zw.Write(b)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGzipSum(t *testing.T) {
+	tv := nistvectors[0]
+	c := tv.cases[3]
+	want := c.mac
+
+	// The tag covers the content, whatever the compression.
+	for _, level := range []int{gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+		z := gzipped(t, c.msg, level)
+		tag, err := SumReader(tv.key, bytes.NewReader(z), WithGzip())
+		if err != nil || !bytes.Equal(tag[:], want) {
+			t.Errorf("level %d: SumReader got %x, %v, want %x", level, tag, err, want)
+		}
+		if err := VerifyReaderContext(context.Background(), tv.key, bytes.NewReader(z), want, WithGzip()); err != nil {
+			t.Errorf("level %d: VerifyReaderContext: %v", level, err)
+		}
+
+		path := filepath.Join(t.TempDir(), "f.gz")
+		if err := os.WriteFile(path, z, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		tag, err = SumFile(tv.key, path, WithGzip())
+		if err != nil || !bytes.Equal(tag[:], want) {
+			t.Errorf("level %d: SumFile got %x, %v, want %x", level, tag, err, want)
+		}
+	}
+
+	if _, err := SumReader(tv.key, bytes.NewReader(c.msg), WithGzip()); err == nil {
+		t.Error("accepted data that is not gzip")
+	}
+	z := gzipped(t, c.msg, gzip.BestSpeed)
+	if _, err := SumReader(tv.key, bytes.NewReader(z[:len(z)-1]), WithGzip()); err == nil {
+		t.Error("accepted truncated gzip data")
+	}
+}
+
+func TestGzipMaxSize(t *testing.T) {
+	key := nistvectors[0].key
+	// A megabyte of zeros compresses to about a kilobyte.
+	z := gzipped(t, make([]byte, 1<<20), gzip.BestCompression)
+	if _, err := SumReader(key, bytes.NewReader(z), WithGzip(), WithMaxSize(1<<19)); err == nil {
+		t.Error("decompressed data over the limit accepted")
+	}
+
+	var out closeBuffer
+	w, _ := NewWriter(&out, key, WithGzip())
+	w.Write(make([]byte, 1<<20))
+	w.Close()
+	r, _ := NewReader(bytes.NewReader(out.Bytes()), key, WithGzip(), WithMaxSize(1<<19))
+	if n, err := io.Copy(io.Discard, r); err == nil || n > 1<<19 {
+		t.Errorf("NewReader returned %d bytes, %v", n, err)
+	}
+}
+
+func TestGzipWriterReader(t *testing.T) {
+	tv := nistvectors[0]
+	for _, c := range tv.cases {
+		var out closeBuffer
+		w, err := NewWriter(&out, tv.key, WithGzip())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(w, iotest.HalfReader(bytes.NewReader(c.msg))); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b := out.Bytes()
+		if !bytes.Equal(b[len(b)-16:], c.mac) {
+			t.Errorf("%d bytes: tag %x, want %x", len(c.msg), b[len(b)-16:], c.mac)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(b[:len(b)-16]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := io.ReadAll(zr); err != nil || !bytes.Equal(got, c.msg) {
+			t.Errorf("%d bytes: decompressed %x, %v", len(c.msg), got, err)
+		}
+
+		r, err := NewReader(bytes.NewReader(b), tv.key, WithGzip())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := iotest.TestReader(r, c.msg); err != nil {
+			t.Errorf("%d bytes: %v", len(c.msg), err)
+		}
+		r, _ = NewReader(iotest.OneByteReader(bytes.NewReader(b)), tv.key, WithGzip())
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, c.msg) {
+			t.Errorf("one byte at a time: got %x, %v", got, err)
+		}
+
+		tampered := append([]byte(nil), b...)
+		tampered[len(tampered)-1] ^= 1
+		r, _ = NewReader(bytes.NewReader(tampered), tv.key, WithGzip())
+		if _, err := io.ReadAll(r); err != ErrInvalidTag {
+			t.Errorf("%d bytes: tampered tag: got %v, want ErrInvalidTag", len(c.msg), err)
+		}
+	}
+
+	// Recompressing the content keeps the tag valid.
+	c := tv.cases[3]
+	z := gzipped(t, c.msg, gzip.NoCompression)
+	r, _ := NewReader(bytes.NewReader(append(z, c.mac...)), tv.key, WithGzip())
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, c.msg) {
+		t.Errorf("recompressed: got %x, %v", got, err)
+	}
+	r, _ = NewReader(bytes.NewReader(z[:10]), tv.key, WithGzip())
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("accepted a stream without a tag")
+	}
+}
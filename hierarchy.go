@@ -0,0 +1,118 @@
+package cmac
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// KeyHierarchy derives labeled child keys from a master key using the
+// SP800-108 counter mode KDF with AES-CMAC. Labels are slash-separated
+// paths: each component derives a key from its parent's, so the key for
+// "storage/2025/tenantX" is the "tenantX" child of "storage/2025".
+//
+// Derived keys have the same length as the master key. A KeyHierarchy
+// caches up to hierarchyCacheSize derived keys and is safe for concurrent
+// use. Wipe erases the master key and the cache once it is no longer
+// needed.
+type KeyHierarchy struct {
+	mu     sync.Mutex
+	master []byte
+	cache  map[string][]byte
+}
+
+// hierarchyCacheSize bounds the number of derived keys a KeyHierarchy
+// caches. When it is reached the cache is wiped and starts over, so that
+// callers deriving keys for many distinct paths cannot grow it without
+// bound.
+const hierarchyCacheSize = 256
+
+// NewKeyHierarchy returns a KeyHierarchy rooted at the given AES key.
+func NewKeyHierarchy(master []byte) (*KeyHierarchy, error) {
+	switch len(master) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("cmac: invalid master key size")
+	}
+
+	k := make([]byte, len(master))
+	copy(k, master)
+	return &KeyHierarchy{master: k, cache: make(map[string][]byte)}, nil
+}
+
+// Key returns the key derived for path. The returned slice is a copy and
+// may be modified by the caller.
+func (h *KeyHierarchy) Key(path string) ([]byte, error) {
+	return h.derive(path)
+}
+
+// Child returns a KeyHierarchy rooted at the key derived for path, so that
+// h.Child("a").Key("b") equals h.Key("a/b"). The child has a cache of its
+// own and must be wiped separately.
+func (h *KeyHierarchy) Child(path string) (*KeyHierarchy, error) {
+	k, err := h.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(k)
+	return NewKeyHierarchy(k)
+}
+
+// Wipe zeroes the master key and all cached keys. h must not be used
+// afterwards; Key and Child return an error.
+func (h *KeyHierarchy) Wipe() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	wipe(h.master)
+	h.master = nil
+	h.wipeCache()
+}
+
+func (h *KeyHierarchy) wipeCache() {
+	for _, k := range h.cache {
+		wipe(k)
+	}
+	h.cache = make(map[string][]byte)
+}
+
+// derive returns a copy of the key for path. The copy is made under the
+// lock, since cached keys are wiped when the cache starts over.
+func (h *KeyHierarchy) derive(path string) ([]byte, error) {
+	labels := strings.Split(path, "/")
+	for _, l := range labels {
+		if l == "" {
+			return nil, errors.New("cmac: invalid key path")
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.master == nil {
+		return nil, errors.New("cmac: KeyHierarchy wiped")
+	}
+
+	// Walk back to the longest cached prefix and derive from there.
+	k, i := h.master, len(labels)
+	for ; i > 0; i-- {
+		if c, ok := h.cache[strings.Join(labels[:i], "/")]; ok {
+			k = c
+			break
+		}
+	}
+
+	for ; i < len(labels); i++ {
+		var err error
+		k, err = kdf(k, []byte(labels[i]), nil, len(k))
+		if err != nil {
+			return nil, err
+		}
+		if len(h.cache) >= hierarchyCacheSize {
+			h.wipeCache()
+		}
+		h.cache[strings.Join(labels[:i+1], "/")] = k
+	}
+
+	d := make([]byte, len(k))
+	copy(d, k)
+	return d, nil
+}
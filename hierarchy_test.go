@@ -0,0 +1,112 @@
+package cmac
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestKDF(t *testing.T) {
+	// Expected outputs computed with OpenSSL's KBKDF (counter mode, CMAC).
+	tests := []struct {
+		key, label, context []byte
+		out                 []byte
+	}{
+		{
+			key:   unhex("000102030405060708090a0b0c0d0e0f"),
+			label: []byte("storage"),
+			out:   unhex("7a8e4f70cbe82764e94670952e031b6f"),
+		},
+		{
+			key:     unhex("000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f"),
+			label:   []byte("label"),
+			context: []byte("context"),
+			out:     unhex("ccbf1b678e08a8743b3b83024185203c4c48edf70a2df6ee2fa36511ad2431afe4b8c9850932d81a"),
+		},
+	}
+
+	for i, tt := range tests {
+		out, err := kdf(tt.key, tt.label, tt.context, len(tt.out))
+		if err != nil {
+			t.Fatalf("tv[%d]: kdf() err: %s\n", i, err)
+		}
+		if !bytes.Equal(out, tt.out) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tt.out, out)
+		}
+	}
+}
+
+func TestKeyHierarchy(t *testing.T) {
+	h, err := NewKeyHierarchy(unhex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := unhex("ae3199f4a6eaeb100042a95a091a238f")
+	k, err := h.Key("storage/2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k, expected) {
+		t.Errorf("expected: %x got %x\n", expected, k)
+	}
+
+	// Again, from the cache, and via Child.
+	k, _ = h.Key("storage/2025")
+	if !bytes.Equal(k, expected) {
+		t.Errorf("cached: expected: %x got %x\n", expected, k)
+	}
+	c, err := h.Child("storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, _ = c.Key("2025")
+	if !bytes.Equal(k, expected) {
+		t.Errorf("child: expected: %x got %x\n", expected, k)
+	}
+
+	k[0] ^= 0xff
+	k, _ = h.Key("storage/2025")
+	if !bytes.Equal(k, expected) {
+		t.Errorf("cache modified through returned key")
+	}
+
+	for _, p := range []string{"", "/storage", "storage/", "storage//2025"} {
+		if _, err := h.Key(p); err == nil {
+			t.Errorf("Key(%q): expected error", p)
+		}
+	}
+}
+
+func TestKeyHierarchyCache(t *testing.T) {
+	h, _ := NewKeyHierarchy(unhex("000102030405060708090a0b0c0d0e0f"))
+	expected := unhex("ae3199f4a6eaeb100042a95a091a238f")
+	h.Key("storage/2025")
+	for i := 0; i < 3*hierarchyCacheSize; i++ {
+		h.Key("tenant/" + strconv.Itoa(i))
+		if len(h.cache) > hierarchyCacheSize {
+			t.Fatalf("cache grew to %d keys", len(h.cache))
+		}
+	}
+	if k, err := h.Key("storage/2025"); err != nil || !bytes.Equal(k, expected) {
+		t.Errorf("after the cache started over: got %x, %v", k, err)
+	}
+
+	var cached [][]byte
+	for _, k := range h.cache {
+		cached = append(cached, k)
+	}
+	master := h.master
+	h.Wipe()
+	for _, k := range append(cached, master) {
+		if !bytes.Equal(k, make([]byte, len(k))) {
+			t.Errorf("key not wiped: %x", k)
+		}
+	}
+	if _, err := h.Key("storage/2025"); err == nil {
+		t.Error("Key after Wipe")
+	}
+	if _, err := h.Child("storage"); err == nil {
+		t.Error("Child after Wipe")
+	}
+}
@@ -0,0 +1,65 @@
+// Package ikev2 implements the IKEv2 pseudorandom function PRF_AES128_CMAC
+// (RFC 4615, transform ID 8) and the prf+ expansion of RFC 7296 section
+// 2.13 built on it, for deriving IKE SA and Child SA keying material.
+package ikev2
+
+import (
+	"errors"
+
+	"github.com/joekir/cmac/ckdf"
+)
+
+// Size is the output size of PRF_AES128_CMAC in bytes.
+const Size = 16
+
+// MaxOutput is the most keying material prf+ can produce.
+const MaxOutput = 255 * Size
+
+// PRF computes PRF_AES128_CMAC(key, msg). Keys of any length are accepted:
+// keys that are not 16 bytes long are first compressed with AES-CMAC under
+// the zero key, as RFC 4615 specifies.
+func PRF(key, msg []byte) ([]byte, error) {
+	// AES-CMAC-PRF-128 is the extract step of package ckdf, with the key
+	// in the role of the salt.
+	return ckdf.Extract(key, msg)
+}
+
+// PRFPlus returns n bytes of prf+(key, seed) = T1 | T2 | ..., where
+// T1 = prf(key, seed | 0x01) and Ti = prf(key, Ti-1 | seed | i).
+func PRFPlus(key, seed []byte, n int) ([]byte, error) {
+	if n < 0 || n > MaxOutput {
+		return nil, errors.New("ikev2: invalid prf+ output length")
+	}
+	out := make([]byte, 0, n+Size)
+	var t []byte
+	in := make([]byte, 0, Size+len(seed)+1)
+	for i := 1; len(out) < n; i++ {
+		in = append(append(append(in[:0], t...), seed...), byte(i))
+		var err error
+		if t, err = PRF(key, in); err != nil {
+			return nil, err
+		}
+		out = append(out, t...)
+	}
+	return out[:n], nil
+}
+
+// SKEYSEED computes prf(Ni | Nr, g^ir). Since PRF_AES128_CMAC has a fixed
+// key size, RFC 7296 section 2.14 keys it with the first 64 bits of each
+// nonce.
+func SKEYSEED(ni, nr, gir []byte) ([]byte, error) {
+	if len(ni) < 8 || len(nr) < 8 {
+		return nil, errors.New("ikev2: nonces must be at least 8 bytes")
+	}
+	key := append(append(make([]byte, 0, 16), ni[:8]...), nr[:8]...)
+	return PRF(key, gir)
+}
+
+// Keys returns n bytes of IKE SA keying material, prf+(SKEYSEED, Ni | Nr |
+// SPIi | SPIr), to be split into SK_d, SK_ai, SK_ar, SK_ei, SK_er, SK_pi
+// and SK_pr.
+func Keys(skeyseed, ni, nr []byte, spii, spir [8]byte, n int) ([]byte, error) {
+	seed := make([]byte, 0, len(ni)+len(nr)+16)
+	seed = append(append(append(append(seed, ni...), nr...), spii[:]...), spir[:]...)
+	return PRFPlus(skeyseed, seed, n)
+}
@@ -0,0 +1,76 @@
+package ikev2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 4615 section 4.
+func TestPRF(t *testing.T) {
+	msg := unhex("000102030405060708090a0b0c0d0e0f10111213")
+	for _, tt := range []struct{ key, out string }{
+		{"000102030405060708090a0b0c0d0e0fedcb", "84a348a4a45d235babfffc0d2b4da09a"},
+		{"000102030405060708090a0b0c0d0e0f", "980ae87b5f4c9c5214f5b6a8455e4c2d"},
+		{"00010203040506070809", "290d9e112edb09ee141fcf64c0b72f3d"},
+	} {
+		out, err := PRF(unhex(tt.key), msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, unhex(tt.out)) {
+			t.Errorf("key %s: expected %s got %x", tt.key, tt.out, out)
+		}
+	}
+}
+
+func TestPRFPlus(t *testing.T) {
+	key := unhex("000102030405060708090a0b0c0d0e0f")
+	seed := []byte("seed")
+	out, err := PRFPlus(key, seed, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t1, _ := PRF(key, append([]byte("seed"), 1))
+	t2, _ := PRF(key, append(append(append([]byte(nil), t1...), "seed"...), 2))
+	t3, _ := PRF(key, append(append(append([]byte(nil), t2...), "seed"...), 3))
+	expected := append(append(append([]byte(nil), t1...), t2...), t3[:8]...)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %x got %x", expected, out)
+	}
+
+	if _, err := PRFPlus(key, seed, MaxOutput+1); err == nil {
+		t.Error("expected error beyond 255 blocks")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	ni := unhex("0102030405060708090a0b0c0d0e0f10")
+	nr := unhex("1112131415161718191a1b1c1d1e1f20")
+	gir := bytes.Repeat([]byte{0x42}, 32)
+
+	s, err := SKEYSEED(ni, nr, gir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := PRF(unhex("01020304050607081112131415161718"), gir); !bytes.Equal(s, expected) {
+		t.Errorf("SKEYSEED: expected %x got %x", expected, s)
+	}
+
+	spii := [8]byte{1}
+	spir := [8]byte{2}
+	k, _ := Keys(s, ni, nr, spii, spir, 100)
+	seed := append(append(append(append([]byte(nil), ni...), nr...), spii[:]...), spir[:]...)
+	if expected, _ := PRFPlus(s, seed, 100); !bytes.Equal(k, expected) {
+		t.Error("Keys does not match prf+ over Ni | Nr | SPIi | SPIr")
+	}
+}
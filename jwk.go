@@ -0,0 +1,95 @@
+package cmac
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JWK "alg" values for AES-CMAC keys. JOSE registers no CMAC algorithms,
+// so these follow the naming of the registered AES key wrap algorithms.
+const (
+	JWKAlgA128CMAC = "A128CMAC"
+	JWKAlgA192CMAC = "A192CMAC"
+	JWKAlgA256CMAC = "A256CMAC"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	K   string `json:"k"`
+}
+
+func jwkAlg(n int) (string, error) {
+	switch n {
+	case 16:
+		return JWKAlgA128CMAC, nil
+	case 24:
+		return JWKAlgA192CMAC, nil
+	case 32:
+		return JWKAlgA256CMAC, nil
+	default:
+		return "", errors.New("cmac: invalid key size")
+	}
+}
+
+// MarshalJWK encodes k as a JSON Web Key of type "oct" (RFC 7517), with
+// its ID as "kid" and "use" set to "sig".
+func (k *Key) MarshalJWK() ([]byte, error) {
+	if k.Algorithm != "" && k.Algorithm != KeyAlgorithm {
+		return nil, fmt.Errorf("cmac: unsupported key algorithm %q", k.Algorithm)
+	}
+	alg, err := jwkAlg(len(k.Material))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&jwk{
+		Kty: "oct",
+		Alg: alg,
+		Kid: k.ID,
+		Use: "sig",
+		K:   base64.RawURLEncoding.EncodeToString(k.Material),
+	})
+}
+
+// ParseJWK decodes an "oct" JSON Web Key. If present, "alg" must match the
+// key size and "use" must be "sig".
+func ParseJWK(data []byte) (*Key, error) {
+	var j jwk
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	if j.Kty != "oct" {
+		return nil, fmt.Errorf("cmac: unsupported JWK key type %q", j.Kty)
+	}
+	if j.Use != "" && j.Use != "sig" {
+		return nil, fmt.Errorf("cmac: JWK use %q is not sig", j.Use)
+	}
+
+	m, err := base64.RawURLEncoding.DecodeString(j.K)
+	if err != nil {
+		return nil, errors.New("cmac: invalid JWK key value")
+	}
+	alg, err := jwkAlg(len(m))
+	if err != nil {
+		return nil, err
+	}
+	if j.Alg != "" && j.Alg != alg {
+		return nil, fmt.Errorf("cmac: JWK alg %q does not match a %d-byte key", j.Alg, len(m))
+	}
+	return &Key{Algorithm: KeyAlgorithm, ID: j.Kid, Material: m}, nil
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of k: the base64url
+// encoded SHA-256 digest of its canonical JWK members.
+func (k *Key) Thumbprint() string {
+	// The required members of an oct key are k and kty, in that order.
+	// Base64url needs no JSON escaping.
+	c := `{"k":"` + base64.RawURLEncoding.EncodeToString(k.Material) + `","kty":"oct"}`
+	d := sha256.Sum256([]byte(c))
+	return base64.RawURLEncoding.EncodeToString(d[:])
+}
@@ -0,0 +1,53 @@
+package cmac
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJWK(t *testing.T) {
+	k := &Key{ID: "k1", Material: unhex("2b7e151628aed2a6abf7158809cf4f3c")}
+	data, err := k.MarshalJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"kty": "oct", "alg": "A128CMAC", "kid": "k1", "use": "sig", "k": "K34VFiiu0qar9xWICc9PPA"}
+	for name, v := range expected {
+		if m[name] != v {
+			t.Errorf("%s: expected %q got %q", name, v, m[name])
+		}
+	}
+
+	k2, err := ParseJWK(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.ID != "k1" || !bytes.Equal(k2.Material, k.Material) {
+		t.Errorf("round trip mismatch: %+v", k2)
+	}
+
+	for _, bad := range []string{
+		`{"kty":"RSA","k":"K34VFiiu0qar9xWICc9PPA"}`,
+		`{"kty":"oct","k":"K34VFiiu0qar9xWICc9P"}`,
+		`{"kty":"oct","alg":"A256CMAC","k":"K34VFiiu0qar9xWICc9PPA"}`,
+		`{"kty":"oct","use":"enc","k":"K34VFiiu0qar9xWICc9PPA"}`,
+		`{"kty":"oct","k":"K34VFiiu0qar9xWICc9PPA=="}`,
+	} {
+		if _, err := ParseJWK([]byte(bad)); err == nil {
+			t.Errorf("ParseJWK(%s): expected error", bad)
+		}
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	k := &Key{Material: unhex("2b7e151628aed2a6abf7158809cf4f3c")}
+	if tp := k.Thumbprint(); tp != "5YtW3HUGnVJ7duuPZz8uEya_f3dWrJGJAkvk_Ag3-x0" {
+		t.Errorf("got %s", tp)
+	}
+}
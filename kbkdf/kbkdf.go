@@ -0,0 +1,232 @@
+// Package kbkdf implements the key-based key derivation functions of NIST
+// SP800-108 with AES-CMAC as the PRF.
+//
+// Counter, feedback and double-pipeline iteration mode are provided. The
+// PRF input is made of a counter, the iteration variable of feedback and
+// double-pipeline mode, and caller-supplied fixed input data. Most
+// protocols use the fixed input data recommended by SP800-108, which
+// FixedInput encodes:
+//
+//	label || 0x00 || context || [L]32
+//
+// with L the output length in bits. Protocols that define their own
+// encoding, and the NIST CAVP vectors, pass their fixed input data as is.
+package kbkdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// CounterLocation is the position of the counter in the PRF input.
+type CounterLocation int
+
+const (
+	// BeforeIteration places the counter first, before the iteration
+	// variable of feedback and double-pipeline mode, if any.
+	BeforeIteration CounterLocation = iota
+	// AfterIteration places the counter between the iteration variable
+	// and the fixed input data, as in the formulas of SP800-108 for
+	// feedback and double-pipeline mode. In counter mode it is the same as
+	// BeforeIteration.
+	AfterIteration
+	// AfterFixed places the counter last.
+	AfterFixed
+	// MiddleFixed places the counter within the fixed input data, after
+	// its first Options.FixedBefore bytes, as in the MIDDLE_FIXED NIST
+	// CAVP vectors.
+	MiddleFixed
+)
+
+// Options configures the PRF input. The zero value, or a nil *Options, is
+// a 32-bit counter at the start of the PRF input.
+type Options struct {
+	// CounterBits is the size of the counter in bits: 8, 16, 24 or 32.
+	// Zero means 32.
+	CounterBits int
+	// Location is the position of the counter.
+	Location CounterLocation
+	// NoCounter omits the counter in feedback and double-pipeline mode,
+	// where it is optional. It is ignored in counter mode.
+	NoCounter bool
+	// FixedBefore is the number of bytes of fixed input data before the
+	// counter when Location is MiddleFixed.
+	FixedBefore int
+}
+
+func (o *Options) location() (CounterLocation, error) {
+	if o == nil {
+		return BeforeIteration, nil
+	}
+	switch o.Location {
+	case BeforeIteration, AfterIteration, AfterFixed, MiddleFixed:
+		return o.Location, nil
+	}
+	return 0, errors.New("kbkdf: invalid counter location")
+}
+
+func (o *Options) counterBytes() (int, error) {
+	if o == nil || o.CounterBits == 0 {
+		return 4, nil
+	}
+	switch o.CounterBits {
+	case 8, 16, 24, 32:
+		return o.CounterBits / 8, nil
+	}
+	return 0, errors.New("kbkdf: invalid counter size")
+}
+
+// FixedInput returns the fixed input data recommended by SP800-108 for
+// deriving n bytes: label, a zero byte, context and the output length in
+// bits as a 32-bit big-endian integer.
+func FixedInput(label, context []byte, n int) []byte {
+	b := make([]byte, 0, len(label)+1+len(context)+4)
+	b = append(b, label...)
+	b = append(b, 0)
+	b = append(b, context...)
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(n)*8)
+	return append(b, l[:]...)
+}
+
+// Counter derives n bytes from key in counter mode. Each block of output
+// is computed as
+//
+//	CMAC(key, [i]r || fixed)
+//
+// for a counter i starting at 1, or with the counter after or within the
+// fixed input data for AfterFixed and MiddleFixed. The key is subject to the cmac package
+// Policy.
+func Counter(key, fixed []byte, n int, o *Options) ([]byte, error) {
+	c, err := newConfig(key, fixed, n, o, false)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, n+c.h.Size())
+	for i := uint32(1); len(out) < n; i++ {
+		out = c.block(out, i, nil, fixed)
+	}
+	return out[:n], nil
+}
+
+// Feedback derives n bytes from key in feedback mode. Each block of output
+// is computed as
+//
+//	K(i) = CMAC(key, K(i-1) || [i]r || fixed)
+//
+// with K(0) = iv, which may be empty. By default the counter is placed
+// first; see Options for placing it as above, last, or omitting it.
+func Feedback(key, iv, fixed []byte, n int, o *Options) ([]byte, error) {
+	c, err := newConfig(key, fixed, n, o, true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, n+c.h.Size())
+	k := iv
+	for i := uint32(1); len(out) < n; i++ {
+		out = c.block(out, i, k, fixed)
+		k = out[len(out)-c.h.Size():]
+	}
+	return out[:n], nil
+}
+
+// DoublePipeline derives n bytes from key in double-pipeline iteration
+// mode. Each block of output is computed as
+//
+//	A(i) = CMAC(key, A(i-1))
+//	K(i) = CMAC(key, A(i) || [i]r || fixed)
+//
+// with A(0) = fixed. As with Feedback the counter is placed first by
+// default and may be moved or omitted.
+func DoublePipeline(key, fixed []byte, n int, o *Options) ([]byte, error) {
+	c, err := newConfig(key, fixed, n, o, true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, n+c.h.Size())
+	a := make([]byte, 0, c.h.Size())
+	prev := fixed
+	for i := uint32(1); len(out) < n; i++ {
+		c.h.Reset()
+		c.h.Write(prev)
+		a = c.h.Sum(a[:0])
+		prev = a
+		out = c.block(out, i, a, fixed)
+	}
+	return out[:n], nil
+}
+
+type config struct {
+	h        hash.Hash
+	r        int
+	location CounterLocation
+	// before is the length of the fixed input data before the counter for
+	// MiddleFixed.
+	before int
+}
+
+func newConfig(key, fixed []byte, n int, o *Options, optional bool) (*config, error) {
+	r, err := o.counterBytes()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := o.location()
+	if err != nil {
+		return nil, err
+	}
+	before := 0
+	if loc == MiddleFixed {
+		if before = o.FixedBefore; before < 0 || before > len(fixed) {
+			return nil, errors.New("kbkdf: counter offset outside the fixed input data")
+		}
+	}
+	if optional && o != nil && o.NoCounter {
+		r = 0
+	}
+	h, err := newPRF(key, n)
+	if err != nil {
+		return nil, err
+	}
+	if blocks := (n + h.Size() - 1) / h.Size(); r > 0 && r < 4 && blocks >= 1<<(8*uint(r)) {
+		return nil, errors.New("kbkdf: output too long for counter size")
+	}
+	return &config{h: h, r: r, location: loc, before: before}, nil
+}
+
+// block appends the PRF output for counter i, iteration variable iter and
+// fixed input data to out.
+func (c *config) block(out []byte, i uint32, iter, fixed []byte) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], i)
+	ctr := b[4-c.r:]
+
+	c.h.Reset()
+	if c.location == BeforeIteration {
+		c.h.Write(ctr)
+	}
+	c.h.Write(iter)
+	if c.location == AfterIteration {
+		c.h.Write(ctr)
+	}
+	if c.location == MiddleFixed {
+		c.h.Write(fixed[:c.before])
+		c.h.Write(ctr)
+		c.h.Write(fixed[c.before:])
+	} else {
+		c.h.Write(fixed)
+	}
+	if c.location == AfterFixed {
+		c.h.Write(ctr)
+	}
+	return c.h.Sum(out)
+}
+
+func newPRF(key []byte, n int) (hash.Hash, error) {
+	if n <= 0 || uint64(n)*8 > 0xffffffff {
+		return nil, errors.New("kbkdf: invalid output length")
+	}
+	return cmac.New(key)
+}
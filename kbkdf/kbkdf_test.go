@@ -0,0 +1,211 @@
+package kbkdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Expected outputs computed with the OpenSSL KBKDF implementation, which
+// takes the label as salt and the context as info.
+func TestCounter(t *testing.T) {
+	tests := []struct {
+		key, fixed, out string
+	}{
+		// Fixed input data without separator or length.
+		{"000102030405060708090a0b0c0d0e0f", "0102030405aabbcc",
+			"3d4ba6c255246720b4fc6c6d19df3b83e0346aa33647e8a2156b767059445953dd9e8980201dc3e4"},
+		{"000102030405060708090a0b0c0d0e0f1011121314151617", hex.EncodeToString(FixedInput([]byte("label"), []byte("context"), 20)),
+			"524b62fbdbecbaae6afb892e5f03fa508c031e2f"},
+		{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", hex.EncodeToString(FixedInput([]byte("label"), []byte("context"), 32)),
+			"fcc8829a433f70928154c608ece0cc4ccd46fe445ebba07cc1ce0f2b3a7cf7c1"},
+	}
+	for i, tt := range tests {
+		expected := unhex(tt.out)
+		out, err := Counter(unhex(tt.key), unhex(tt.fixed), len(expected), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("tv[%d]: expected %x got %x", i, expected, out)
+		}
+	}
+}
+
+// Records from KDFCTR_gen.rsp of the NIST CAVP SP800-108 vectors, all
+// with L = 128.
+func TestCounterCAVP(t *testing.T) {
+	tests := []struct {
+		prf           string
+		location      CounterLocation
+		rlen          int
+		ki, fixed, ko string
+	}{
+		{"CMAC_AES128", BeforeIteration, 8, "dff1e50ac0b69dc40f1051d46c2b069c",
+			"c16e6e02c5a3dcc8d78b9ac1306877761310455b4e41469951d9e6c2245a064b33fd8c3b01203a7824485bf0a64060c4648b707d2607935699316ea5",
+			"8be8f0869b3c0ba97b71863d1b9f7813"},
+		{"CMAC_AES128", BeforeIteration, 16, "30ec5f6fa1def33cff008178c4454211",
+			"c95e7b1d4f2570259abfc05bb00730f0284c3bb9a61d07259848a1cb57c81d8a6c3382c500bf801dfc8f70726b082cf4c3fa34386c1e7bf0e5471438",
+			"00018fff9574994f5c4457f461c7a67e"},
+		{"CMAC_AES128", BeforeIteration, 24, "ca1cf43e5ccd512cc719a2f9de41734c",
+			"e3884ac963196f02ddd09fc04c20c88b60faa775b5ef6feb1faf8c5e098b5210e2b4e45d62cc0bf907fd68022ee7b15631b5c8daf903d99642c5b831",
+			"1cb2b12326cc5ec1eba248167f0efd58"},
+		{"CMAC_AES128", BeforeIteration, 32, "c10b152e8c97b77e18704e0f0bd38305",
+			"98cd4cbbbebe15d17dc86e6dbad800a2dcbd64f7c7ad0e78e9cf94ffdba89d03e97eadf6c4f7b806caf52aa38f09d0eb71d71f497bcc6906b48d36c4",
+			"26faf61908ad9ee881b8305c221db53f"},
+		{"CMAC_AES128", AfterFixed, 8, "e61a51e1633e7d0de704dcebbd8f962f",
+			"5eef88f8cb188e63e08e23c957ee424a3345da88400c567548b57693931a847501f8e1bce1c37a09ef8c6e2ad553dd0f603b52cc6d4e4cbb76eb6c8f",
+			"63a5647d0fe69d21fc420b1a8ce34cc1"},
+		{"CMAC_AES192", BeforeIteration, 8, "53d1705caab7b06886e2dbb53eea349aa7419a034e2d92b9",
+			"b120f7ce30235784664deae3c40723ca0539b4521b9aece43501366cc5df1d9ea163c602702d0974665277c8a7f6a057733d66f928eb7548cf43e374",
+			"eae32661a323f6d06d0116bb739bd76a"},
+		{"CMAC_AES256", BeforeIteration, 8, "aeb7201d055f754212b3e497bd0b25789a49e51da9f363df414a0f80e6f4e42c",
+			"11ec30761780d4c44acb1f26ca1eb770f87c0e74505e15b7e456b019ce0c38103c4d14afa1de71d340db51410596627512cf199fffa20ef8c5f4841e",
+			"2a9e2fe078bd4f5d3076d14d46f39fb2"},
+	}
+	for _, tt := range tests {
+		expected := unhex(tt.ko)
+		out, err := Counter(unhex(tt.ki), unhex(tt.fixed), len(expected), &Options{CounterBits: tt.rlen, Location: tt.location})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("%s, location %d, %d-bit counter: expected %x got %x", tt.prf, tt.location, tt.rlen, expected, out)
+		}
+	}
+}
+
+func TestCounterOptions(t *testing.T) {
+	key := make([]byte, 16)
+	fixed := []byte("fixed input")
+	for _, bits := range []int{8, 16, 24, 32} {
+		for _, loc := range []CounterLocation{BeforeIteration, AfterIteration, AfterFixed, MiddleFixed} {
+			out, err := Counter(key, fixed, 40, &Options{CounterBits: bits, Location: loc, FixedBefore: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected []byte
+			for i := 1; i <= 3; i++ {
+				ctr := []byte{0, 0, 0, byte(i)}[4-bits/8:]
+				h, _ := cmac.New(key)
+				for _, part := range map[CounterLocation][][]byte{
+					BeforeIteration: {ctr, fixed},
+					AfterIteration:  {ctr, fixed},
+					AfterFixed:      {fixed, ctr},
+					MiddleFixed:     {fixed[:5], ctr, fixed[5:]},
+				}[loc] {
+					h.Write(part)
+				}
+				expected = h.Sum(expected)
+			}
+			if !bytes.Equal(out, expected[:40]) {
+				t.Errorf("%d-bit counter, location %d: expected %x got %x", bits, loc, expected[:40], out)
+			}
+		}
+	}
+
+	if _, err := Counter(key, fixed, 255*16, &Options{CounterBits: 8}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Counter(key, fixed, 255*16+1, &Options{CounterBits: 8}); err == nil {
+		t.Error("expected error for counter overflow")
+	}
+	if _, err := Counter(key, fixed, 16, &Options{CounterBits: 12}); err == nil {
+		t.Error("expected error for invalid counter size")
+	}
+	if _, err := Counter(key, fixed, 0, nil); err == nil {
+		t.Error("expected error for empty output")
+	}
+	for _, before := range []int{-1, len(fixed) + 1} {
+		if _, err := Counter(key, fixed, 16, &Options{Location: MiddleFixed, FixedBefore: before}); err == nil {
+			t.Errorf("expected error for counter offset %d", before)
+		}
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	// Computed with the OpenSSL KBKDF implementation, which places the
+	// counter after the iteration variable.
+	key := unhex("000102030405060708090a0b0c0d0e0f")
+	fixed := FixedInput([]byte("label"), []byte("context"), 40)
+	for _, tt := range []struct{ iv, out string }{
+		{"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff", "2ba020c0a0cc28650dfaede874f0e00998fc25c427c0a04bc692cbcde5efd04e7a3a5777a3fc8a70"},
+		{"", "3fc9b552ad320ef843abf45fe0209ce57a977f790dae948aeb1a36071629da5fed4e9bac8d8e1593"},
+	} {
+		expected := unhex(tt.out)
+		out, err := Feedback(key, unhex(tt.iv), fixed, len(expected), &Options{Location: AfterIteration})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("iv %q: expected %x got %x", tt.iv, expected, out)
+		}
+	}
+
+	// Without a counter, each block is the CMAC of the previous one and
+	// the fixed input data.
+	out, _ := Feedback(key, nil, fixed, 32, &Options{NoCounter: true})
+	h, _ := cmac.New(key)
+	h.Write(fixed)
+	k1 := h.Sum(nil)
+	h.Reset()
+	h.Write(k1)
+	h.Write(fixed)
+	if expected := h.Sum(k1); !bytes.Equal(out, expected) {
+		t.Errorf("no counter: expected %x got %x", expected, out)
+	}
+}
+
+func TestDoublePipeline(t *testing.T) {
+	key := unhex("000102030405060708090a0b0c0d0e0f")
+	fixed := []byte("fixed input")
+	for _, loc := range []CounterLocation{BeforeIteration, AfterIteration, AfterFixed} {
+		for _, none := range []bool{false, true} {
+			o := &Options{CounterBits: 16, Location: loc, NoCounter: none}
+			out, err := DoublePipeline(key, fixed, 40, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected []byte
+			a := fixed
+			for i := 1; i <= 3; i++ {
+				h, _ := cmac.New(key)
+				h.Write(a)
+				a = h.Sum(nil)
+
+				ctr := []byte{0, byte(i)}
+				if none {
+					ctr = nil
+				}
+				h.Reset()
+				for _, part := range map[CounterLocation][][]byte{
+					BeforeIteration: {ctr, a, fixed},
+					AfterIteration:  {a, ctr, fixed},
+					AfterFixed:      {a, fixed, ctr},
+				}[loc] {
+					h.Write(part)
+				}
+				expected = h.Sum(expected)
+			}
+			if !bytes.Equal(out, expected[:40]) {
+				t.Errorf("location %d, no counter %v: expected %x got %x", loc, none, expected[:40], out)
+			}
+		}
+	}
+
+	if _, err := DoublePipeline(key, fixed, 16, &Options{Location: 7}); err == nil {
+		t.Error("expected error for invalid location")
+	}
+}
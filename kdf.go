@@ -0,0 +1,53 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Derive returns an n-byte key derived from key for the purpose named by
+// label, optionally bound to context such as a user or session identifier.
+// Different labels or contexts give independent keys; keys of any size can
+// be derived from an AES key of any size.
+//
+// Derive is the SP800-108 KDF in counter mode with AES-CMAC as the PRF and
+// the recommended fixed input data, the same as OpenSSL's KBKDF with the
+// label as salt and the context as info. The kbkdf package offers the
+// other SP800-108 modes and encodings.
+func Derive(key []byte, label string, context []byte, n int) ([]byte, error) {
+	return kdf(key, []byte(label), context, n)
+}
+
+// kdf derives n bytes of keying material from key using the NIST SP800-108
+// KDF in counter mode with AES-CMAC as the PRF. Each block is computed as
+//
+//	CMAC(key, [i]32 || label || 0x00 || context || [L]32)
+//
+// with a 32-bit big-endian counter i starting at 1 and L the output length
+// in bits.
+func kdf(key, label, context []byte, n int) ([]byte, error) {
+	if n <= 0 || uint64(n)*8 > 0xffffffff {
+		return nil, errors.New("cmac: invalid derived key length")
+	}
+
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var ctr, l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(n)*8)
+
+	out := make([]byte, 0, n+h.Size())
+	for i := uint32(1); len(out) < n; i++ {
+		binary.BigEndian.PutUint32(ctr[:], i)
+		h.Reset()
+		h.Write(ctr[:])
+		h.Write(label)
+		h.Write([]byte{0})
+		h.Write(context)
+		h.Write(l[:])
+		out = h.Sum(out)
+	}
+	return out[:n], nil
+}
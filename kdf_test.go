@@ -0,0 +1,27 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	// Computed with the OpenSSL KBKDF implementation.
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	expected := unhex("ce9695a0059a94a4303684708ebd59fa0ef1b8a3e82cf9a0e298a4f18b40a89d")
+	k, err := Derive(key, "session-encryption", []byte("user-42"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k, expected) {
+		t.Errorf("expected: %x got %x\n", expected, k)
+	}
+
+	other, _ := Derive(key, "session-mac", []byte("user-42"), 32)
+	if bytes.Equal(k, other) {
+		t.Error("different labels derived the same key")
+	}
+	if _, err := Derive(key, "x", nil, 0); err == nil {
+		t.Error("expected error for empty output")
+	}
+}
@@ -0,0 +1,98 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// Keyed holds a cipher and its CMAC subkeys, derived once, and hands out
+// hashes or computes one-shot tags without repeating the key setup. Servers
+// MACing many small messages under one key can share a single Keyed rather
+// than a hash, which is not safe for concurrent use, or pay for the AES
+// key schedule on every message. A Keyed is immutable and safe for
+// concurrent use.
+type Keyed struct {
+	s   State
+	p   *Policy
+	cbc cbcPool
+}
+
+// NewKeyed returns a Keyed for AES-CMAC with key, subject to the package
+// Policy set with SetPolicy. The Policy in effect at this call applies to
+// everything the Keyed computes.
+func NewKeyed(key []byte) (*Keyed, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyed(c, p)
+}
+
+// NewKeyedWithCipher returns a Keyed for CMAC using c, as NewWithCipher.
+// c must be safe for concurrent use if the Keyed is.
+func NewKeyedWithCipher(c cipher.Block) (*Keyed, error) {
+	return newKeyed(c, currentPolicy())
+}
+
+func newKeyed(c cipher.Block, p *Policy) (*Keyed, error) {
+	k := &Keyed{p: p}
+	if err := k.s.Init(c); err != nil {
+		return nil, err
+	}
+	if err := p.checkBlockSize(k.s.size); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// New returns a new hash.Hash computing CMAC with the key of k. It only
+// allocates the hash itself.
+func (k *Keyed) New() hash.Hash {
+	return k.p.wrap(&cmac{State: k.s})
+}
+
+// Sum returns the CMAC of msg. For ciphers with an 8-byte block only the
+// first 8 bytes of the result are used; the rest is zero. Sum does not
+// allocate. It returns an error, and no tag, if msg exceeds
+// Policy.MaxMessageSize.
+func (k *Keyed) Sum(msg []byte) ([16]byte, error) {
+	var tag [16]byte
+	if k.p != nil && k.p.MaxMessageSize > 0 && int64(len(msg)) > k.p.MaxMessageSize {
+		return tag, errors.New("cmac: message size limit exceeded")
+	}
+	s := scratchPool.Get().(*scratch)
+	defer s.release()
+	s.State = k.s
+	cbc := k.cbc.get()
+	s.write(msg, &cbc)
+	k.cbc.put(cbc)
+	copy(tag[:], s.Sum(s.tag[:0]))
+	return tag, nil
+}
+
+// Verify reports whether tag is the MAC of msg, using a constant-time
+// comparison. Only full-length tags are accepted, and a message refused
+// by the Policy always fails.
+func (k *Keyed) Verify(msg, tag []byte) bool {
+	t, err := k.Sum(msg)
+	return err == nil && subtle.ConstantTimeCompare(t[:k.s.size], tag) == 1
+}
+
+// wipe clears the subkeys of k and the CBC caches it has pooled. k must no
+// longer be in use.
+func (k *Keyed) wipe() {
+	k.s.Wipe()
+	for c := k.cbc.get(); c != nil; c = k.cbc.get() {
+		c.wipe()
+	}
+}
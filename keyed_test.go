@@ -0,0 +1,98 @@
+package cmac
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestKeyed(t *testing.T) {
+	for _, tv := range nistvectors {
+		c, err := tv.cipher(tv.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := NewKeyedWithCipher(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range tv.cases {
+			sum, err := k.Sum(tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(sum[:len(tc.mac)], tc.mac) {
+				t.Errorf("Sum: got %x, want %x", sum, tc.mac)
+			}
+			if !k.Verify(tc.msg, tc.mac) {
+				t.Error("Verify failed")
+			}
+			if len(tc.mac) == 8 && k.Verify(tc.msg, sum[:]) {
+				t.Error("Verify accepted a zero-padded 64-bit tag")
+			}
+			h := k.New()
+			h.Write(tc.msg)
+			if got := h.Sum(nil); !bytes.Equal(got, tc.mac) {
+				t.Errorf("New: got %x, want %x", got, tc.mac)
+			}
+		}
+	}
+
+	if _, err := NewKeyed(nil); err == nil {
+		t.Error("accepted an empty key")
+	}
+}
+
+func TestKeyedPolicy(t *testing.T) {
+	SetPolicy(&Policy{MaxMessageSize: 16})
+	defer SetPolicy(nil)
+	k, err := NewKeyed(nistvectors[0].key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, err := k.Sum(nistmsg[:40]); err == nil {
+		t.Errorf("Sum over the size limit returned %x", tag)
+	}
+	if k.Verify(nistmsg[:40], nistvectors[0].cases[2].mac) {
+		t.Error("Verify accepted a message over the size limit")
+	}
+	if _, err := k.New().Write(nistmsg[:40]); err == nil {
+		t.Error("hash accepted a message over the size limit")
+	}
+}
+
+func TestKeyedConcurrent(t *testing.T) {
+	tv := nistvectors[0]
+	k, _ := NewKeyed(tv.key)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if !k.Verify(tv.cases[3].msg, tv.cases[3].mac) {
+					t.Error("Verify failed")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestKeyedAllocs(t *testing.T) {
+	k, _ := NewKeyed(nistvectors[0].key)
+	if n := testing.AllocsPerRun(100, func() { k.Sum(nistmsg) }); n > 0 {
+		t.Errorf("Sum allocates %v times", n)
+	}
+}
+
+func BenchmarkKeyedSum64(b *testing.B) {
+	k, _ := NewKeyed(make([]byte, 16))
+	msg := make([]byte, 64)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		k.Sum(msg)
+	}
+}
@@ -0,0 +1,173 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// KeyAlgorithm is the algorithm name recorded for AES-CMAC keys.
+const KeyAlgorithm = "AES-CMAC"
+
+const keyPEMType = "CMAC KEY"
+
+// Key is an AES-CMAC key together with its metadata.
+type Key struct {
+	Algorithm string
+	ID        string
+	Created   time.Time
+	Material  []byte
+}
+
+// GenerateKey returns a new random AES-CMAC key of the given size in bits
+// (128, 192 or 256), reading randomness from rand, which would usually be
+// crypto/rand.Reader.
+func GenerateKey(rand io.Reader, bits int) (Key, error) {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return Key{}, fmt.Errorf("cmac: invalid key size %d bits", bits)
+	}
+
+	m := make([]byte, bits/8)
+	if _, err := io.ReadFull(rand, m); err != nil {
+		return Key{}, err
+	}
+	return Key{Algorithm: KeyAlgorithm, Created: time.Now().UTC(), Material: m}, nil
+}
+
+// KCV returns the key check value of an AES key: the leftmost five bytes
+// of the AES-CMAC of an all-zero block, as in ANSI X9.24-1:2017.
+func KCV(key []byte) ([]byte, error) {
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(make([]byte, 16))
+	return h.Sum(nil)[:5], nil
+}
+
+// KCV returns the key check value of k.
+func (k *Key) KCV() ([]byte, error) {
+	return KCV(k.Material)
+}
+
+// MarshalKey encodes k as a PEM block of type "CMAC KEY", with the
+// algorithm, key ID, creation time and key check value as headers:
+//
+//	-----BEGIN CMAC KEY-----
+//	Algorithm: AES-CMAC
+//	Created: 2025-01-02T15:04:05Z
+//	KCV: 7ad386c376
+//	Key-Id: storage-2025
+//
+//	K34VFiiu0qar9xWICc9PPA==
+//	-----END CMAC KEY-----
+func MarshalKey(k *Key) ([]byte, error) {
+	alg := k.Algorithm
+	if alg == "" {
+		alg = KeyAlgorithm
+	}
+	if alg != KeyAlgorithm {
+		return nil, fmt.Errorf("cmac: unsupported key algorithm %q", alg)
+	}
+	kcv, err := k.KCV()
+	if err != nil {
+		return nil, err
+	}
+
+	h := map[string]string{
+		"Algorithm": alg,
+		"KCV":       hex.EncodeToString(kcv),
+	}
+	if k.ID != "" {
+		h["Key-Id"] = k.ID
+	}
+	if !k.Created.IsZero() {
+		h["Created"] = k.Created.UTC().Format(time.RFC3339)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: keyPEMType, Headers: h, Bytes: k.Material}), nil
+}
+
+// UnmarshalKey decodes a key encoded by MarshalKey, checking the key
+// material against the recorded key check value.
+func UnmarshalKey(data []byte) (*Key, error) {
+	b, _ := pem.Decode(data)
+	if b == nil || b.Type != keyPEMType {
+		return nil, errors.New("cmac: no CMAC KEY block found")
+	}
+
+	k := &Key{Algorithm: b.Headers["Algorithm"], ID: b.Headers["Key-Id"], Material: b.Bytes}
+	if k.Algorithm != KeyAlgorithm {
+		return nil, fmt.Errorf("cmac: unsupported key algorithm %q", k.Algorithm)
+	}
+	if c, ok := b.Headers["Created"]; ok {
+		t, err := time.Parse(time.RFC3339, c)
+		if err != nil {
+			return nil, fmt.Errorf("cmac: invalid key creation time: %v", err)
+		}
+		k.Created = t
+	}
+
+	want, err := hex.DecodeString(b.Headers["KCV"])
+	if err != nil || len(want) == 0 {
+		return nil, errors.New("cmac: missing or invalid key check value")
+	}
+	kcv, err := k.KCV()
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(kcv, want) != 1 {
+		return nil, errors.New("cmac: key check value mismatch")
+	}
+	return k, nil
+}
+
+// SaveKey writes k to a new file at path, readable and writable only by
+// its owner. It fails if the file already exists.
+func SaveKey(path string, k *Key) error {
+	data, err := MarshalKey(k)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadKey reads a key written by SaveKey. Except on Windows, it refuses
+// files that are accessible to the group or to others.
+func LoadKey(path string) (*Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("cmac: key file %s has permissions %v, want 0600 or stricter", path, fi.Mode().Perm())
+	}
+
+	data := make([]byte, fi.Size())
+	if _, err := f.ReadAt(data, 0); err != nil {
+		return nil, err
+	}
+	return UnmarshalKey(data)
+}
@@ -0,0 +1,110 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKCV(t *testing.T) {
+	c, err := KCV(unhex("2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := unhex("7ad386c376"); !bytes.Equal(c, expected) {
+		t.Errorf("expected: %x got %x\n", expected, c)
+	}
+}
+
+func TestMarshalKey(t *testing.T) {
+	k := &Key{
+		ID:       "storage-2025",
+		Created:  time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+		Material: unhex("2b7e151628aed2a6abf7158809cf4f3c"),
+	}
+	data, err := MarshalKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Key-Id: storage-2025") {
+		t.Errorf("missing key ID header:\n%s", data)
+	}
+
+	k2, err := UnmarshalKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.ID != k.ID || !k2.Created.Equal(k.Created) || !bytes.Equal(k2.Material, k.Material) || k2.Algorithm != KeyAlgorithm {
+		t.Errorf("round trip mismatch: %+v", k2)
+	}
+
+	// A corrupted key fails the check value.
+	bad := strings.Replace(string(data), "K34VFiiu0qar9xWICc9PPA==", "K34VFiiu0qar9xWICc9PPQ==", 1)
+	if bad == string(data) {
+		t.Fatal("test key encoding not found")
+	}
+	if _, err := UnmarshalKey([]byte(bad)); err == nil {
+		t.Error("expected check value mismatch")
+	}
+
+	if _, err := MarshalKey(&Key{Algorithm: "HMAC", Material: k.Material}); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+}
+
+func TestSaveLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.pem")
+	k := &Key{ID: "id", Material: make([]byte, 32)}
+
+	if err := SaveKey(path, k); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKey(path, k); err == nil {
+		t.Error("SaveKey overwrote an existing file")
+	}
+
+	k2, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k2.Material, k.Material) {
+		t.Error("loaded key differs")
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKey(path); err == nil {
+			t.Error("LoadKey accepted a world-readable key file")
+		}
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	for _, bits := range []int{128, 192, 256} {
+		k, err := GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(k.Material) != bits/8 || k.Algorithm != KeyAlgorithm || k.Created.IsZero() {
+			t.Errorf("%d bits: bad key %+v", bits, k)
+		}
+		if _, err := k.KCV(); err != nil {
+			t.Errorf("%d bits: KCV: %v", bits, err)
+		}
+	}
+
+	if _, err := GenerateKey(rand.Reader, 64); err == nil {
+		t.Error("expected error for 64-bit key")
+	}
+	if _, err := GenerateKey(bytes.NewReader(make([]byte, 8)), 128); err == nil {
+		t.Error("expected error for short random source")
+	}
+}
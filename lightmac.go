@@ -0,0 +1,110 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"errors"
+	"hash"
+)
+
+// lightMAC implements LightMAC of Luykx, Preneel, Tischhauser and Yasuda,
+// as standardized in ISO/IEC 29192-6.
+type lightMAC struct {
+	c1, c2  cipher.Block
+	size    int
+	ctrSize int
+	// max is the largest counter value, 0 if it does not fit a uint64.
+	max uint64
+
+	v [16]byte
+	// buf holds the last chunk of up to size-ctrSize bytes seen, which is
+	// only processed once it is known not to be the final one.
+	buf    [16]byte
+	cursor int
+	ctr    uint64
+	err    error
+}
+
+// NewLightMAC returns a hash.Hash computing LightMAC with two independently
+// keyed instances of the same block cipher: c1 processes the message and
+// c2 the final sum. The block size must be 8 or 16 bytes and counterSize,
+// in bytes, at least 1 and less than the block size.
+//
+// Each block of input carries blockSize-counterSize bytes of message next
+// to its counter, so the forgery bound depends on the number of messages
+// rather than their length. This makes LightMAC a better fit than CMAC for
+// 64-bit block ciphers. Messages are limited to 2^(8*counterSize)-1 blocks;
+// Write returns an error beyond that.
+func NewLightMAC(c1, c2 cipher.Block, counterSize int) (hash.Hash, error) {
+	if c1 == nil || c2 == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	size := c1.BlockSize()
+	if size != 8 && size != 16 || c2.BlockSize() != size {
+		return nil, errors.New("cmac: invalid block size")
+	}
+	if counterSize < 1 || counterSize >= size {
+		return nil, errors.New("cmac: invalid LightMAC counter size")
+	}
+	p := currentPolicy()
+	if err := p.checkBlockSize(size); err != nil {
+		return nil, err
+	}
+	if err := p.CheckTagSize(size); err != nil {
+		return nil, err
+	}
+
+	m := &lightMAC{c1: c1, c2: c2, size: size, ctrSize: counterSize}
+	if counterSize < 8 {
+		m.max = 1<<(8*uint(counterSize)) - 1
+	}
+	return p.wrap(m), nil
+}
+
+func (m *lightMAC) Size() int      { return m.size }
+func (m *lightMAC) BlockSize() int { return m.size - m.ctrSize }
+
+func (m *lightMAC) Reset() {
+	m.v, m.buf = [16]byte{}, [16]byte{}
+	m.cursor, m.ctr, m.err = 0, 0, nil
+}
+
+func (m *lightMAC) Write(b []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	n, chunk := len(b), m.size-m.ctrSize
+	for len(b) > 0 {
+		if m.cursor == chunk {
+			if m.ctr == m.max && m.max != 0 {
+				m.err = errors.New("cmac: message too long for LightMAC counter")
+				return n - len(b), m.err
+			}
+			m.process()
+			m.cursor = 0
+		}
+		c := copy(m.buf[m.cursor:chunk], b)
+		m.cursor += c
+		b = b[c:]
+	}
+	return n, nil
+}
+
+// process adds the buffered chunk, which is not the last one, to the sum.
+func (m *lightMAC) process() {
+	m.ctr++
+	var t [16]byte
+	for i, c := 0, m.ctr; i < m.ctrSize; i, c = i+1, c>>8 {
+		t[m.ctrSize-1-i] = byte(c)
+	}
+	copy(t[m.ctrSize:m.size], m.buf[:m.size-m.ctrSize])
+	m.c1.Encrypt(t[:m.size], t[:m.size])
+	xorBlock(m.v[:m.size], t[:m.size])
+}
+
+func (m *lightMAC) Sum(b []byte) []byte {
+	v := m.v
+	xorBlock(v[:], m.buf[:m.cursor])
+	v[m.cursor] ^= 0x80
+	m.c2.Encrypt(v[:m.size], v[:m.size])
+	return append(b, v[:m.size]...)
+}
@@ -0,0 +1,84 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"testing"
+)
+
+// lightMACDirect computes LightMAC over a whole message as written in the
+// specification.
+func lightMACDirect(c1, c2 cipher.Block, s int, msg []byte) []byte {
+	n := c1.BlockSize()
+	v := make([]byte, n)
+	i := 1
+	for ; len(msg) > n-s; i++ {
+		t := make([]byte, n)
+		for j, c := 0, i; j < s; j, c = j+1, c>>8 {
+			t[s-1-j] = byte(c)
+		}
+		copy(t[s:], msg[:n-s])
+		c1.Encrypt(t, t)
+		xorBlock(v, t)
+		msg = msg[n-s:]
+	}
+	xorBlock(v, msg)
+	v[len(msg)] ^= 0x80
+	c2.Encrypt(v, v)
+	return v
+}
+
+func TestLightMAC(t *testing.T) {
+	a1, _ := aes.NewCipher(seq(16))
+	a2, _ := aes.NewCipher(seq(32)[16:])
+	d1, _ := des.NewTripleDESCipher(seq(24))
+	d2, _ := des.NewTripleDESCipher(seq(48)[24:])
+
+	for _, ciphers := range [][2]cipher.Block{{a1, a2}, {d1, d2}} {
+		for _, s := range []int{1, 2, 4} {
+			h, err := NewLightMAC(ciphers[0], ciphers[1], s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range []int{0, 1, 3, 4, 5, 12, 13, 100} {
+				msg := seq(n)
+				h.Reset()
+				for j := range msg {
+					h.Write(msg[j : j+1])
+				}
+				expected := lightMACDirect(ciphers[0], ciphers[1], s, msg)
+				if tag := h.Sum(nil); !bytes.Equal(tag, expected) {
+					t.Errorf("block %d, counter %d, len %d: expected %x got %x", h.Size(), s, n, expected, tag)
+				}
+			}
+		}
+	}
+
+	// A 1-byte counter on a 64-bit cipher allows 255 blocks of 7 bytes;
+	// the last block is held back, so one more fits.
+	h, _ := NewLightMAC(d1, d2, 1)
+	if _, err := h.Write(make([]byte, 256*7)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := h.Write([]byte{0}); err == nil {
+		t.Error("expected error for counter overflow")
+	}
+
+	if _, err := NewLightMAC(a1, d2, 2); err == nil {
+		t.Error("expected error for mismatched ciphers")
+	}
+	if _, err := NewLightMAC(a1, a2, 16); err == nil {
+		t.Error("expected error for oversized counter")
+	}
+
+	SetPolicy(&Policy{MinTagSize: 16})
+	defer SetPolicy(nil)
+	if _, err := NewLightMAC(d1, d2, 2); err == nil {
+		t.Error("64-bit tag accepted under a policy requiring 128 bits")
+	}
+	if _, err := NewLightMAC(a1, a2, 2); err != nil {
+		t.Errorf("128-bit tag: %v", err)
+	}
+}
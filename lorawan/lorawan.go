@@ -0,0 +1,241 @@
+// Package lorawan implements the message integrity codes and session key
+// derivations of LoRaWAN 1.0.x and 1.1.
+//
+// All functions take messages as they appear on air, from MHDR up to but
+// excluding the MIC, and multi-byte fields as integers or in the byte
+// order they are written in the specification (most significant byte
+// first); they are serialized least significant byte first as LoRaWAN
+// requires.
+package lorawan
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/joekir/cmac"
+)
+
+// MICSize is the length of a MIC in bytes.
+const MICSize = 4
+
+// Direction is the direction of a data frame.
+type Direction byte
+
+// Frame directions.
+const (
+	Uplink   Direction = 0
+	Downlink Direction = 1
+)
+
+// EUI64 is a DevEUI, JoinEUI or AppEUI, most significant byte first.
+type EUI64 [8]byte
+
+// Join request types authenticated by the 1.1 join-accept MIC.
+const (
+	JoinRequest    = 0xff
+	RejoinRequest0 = 0x00
+	RejoinRequest1 = 0x01
+	RejoinRequest2 = 0x02
+)
+
+// block returns the B0 or B1 block 0x49 || head (4 bytes) || dir ||
+// devAddr || fcnt || 0x00 || len(msg).
+func block(head [4]byte, dir Direction, devAddr, fcnt uint32, msg []byte) ([16]byte, error) {
+	var b [16]byte
+	if len(msg) > 0xff {
+		return b, errors.New("lorawan: message too long")
+	}
+	b[0] = 0x49
+	copy(b[1:5], head[:])
+	b[5] = byte(dir)
+	binary.LittleEndian.PutUint32(b[6:], devAddr)
+	binary.LittleEndian.PutUint32(b[10:], fcnt)
+	b[15] = byte(len(msg))
+	return b, nil
+}
+
+func mic(key []byte, parts ...[]byte) (m [MICSize]byte, err error) {
+	if len(key) != 16 {
+		return m, errors.New("lorawan: keys must be 16 bytes")
+	}
+	h, err := cmac.New(key)
+	if err != nil {
+		return m, err
+	}
+	for _, p := range parts {
+		h.Write(p)
+	}
+	copy(m[:], h.Sum(nil))
+	return m, nil
+}
+
+// B0 returns the B0 block of LoRaWAN 1.0.x data frames, which is also the
+// B0 block of 1.1 uplinks. fcnt is the full 32-bit frame counter.
+func B0(dir Direction, devAddr, fcnt uint32, msg []byte) ([16]byte, error) {
+	return block([4]byte{}, dir, devAddr, fcnt, msg)
+}
+
+// DataMIC computes the MIC of a LoRaWAN 1.0.x data frame with NwkSKey.
+func DataMIC(nwkSKey []byte, dir Direction, devAddr, fcnt uint32, msg []byte) ([MICSize]byte, error) {
+	b0, err := B0(dir, devAddr, fcnt, msg)
+	if err != nil {
+		return [MICSize]byte{}, err
+	}
+	return mic(nwkSKey, b0[:], msg)
+}
+
+// UplinkMIC11 computes the MIC of a LoRaWAN 1.1 uplink data frame:
+// the first two bytes of the CMAC over B1 with SNwkSIntKey followed by
+// the first two bytes of the CMAC over B0 with FNwkSIntKey. confFCnt is
+// the counter of the acknowledged downlink, or 0 if the ACK bit is unset.
+func UplinkMIC11(fNwkSIntKey, sNwkSIntKey []byte, confFCnt uint16, txDr, txCh uint8, devAddr, fcnt uint32, msg []byte) ([MICSize]byte, error) {
+	var m [MICSize]byte
+	b0, err := B0(Uplink, devAddr, fcnt, msg)
+	if err != nil {
+		return m, err
+	}
+	var head [4]byte
+	binary.LittleEndian.PutUint16(head[:], confFCnt)
+	head[2], head[3] = txDr, txCh
+	b1, err := block(head, Uplink, devAddr, fcnt, msg)
+	if err != nil {
+		return m, err
+	}
+
+	f, err := mic(fNwkSIntKey, b0[:], msg)
+	if err != nil {
+		return m, err
+	}
+	s, err := mic(sNwkSIntKey, b1[:], msg)
+	if err != nil {
+		return m, err
+	}
+	copy(m[:2], s[:2])
+	copy(m[2:], f[:2])
+	return m, nil
+}
+
+// DownlinkMIC11 computes the MIC of a LoRaWAN 1.1 downlink data frame with
+// SNwkSIntKey. fcnt is NFCntDown or AFCntDown depending on the port, and
+// confFCnt the counter of the acknowledged uplink, or 0 if the ACK bit is
+// unset.
+func DownlinkMIC11(sNwkSIntKey []byte, confFCnt uint16, devAddr, fcnt uint32, msg []byte) ([MICSize]byte, error) {
+	var head [4]byte
+	binary.LittleEndian.PutUint16(head[:], confFCnt)
+	b0, err := block(head, Downlink, devAddr, fcnt, msg)
+	if err != nil {
+		return [MICSize]byte{}, err
+	}
+	return mic(sNwkSIntKey, b0[:], msg)
+}
+
+// JoinMIC computes the MIC of a join-request or rejoin-request, and of a
+// LoRaWAN 1.0.x join-accept from its decrypted fields. The key is AppKey
+// for 1.0.x, NwkKey for 1.1 join-requests, SNwkSIntKey for rejoin-requests
+// of type 0 and 2 and JSIntKey for rejoin-requests of type 1.
+func JoinMIC(key, msg []byte) ([MICSize]byte, error) {
+	return mic(key, msg)
+}
+
+// JoinAcceptMIC11 computes the MIC of a LoRaWAN 1.1 join-accept with the
+// OptNeg bit set, from its decrypted fields, with JSIntKey. joinReqType is
+// JoinRequest or one of the rejoin request types answered by msg.
+func JoinAcceptMIC11(jsIntKey []byte, joinReqType byte, joinEUI EUI64, devNonce uint16, msg []byte) ([MICSize]byte, error) {
+	var pre [11]byte
+	pre[0] = joinReqType
+	putEUI(pre[1:9], joinEUI)
+	binary.LittleEndian.PutUint16(pre[9:], devNonce)
+	return mic(jsIntKey, pre[:], msg)
+}
+
+func putEUI(d []byte, e EUI64) {
+	for i := range e {
+		d[i] = e[len(e)-1-i]
+	}
+}
+
+func put24(d []byte, v uint32) {
+	d[0], d[1], d[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// deriveKey returns aes128_encrypt(key, in), the derivation primitive of
+// both specification versions.
+func deriveKey(key []byte, in *[16]byte) (k [16]byte, err error) {
+	if len(key) != 16 {
+		return k, errors.New("lorawan: keys must be 16 bytes")
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return k, err
+	}
+	c.Encrypt(k[:], in[:])
+	return k, nil
+}
+
+// SessionKeys10 derives NwkSKey and AppSKey of LoRaWAN 1.0.x from AppKey
+// and the AppNonce and NetID (24 bits each) of the join-accept and the
+// DevNonce of the join-request.
+func SessionKeys10(appKey []byte, appNonce, netID uint32, devNonce uint16) (nwkSKey, appSKey [16]byte, err error) {
+	var in [16]byte
+	put24(in[1:], appNonce)
+	put24(in[4:], netID)
+	binary.LittleEndian.PutUint16(in[7:], devNonce)
+
+	in[0] = 0x01
+	if nwkSKey, err = deriveKey(appKey, &in); err != nil {
+		return
+	}
+	in[0] = 0x02
+	appSKey, err = deriveKey(appKey, &in)
+	return
+}
+
+// SessionKeys11 holds the session keys of a LoRaWAN 1.1 device.
+type SessionKeys11 struct {
+	FNwkSIntKey, SNwkSIntKey, NwkSEncKey, AppSKey [16]byte
+}
+
+// DeriveSessionKeys11 derives the LoRaWAN 1.1 session keys from NwkKey
+// and AppKey, the JoinNonce (24 bits) of the join-accept and the JoinEUI
+// and DevNonce of the join-request.
+func DeriveSessionKeys11(nwkKey, appKey []byte, joinNonce uint32, joinEUI EUI64, devNonce uint16) (*SessionKeys11, error) {
+	var in [16]byte
+	put24(in[1:], joinNonce)
+	putEUI(in[4:12], joinEUI)
+	binary.LittleEndian.PutUint16(in[12:], devNonce)
+
+	var k SessionKeys11
+	for _, d := range []struct {
+		prefix byte
+		root   []byte
+		out    *[16]byte
+	}{
+		{0x01, nwkKey, &k.FNwkSIntKey},
+		{0x02, appKey, &k.AppSKey},
+		{0x03, nwkKey, &k.SNwkSIntKey},
+		{0x04, nwkKey, &k.NwkSEncKey},
+	} {
+		in[0] = d.prefix
+		var err error
+		if *d.out, err = deriveKey(d.root, &in); err != nil {
+			return nil, err
+		}
+	}
+	return &k, nil
+}
+
+// JoinServerKeys derives JSIntKey and JSEncKey of LoRaWAN 1.1 from NwkKey
+// and the DevEUI.
+func JoinServerKeys(nwkKey []byte, devEUI EUI64) (jsIntKey, jsEncKey [16]byte, err error) {
+	var in [16]byte
+	putEUI(in[1:9], devEUI)
+
+	in[0] = 0x06
+	if jsIntKey, err = deriveKey(nwkKey, &in); err != nil {
+		return
+	}
+	in[0] = 0x05
+	jsEncKey, err = deriveKey(nwkKey, &in)
+	return
+}
@@ -0,0 +1,129 @@
+package lorawan
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func cmacOf(key []byte, parts ...[]byte) []byte {
+	h, _ := cmac.New(key)
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func TestDataMIC(t *testing.T) {
+	// Unconfirmed uplink "test" from DevAddr 49be7df1, FCnt 2.
+	phy := unhex("40f17dbe4900020001954378762b11ff0d")
+	m, err := DataMIC(unhex("44024241ed4ce9a68c6a8bc055233fd3"), Uplink, 0x49be7df1, 2, phy[:len(phy)-4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m[:], phy[len(phy)-4:]) {
+		t.Errorf("expected %x got %x", phy[len(phy)-4:], m)
+	}
+}
+
+func TestMIC11(t *testing.T) {
+	f := unhex("000102030405060708090a0b0c0d0e0f")
+	s := unhex("101112131415161718191a1b1c1d1e1f")
+	msg := unhex("40f17dbe4920030001954378")
+
+	m, err := UplinkMIC11(f, s, 0x0201, 5, 3, 0x49be7df1, 0x10003, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b0 := unhex("4900000000" + "00" + "f17dbe49" + "03000100" + "000c")
+	b1 := unhex("490102" + "0503" + "00" + "f17dbe49" + "03000100" + "000c")
+	expected := append(cmacOf(s, b1, msg)[:2], cmacOf(f, b0, msg)[:2]...)
+	if !bytes.Equal(m[:], expected) {
+		t.Errorf("uplink: expected %x got %x", expected, m)
+	}
+
+	m, _ = DownlinkMIC11(s, 0x0201, 0x49be7df1, 7, msg)
+	b0 = unhex("4901020000" + "01" + "f17dbe49" + "07000000" + "000c")
+	if expected := cmacOf(s, b0, msg)[:4]; !bytes.Equal(m[:], expected) {
+		t.Errorf("downlink: expected %x got %x", expected, m)
+	}
+
+	joinEUI := EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+	m, _ = JoinAcceptMIC11(f, JoinRequest, joinEUI, 0x1234, msg)
+	pre := unhex("ff" + "0807060504030201" + "3412")
+	if expected := cmacOf(f, pre, msg)[:4]; !bytes.Equal(m[:], expected) {
+		t.Errorf("join-accept: expected %x got %x", expected, m)
+	}
+
+	if _, err := JoinMIC(f[:8], msg); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func encrypt(key, in []byte) []byte {
+	c, _ := aes.NewCipher(key)
+	out := make([]byte, 16)
+	c.Encrypt(out, in)
+	return out
+}
+
+func TestSessionKeys(t *testing.T) {
+	appKey := unhex("000102030405060708090a0b0c0d0e0f")
+	nwkKey := unhex("101112131415161718191a1b1c1d1e1f")
+
+	nwk, app, err := SessionKeys10(appKey, 0x030201, 0x000013, 0x0605)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := unhex("01" + "010203" + "130000" + "0506" + "00000000000000")
+	if !bytes.Equal(nwk[:], encrypt(appKey, in)) {
+		t.Error("NwkSKey mismatch")
+	}
+	in[0] = 0x02
+	if !bytes.Equal(app[:], encrypt(appKey, in)) {
+		t.Error("AppSKey mismatch")
+	}
+
+	joinEUI := EUI64{1, 2, 3, 4, 5, 6, 7, 8}
+	k, err := DeriveSessionKeys11(nwkKey, appKey, 0x030201, joinEUI, 0x0605)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in = unhex("00" + "010203" + "0807060504030201" + "0506" + "0000")
+	for _, d := range []struct {
+		prefix byte
+		root   []byte
+		got    [16]byte
+	}{
+		{0x01, nwkKey, k.FNwkSIntKey},
+		{0x02, appKey, k.AppSKey},
+		{0x03, nwkKey, k.SNwkSIntKey},
+		{0x04, nwkKey, k.NwkSEncKey},
+	} {
+		in[0] = d.prefix
+		if !bytes.Equal(d.got[:], encrypt(d.root, in)) {
+			t.Errorf("key %02x mismatch", d.prefix)
+		}
+	}
+
+	ji, je, _ := JoinServerKeys(nwkKey, joinEUI)
+	in = unhex("06" + "0807060504030201" + "00000000000000")
+	if !bytes.Equal(ji[:], encrypt(nwkKey, in)) {
+		t.Error("JSIntKey mismatch")
+	}
+	in[0] = 0x05
+	if !bytes.Equal(je[:], encrypt(nwkKey, in)) {
+		t.Error("JSEncKey mismatch")
+	}
+}
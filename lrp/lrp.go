@@ -0,0 +1,184 @@
+// Package lrp implements the Leakage Resilient Primitive of NXP AN12304,
+// as used by NTAG 424 DNA and MIFARE DESFire EV3 cards in LRP mode: the
+// plaintext and updated key generation, the LRP evaluation function,
+// LRICB encryption and the CMAC-LRP MAC.
+//
+// LRP is built from AES-128 with a fresh key for every nibble of input,
+// so it is far slower than AES and meant for the short messages of card
+// protocols.
+package lrp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// BlockSize is the LRP block size in bytes.
+const BlockSize = 16
+
+// The AN12304 parameter m: plaintexts are indexed by m-bit nibbles.
+const numPlaintexts = 16
+
+var (
+	c55 = [BlockSize]byte{0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55}
+	cAA = [BlockSize]byte{0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa}
+)
+
+func encrypt(key, in [BlockSize]byte) [BlockSize]byte {
+	c, _ := aes.NewCipher(key[:])
+	c.Encrypt(in[:], in[:])
+	return in
+}
+
+func checkKey(key []byte) ([BlockSize]byte, error) {
+	var k [BlockSize]byte
+	if len(key) != BlockSize {
+		return k, errors.New("lrp: invalid key size")
+	}
+	copy(k[:], key)
+	return k, nil
+}
+
+// Plaintexts returns the 16 plaintexts derived from key (AN12304
+// algorithm 1).
+func Plaintexts(key []byte) ([numPlaintexts][BlockSize]byte, error) {
+	var p [numPlaintexts][BlockSize]byte
+	h, err := checkKey(key)
+	if err != nil {
+		return p, err
+	}
+	h = encrypt(h, c55)
+	for i := range p {
+		p[i] = encrypt(h, cAA)
+		h = encrypt(h, c55)
+	}
+	return p, nil
+}
+
+// UpdatedKeys returns the first n updated keys derived from key (AN12304
+// algorithm 2).
+func UpdatedKeys(key []byte, n int) ([][BlockSize]byte, error) {
+	h, err := checkKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.New("lrp: invalid number of updated keys")
+	}
+	ks := make([][BlockSize]byte, n)
+	h = encrypt(h, cAA)
+	for i := range ks {
+		ks[i] = encrypt(h, cAA)
+		h = encrypt(h, c55)
+	}
+	return ks, nil
+}
+
+// LRP is an instance of the primitive: the plaintexts of a key together
+// with one of its updated keys.
+type LRP struct {
+	p [numPlaintexts][BlockSize]byte
+	k [BlockSize]byte
+}
+
+// New returns the LRP instance for key that uses the updated key with
+// index u. NTAG 424 DNA uses index 0 for both CMAC-LRP and LRICB.
+func New(key []byte, u int) (*LRP, error) {
+	p, err := Plaintexts(key)
+	if err != nil {
+		return nil, err
+	}
+	if u < 0 {
+		return nil, errors.New("lrp: invalid updated key index")
+	}
+	ks, err := UpdatedKeys(key, u+1)
+	if err != nil {
+		return nil, err
+	}
+	return &LRP{p: p, k: ks[u]}, nil
+}
+
+// Eval evaluates LRP on x, one nibble at a time starting with the most
+// significant nibble of x[0] (AN12304 algorithm 3). If final is set, the
+// result is encrypted once more under itself with an all-zero block.
+func (l *LRP) Eval(x []byte, final bool) [BlockSize]byte {
+	y := l.k
+	for _, b := range x {
+		y = encrypt(y, l.p[b>>4])
+		y = encrypt(y, l.p[b&0x0f])
+	}
+	if final {
+		y = encrypt(y, [BlockSize]byte{})
+	}
+	return y
+}
+
+// EncryptLRICB encrypts src into dst in LRICB mode (AN12304 algorithm 4),
+// starting from counter, which is incremented in place once per block as
+// a big-endian integer of its length. src must be a multiple of BlockSize
+// long; applying padding is up to the caller. dst and src may overlap
+// entirely or not at all.
+func (l *LRP) EncryptLRICB(dst, src, counter []byte) {
+	l.lricb(dst, src, counter, func(c cipher.Block, dst, src []byte) { c.Encrypt(dst, src) })
+}
+
+// DecryptLRICB is the inverse of EncryptLRICB.
+func (l *LRP) DecryptLRICB(dst, src, counter []byte) {
+	l.lricb(dst, src, counter, func(c cipher.Block, dst, src []byte) { c.Decrypt(dst, src) })
+}
+
+func (l *LRP) lricb(dst, src, counter []byte, f func(c cipher.Block, dst, src []byte)) {
+	if len(src)%BlockSize != 0 {
+		panic("lrp: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("lrp: output smaller than input")
+	}
+	for i := 0; i < len(src); i += BlockSize {
+		k := l.Eval(counter, true)
+		c, _ := aes.NewCipher(k[:])
+		f(c, dst[i:i+BlockSize], src[i:i+BlockSize])
+		for j := len(counter) - 1; j >= 0; j-- {
+			if counter[j]++; counter[j] != 0 {
+				break
+			}
+		}
+	}
+}
+
+// block makes the final LRP evaluation usable as the block function of
+// CMAC, which only ever encrypts.
+type block struct{ *LRP }
+
+func (block) BlockSize() int { return BlockSize }
+
+func (b block) Encrypt(dst, src []byte) {
+	y := b.Eval(src[:BlockSize], true)
+	copy(dst, y[:])
+}
+
+func (block) Decrypt(dst, src []byte) { panic("lrp: CMAC-LRP block function is not invertible") }
+
+// CMAC returns a hash.Hash computing CMAC-LRP (AN12304 algorithm 6): CMAC
+// with the final LRP evaluation in place of the block cipher.
+func (l *LRP) CMAC() hash.Hash {
+	h, err := cmac.NewWithCipher(block{l})
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// NewCMAC returns a hash.Hash computing CMAC-LRP with key and its first
+// updated key, as NTAG 424 DNA does.
+func NewCMAC(key []byte) (hash.Hash, error) {
+	l, err := New(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	return l.CMAC(), nil
+}
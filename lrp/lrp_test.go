@@ -0,0 +1,183 @@
+package lrp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var key = unhex("567826b8da8e768432a9548dbe4aa3a0")
+
+// Vectors from NXP AN12304 sections 2.1 and 2.2.
+func TestGeneration(t *testing.T) {
+	p, err := Plaintexts(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range map[int]string{
+		0:  "ac20d39f5341fe98dfca21da86ba7914",
+		15: "71b444af257a93215311d758dd333247",
+	} {
+		if got := p[i][:]; !bytes.Equal(got, unhex(want)) {
+			t.Errorf("plaintext %d: expected %s got %x", i, want, got)
+		}
+	}
+
+	ks, err := UpdatedKeys(key, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{
+		"163d14ed24ed935373568ec521e96cf4",
+		"1c519c000208b95a39a65db058327188",
+		"fe30ab50467e61783bfe6b5e0560160e",
+	} {
+		if !bytes.Equal(ks[i][:], unhex(want)) {
+			t.Errorf("updated key %d: expected %s got %x", i, want, ks[i])
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	l, err := New(key, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Eval walks the plaintexts nibble by nibble from the updated key.
+	p, _ := Plaintexts(key)
+	ks, _ := UpdatedKeys(key, 3)
+	y := ks[2]
+	for _, n := range []byte{0x1, 0x3, 0x5, 0x9} {
+		c, _ := aes.NewCipher(y[:])
+		c.Encrypt(y[:], p[n][:])
+	}
+	if got := l.Eval([]byte{0x13, 0x59}, false); got != y {
+		t.Errorf("expected %x got %x", y, got)
+	}
+	c, _ := aes.NewCipher(y[:])
+	c.Encrypt(y[:], make([]byte, BlockSize))
+	if got := l.Eval([]byte{0x13, 0x59}, true); got != y {
+		t.Errorf("final: expected %x got %x", y, got)
+	}
+}
+
+// Vectors from NXP AN12304.
+func TestEvalVectors(t *testing.T) {
+	for _, tt := range []struct {
+		key  string
+		u    int
+		x    string
+		want string
+	}{
+		{"567826b8da8e768432a9548dbe4aa3a0", 2, "1359", "1ba2c0c578996bc497dd181c6885a9dd"},
+		{"88b95581002057a93e421efe4076338b", 2, "77299d", "e9c04556a214ac3297b83e4bdf46f142"},
+	} {
+		l, err := New(unhex(tt.key), tt.u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Eval(unhex(tt.x), true); !bytes.Equal(got[:], unhex(tt.want)) {
+			t.Errorf("key %s, x %s: expected %s got %x", tt.key, tt.x, tt.want, got)
+		}
+	}
+}
+
+// Vector from NXP AN12304, with the plaintext padded by the caller.
+func TestLRICBVector(t *testing.T) {
+	l, _ := New(unhex("e0c4935ff0c254cd2cef8fddc32460cf"), 0)
+	pt := unhex("012d7f1653caf6503c6ab0c1010e8cb080000000000000000000000000000000")
+	want := unhex("fcbbacaa4f29182464f99de41085266f480e863e487baaf687b43ed1ece0d623")
+
+	ctr := unhex("c3315dbf")
+	ct := make([]byte, len(pt))
+	l.EncryptLRICB(ct, pt, ctr)
+	if !bytes.Equal(ct, want) {
+		t.Errorf("expected %x got %x", want, ct)
+	}
+	if !bytes.Equal(ctr, unhex("c3315dc1")) {
+		t.Errorf("counter: expected c3315dc1 got %x", ctr)
+	}
+	l.DecryptLRICB(ct, ct, unhex("c3315dbf"))
+	if !bytes.Equal(ct, pt) {
+		t.Errorf("decrypt: expected %x got %x", pt, ct)
+	}
+}
+
+func TestLRICB(t *testing.T) {
+	l, _ := New(key, 0)
+	pt := bytes.Repeat([]byte("0123456789abcdef"), 3)
+
+	ctr := []byte{0x00, 0xff}
+	ct := make([]byte, len(pt))
+	l.EncryptLRICB(ct, pt, ctr)
+	if !bytes.Equal(ctr, []byte{0x01, 0x02}) {
+		t.Errorf("counter not advanced per block: %x", ctr)
+	}
+	if bytes.Equal(ct[:16], ct[16:32]) {
+		t.Error("equal blocks encrypt equally")
+	}
+
+	out := make([]byte, len(ct))
+	l.DecryptLRICB(out, ct, []byte{0x00, 0xff})
+	if !bytes.Equal(out, pt) {
+		t.Errorf("expected %x got %x", pt, out)
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	h, err := NewCMAC(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("CMAC-LRP message spanning blocks")
+	h.Write(msg)
+	tag := h.Sum(nil)
+
+	// CMAC over the LRP block function, computed directly.
+	l, _ := New(key, 0)
+	k1 := l.Eval(make([]byte, BlockSize), true)
+	cmac.Dbl(k1[:], k1[:])
+	var y [BlockSize]byte
+	for i := 0; i < len(msg); i += BlockSize {
+		for j := range y {
+			y[j] ^= msg[i+j]
+			if i+BlockSize == len(msg) {
+				y[j] ^= k1[j]
+			}
+		}
+		y = l.Eval(y[:], true)
+	}
+	if !bytes.Equal(tag, y[:]) {
+		t.Errorf("expected %x got %x", y, tag)
+	}
+
+	// Vectors from NXP AN12304.
+	for _, tt := range []struct{ key, msg, want string }{
+		{"63a0169b4d9fe42c72b2784c806eac21", "", "0e07c601970814a4176fda633c6fc3de"},
+		{"8195088ce6c393708ebbe6c7914ecb0b", "bbd5b85772c7", "ad8595e0b49c5c0db18e77355f5aaff6"},
+		{"e2f84a0b0af40efeb3eea215a436605c", "8bf1dda9fe445560a4f4eb9ce0", "d04382df71bc293fec4bb10bdb13805f"},
+		{"5aa9f6c6de5138113df5d6b6c77d5d52", "a4434d740c2cb665fe5396959189383f", "8b43adf767e46b692e8f24e837cb5efc"},
+	} {
+		h, _ := NewCMAC(unhex(tt.key))
+		h.Write(unhex(tt.msg))
+		if got := h.Sum(nil); !bytes.Equal(got, unhex(tt.want)) {
+			t.Errorf("key %s: expected %s got %x", tt.key, tt.want, got)
+		}
+	}
+
+	if _, err := NewCMAC(key[1:]); err == nil {
+		t.Error("expected error for invalid key size")
+	}
+}
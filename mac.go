@@ -0,0 +1,94 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// Algorithm names a MAC algorithm that NewMAC can construct.
+type Algorithm string
+
+// Algorithms supported by NewMAC.
+const (
+	AlgCMAC   Algorithm = "AES-CMAC"
+	AlgCMAC96 Algorithm = "AES-CMAC-96"
+	AlgOMAC2  Algorithm = "AES-OMAC2"
+	AlgXCBC   Algorithm = "AES-XCBC-MAC"
+	AlgXCBC96 Algorithm = "AES-XCBC-MAC-96"
+	AlgPMAC   Algorithm = "AES-PMAC"
+	AlgGMAC   Algorithm = "AES-GMAC"
+)
+
+// MAC is a keyed hash.Hash that can verify tags in constant time.
+type MAC interface {
+	hash.Hash
+
+	// Verify reports whether tag is the MAC of the data written so far.
+	// It does not change the underlying state.
+	Verify(tag []byte) bool
+}
+
+// NeedsNonce reports whether a takes a nonce.
+func (a Algorithm) NeedsNonce() bool {
+	return a == AlgGMAC
+}
+
+// NewMAC returns a MAC computing a with key, so that applications can make
+// the algorithm a configuration choice. The nonce is required for
+// algorithms for which NeedsNonce reports true and must be nil otherwise.
+// Constructed MACs are subject to the package Policy set with SetPolicy.
+func NewMAC(a Algorithm, key, nonce []byte) (MAC, error) {
+	if a.NeedsNonce() != (nonce != nil) {
+		if nonce == nil {
+			return nil, errors.New("cmac: " + string(a) + " requires a nonce")
+		}
+		return nil, errors.New("cmac: " + string(a) + " does not take a nonce")
+	}
+
+	var h hash.Hash
+	var err error
+	switch a {
+	case AlgCMAC:
+		h, err = New(key)
+	case AlgCMAC96:
+		h, err = New96(key)
+	case AlgOMAC2:
+		h, err = NewOMAC2(key)
+	case AlgXCBC:
+		h, err = NewXCBC(key)
+	case AlgXCBC96:
+		h, err = NewXCBC96(key)
+	case AlgPMAC:
+		h, err = NewPMAC(key)
+	case AlgGMAC:
+		h, err = NewGMAC(key, nonce)
+	default:
+		return nil, errors.New("cmac: unknown algorithm " + string(a))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := h.(MAC); ok {
+		return m, nil
+	}
+	return verifier{h}, nil
+}
+
+// verifier adds Verify to a hash.Hash.
+type verifier struct {
+	hash.Hash
+}
+
+func (v verifier) Verify(tag []byte) bool {
+	return verify(v.Hash, tag)
+}
+
+// verify compares tag with the current MAC of h, using h's own Verify if
+// it has one.
+func verify(h hash.Hash, tag []byte) bool {
+	if m, ok := h.(MAC); ok {
+		return m.Verify(tag)
+	}
+	return subtle.ConstantTimeCompare(h.Sum(nil), tag) == 1
+}
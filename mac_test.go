@@ -0,0 +1,125 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+func TestGMAC(t *testing.T) {
+	// The tag of GCM test case 1 of McGrew and Viega, which has neither
+	// plaintext nor additional data.
+	h, err := NewGMAC(make([]byte, 16), make([]byte, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := unhex("58e2fccefa7e3061367f1d57a4e7455a"); !bytes.Equal(h.Sum(nil), expected) {
+		t.Errorf("expected %x got %x", expected, h.Sum(nil))
+	}
+
+	key, nonce := seq(16), seq(12)
+	h, _ = NewGMAC(key, nonce)
+	h.Write(nistmsg[:20])
+	h.Write(nistmsg[20:])
+	c, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(c)
+	if expected := gcm.Seal(nil, nonce, nil, nistmsg); !bytes.Equal(h.Sum(nil), expected) {
+		t.Errorf("expected %x got %x", expected, h.Sum(nil))
+	}
+
+	if _, err := NewGMAC(key, nil); err == nil {
+		t.Error("expected error for missing nonce")
+	}
+}
+
+func TestGMACSingleUse(t *testing.T) {
+	key, nonce := seq(16), seq(12)
+	defer SetPolicy(nil)
+	for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+		SetPolicy(p)
+		h, err := NewGMAC(key, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(nistmsg[:20])
+		tag := h.Sum(nil)
+		if !bytes.Equal(h.Sum(nil), tag) {
+			t.Error("second Sum returned a different tag")
+		}
+		if _, err := h.Write(nistmsg[20:]); err == nil {
+			t.Error("Write after Sum accepted")
+		}
+		if !bytes.Equal(h.Sum(nil), tag) {
+			t.Error("rejected Write changed the tag")
+		}
+		h.Reset()
+		if _, err := h.Write(nistmsg[20:]); err == nil {
+			t.Error("Write after Reset accepted")
+		}
+		if !bytes.Equal(h.Sum(nil), tag) {
+			t.Error("Reset changed the tag")
+		}
+	}
+	SetPolicy(nil)
+
+	// NewMAC hands out the same single-use hash.
+	m, _ := NewMAC(AlgGMAC, key, nonce)
+	m.Write(nistmsg)
+	if !m.Verify(m.Sum(nil)) {
+		t.Error("valid tag rejected")
+	}
+	if _, err := m.Write(nistmsg); err == nil {
+		t.Error("NewMAC GMAC reusable after Sum")
+	}
+}
+
+func TestNewMAC(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	for _, a := range []Algorithm{AlgCMAC, AlgCMAC96, AlgOMAC2, AlgXCBC, AlgXCBC96, AlgPMAC, AlgGMAC} {
+		var nonce []byte
+		if a.NeedsNonce() {
+			nonce = make([]byte, 12)
+		}
+		m, err := NewMAC(a, key, nonce)
+		if err != nil {
+			t.Fatalf("%s: %v", a, err)
+		}
+		m.Write(nistmsg)
+		tag := m.Sum(nil)
+		if len(tag) != m.Size() {
+			t.Errorf("%s: tag of %d bytes, Size %d", a, len(tag), m.Size())
+		}
+		if !m.Verify(tag) {
+			t.Errorf("%s: valid tag rejected", a)
+		}
+		tag[0] ^= 1
+		if m.Verify(tag) {
+			t.Errorf("%s: invalid tag accepted", a)
+		}
+	}
+
+	m, _ := NewMAC(AlgCMAC, key, nil)
+	m.Write(nistmsg[:16])
+	if expected := unhex("070a16b46b4d4144f79bdd9dd04a287c"); !bytes.Equal(m.Sum(nil), expected) {
+		t.Errorf("expected %x got %x", expected, m.Sum(nil))
+	}
+
+	if _, err := NewMAC(AlgGMAC, key, nil); err == nil {
+		t.Error("expected error for missing nonce")
+	}
+	if _, err := NewMAC(AlgCMAC, key, make([]byte, 12)); err == nil {
+		t.Error("expected error for unexpected nonce")
+	}
+	if _, err := NewMAC("HMAC-SHA1", key, nil); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+
+	// Verify also works through the policy wrapper.
+	SetPolicy(&Policy{MaxMessageSize: 1 << 20})
+	defer SetPolicy(nil)
+	m, _ = NewMAC(AlgPMAC, key, nil)
+	if !m.Verify(m.Sum(nil)) {
+		t.Error("valid tag rejected under policy")
+	}
+}
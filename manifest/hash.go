@@ -0,0 +1,133 @@
+package manifest
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/joekir/cmac"
+)
+
+// Result is the outcome of hashing a single file. Err is set if the file
+// could not be read or the directory containing it could not be listed;
+// Entry.Path is always set.
+type Result struct {
+	Entry
+	Err error
+}
+
+// HashFS computes the AES-CMAC tag of every regular file in fsys, using up
+// to workers goroutines, or GOMAXPROCS if workers is not positive. Use
+// os.DirFS to hash a directory tree. Paths are slash-separated and
+// relative to the root of fsys; other files such as symlinks are skipped.
+//
+// Results are sent in no particular order on the returned channel, which
+// is closed once all files have been hashed or ctx is done. The channel is
+// unbuffered, so a slow consumer holds back the workers. The caller must
+// either drain the channel or cancel ctx.
+func HashFS(ctx context.Context, fsys fs.FS, key []byte, workers int) (<-chan Result, error) {
+	if _, err := cmac.New(key); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	paths := make(chan string)
+	results := make(chan Result)
+	send := func(r Result) bool {
+		select {
+		case results <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(paths)
+		fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if !send(Result{Entry: Entry{Path: path}, Err: err}) {
+					return ctx.Err()
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, _ := cmac.New(key)
+			for path := range paths {
+				r := Result{Entry: Entry{Path: path, Binary: true}}
+				r.Tag, r.Err = hashFile(fsys, path, h)
+				if !send(r) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
+
+func hashFile(fsys fs.FS, path string, h interface {
+	io.Writer
+	Reset()
+	Sum([]byte) []byte
+}) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h.Reset()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Build hashes fsys with HashFS and returns a manifest of the results
+// sorted by path. It stops at the first error.
+func Build(ctx context.Context, fsys fs.FS, key []byte, workers int) (*Manifest, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results, err := HashFS(ctx, fsys, key, workers)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{TagLength: 16}
+	for r := range results {
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		m.Entries = append(m.Entries, r.Entry)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m, nil
+}
@@ -0,0 +1,63 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/joekir/cmac"
+)
+
+func TestBuild(t *testing.T) {
+	key := make([]byte, 16)
+	fsys := fstest.MapFS{}
+	for i := 0; i < 50; i++ {
+		fsys[fmt.Sprintf("d%d/f%02d", i%3, i)] = &fstest.MapFile{Data: bytes.Repeat([]byte{byte(i)}, i*37)}
+	}
+
+	m, err := Build(context.Background(), fsys, key, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != len(fsys) {
+		t.Fatalf("expected %d entries, got %d", len(fsys), len(m.Entries))
+	}
+	for i, e := range m.Entries {
+		if i > 0 && m.Entries[i-1].Path >= e.Path {
+			t.Errorf("entries not sorted at %q", e.Path)
+		}
+		h, _ := cmac.New(key)
+		h.Write(fsys[e.Path].Data)
+		if !bytes.Equal(e.Tag, h.Sum(nil)) {
+			t.Errorf("%s: wrong tag %x", e.Path, e.Tag)
+		}
+	}
+}
+
+func TestHashFSCancel(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 100; i++ {
+		fsys[fmt.Sprintf("f%03d", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := HashFS(ctx, fsys, make([]byte, 16), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-results
+	cancel()
+	// The channel is closed without being drained further.
+	for range results {
+	}
+
+	if _, err := Build(ctx, fsys, make([]byte, 16), 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := HashFS(context.Background(), fsys, make([]byte, 5), 2); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
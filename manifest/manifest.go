@@ -0,0 +1,260 @@
+// Package manifest reads and writes checksum manifests of CMAC tags, in
+// the line formats of GNU coreutils' sha256sum and of BSD style tagged
+// output (sha256sum --tag, BSD sha256):
+//
+//	bb1d6929e95937287fa37d129b756746  path/to/file
+//	CMAC-AES (path/to/file) = bb1d6929e95937287fa37d129b756746
+//
+// Lines starting with # are comments. Comments of the form "# key-id: ID"
+// and "# tag-length: N" carry metadata: the identifier of the key the tags
+// were computed with, and the length in bytes tags were truncated to.
+package manifest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultAlgorithm is the algorithm name written in BSD style lines.
+const DefaultAlgorithm = "CMAC-AES"
+
+// Style selects the line format used when writing a manifest.
+type Style int
+
+// Line formats.
+const (
+	Coreutils Style = iota
+	BSD
+)
+
+// Entry is the tag of a single file.
+type Entry struct {
+	Path string
+	Tag  []byte
+	// Binary records the coreutils '*' binary-mode marker.
+	Binary bool
+}
+
+// Manifest is a parsed manifest.
+type Manifest struct {
+	Style Style
+	// Algorithm is the algorithm name of BSD style lines.
+	Algorithm string
+	KeyID     string
+	// TagLength is the length of every tag in bytes, or 0 if not
+	// recorded.
+	TagLength int
+	// Comments holds all other comment lines, without the leading #.
+	Comments []string
+	Entries  []Entry
+}
+
+// SyntaxError reports a malformed manifest line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("manifest: line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads a manifest. Coreutils and BSD style lines may be mixed; the
+// Style of the result is that of the first entry.
+func Parse(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	s := bufio.NewScanner(r)
+	first := true
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "#") {
+			if err := m.parseComment(strings.TrimPrefix(text, "#")); err != nil {
+				return nil, &SyntaxError{line, err.Error()}
+			}
+			continue
+		}
+
+		e, style, alg, err := parseEntry(text)
+		if err != nil {
+			return nil, &SyntaxError{line, err.Error()}
+		}
+		if m.TagLength != 0 && len(e.Tag) != m.TagLength {
+			return nil, &SyntaxError{line, "tag length does not match tag-length header"}
+		}
+		if first {
+			m.Style, first = style, false
+		}
+		if alg != "" {
+			m.Algorithm = alg
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Manifest) parseComment(c string) error {
+	t := strings.TrimSpace(c)
+	switch {
+	case strings.HasPrefix(t, "key-id:"):
+		m.KeyID = strings.TrimSpace(strings.TrimPrefix(t, "key-id:"))
+	case strings.HasPrefix(t, "tag-length:"):
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(t, "tag-length:")))
+		if err != nil || n <= 0 {
+			return errors.New("invalid tag-length")
+		}
+		m.TagLength = n
+	default:
+		m.Comments = append(m.Comments, c)
+	}
+	return nil
+}
+
+func parseEntry(text string) (e Entry, style Style, alg string, err error) {
+	// coreutils prefixes lines whose file name needed escaping with a
+	// backslash.
+	escaped := strings.HasPrefix(text, "\\")
+	if escaped {
+		text = text[1:]
+	}
+
+	if i := strings.Index(text, " ("); i > 0 && !strings.Contains(text[:i], " ") {
+		j := strings.LastIndex(text, ") = ")
+		if j < i {
+			return e, style, alg, errors.New("malformed BSD style line")
+		}
+		alg, e.Path = text[:i], text[i+2:j]
+		e.Tag, err = hex.DecodeString(text[j+4:])
+		style = BSD
+	} else {
+		i := strings.Index(text, " ")
+		if i <= 0 || i+1 >= len(text) || (text[i+1] != ' ' && text[i+1] != '*') {
+			return e, style, alg, errors.New("malformed line")
+		}
+		e.Tag, err = hex.DecodeString(text[:i])
+		e.Binary = text[i+1] == '*'
+		e.Path = text[i+2:]
+		style = Coreutils
+	}
+	if err != nil || len(e.Tag) == 0 {
+		return e, style, alg, errors.New("invalid tag")
+	}
+	if e.Path == "" {
+		return e, style, alg, errors.New("missing path")
+	}
+	if escaped {
+		if e.Path, err = unescape(e.Path); err != nil {
+			return e, style, alg, err
+		}
+	}
+	return e, style, alg, nil
+}
+
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", errors.New("invalid escape in path")
+		}
+		switch s[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", errors.New("invalid escape in path")
+		}
+	}
+	return b.String(), nil
+}
+
+var escaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// WriteTo writes the manifest: metadata first, then comments, then one
+// line per entry in m.Style.
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countWriter{w: bw}
+
+	if m.KeyID != "" {
+		fmt.Fprintf(cw, "# key-id: %s\n", m.KeyID)
+	}
+	if m.TagLength != 0 {
+		fmt.Fprintf(cw, "# tag-length: %d\n", m.TagLength)
+	}
+	for _, c := range m.Comments {
+		fmt.Fprintf(cw, "#%s\n", c)
+	}
+
+	alg := m.Algorithm
+	if alg == "" {
+		alg = DefaultAlgorithm
+	}
+	for _, e := range m.Entries {
+		if m.TagLength != 0 && len(e.Tag) != m.TagLength {
+			return cw.n, fmt.Errorf("manifest: tag for %q does not match tag length", e.Path)
+		}
+
+		path, prefix := e.Path, ""
+		if strings.ContainsAny(path, "\\\n\r") {
+			path, prefix = escaper.Replace(path), "\\"
+		}
+		if m.Style == BSD {
+			fmt.Fprintf(cw, "%s%s (%s) = %x\n", prefix, alg, path, e.Tag)
+		} else {
+			mode := byte(' ')
+			if e.Binary {
+				mode = '*'
+			}
+			fmt.Fprintf(cw, "%s%x %c%s\n", prefix, e.Tag, mode, path)
+		}
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+// Lookup returns the entry for path.
+func (m *Manifest) Lookup(path string) (Entry, bool) {
+	for _, e := range m.Entries {
+		if e.Path == path {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,91 @@
+package manifest
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `# key-id: prod-2025
+# tag-length: 16
+# generated by the release job
+bb1d6929e95937287fa37d129b756746  a.txt
+070a16b46b4d4144f79bdd9dd04a287c *dir/b.bin
+CMAC-AES (c d.txt) = dfa66747de9ae63030ca32611497c827
+\51f0bebf7e3b9d92fc49741779363cfe  new\nline\\x
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.KeyID != "prod-2025" || m.TagLength != 16 || m.Style != Coreutils || m.Algorithm != "CMAC-AES" {
+		t.Errorf("bad metadata: %+v", m)
+	}
+	if !reflect.DeepEqual(m.Comments, []string{" generated by the release job"}) {
+		t.Errorf("comments: %q", m.Comments)
+	}
+
+	paths := []string{"a.txt", "dir/b.bin", "c d.txt", "new\nline\\x"}
+	if len(m.Entries) != len(paths) {
+		t.Fatalf("expected %d entries, got %d", len(paths), len(m.Entries))
+	}
+	for i, p := range paths {
+		if m.Entries[i].Path != p {
+			t.Errorf("entry %d: expected path %q got %q", i, p, m.Entries[i].Path)
+		}
+	}
+	if !m.Entries[1].Binary || m.Entries[0].Binary {
+		t.Error("binary markers not parsed")
+	}
+	if e, ok := m.Lookup("c d.txt"); !ok || len(e.Tag) != 16 {
+		t.Errorf("Lookup: %v %v", e, ok)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	m, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, style := range []Style{Coreutils, BSD} {
+		m.Style = style
+		var buf bytes.Buffer
+		if _, err := m.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("style %d: %v", style, err)
+		}
+		if style == BSD {
+			// BSD lines carry no binary marker.
+			m2.Entries[1].Binary = true
+		} else {
+			// Coreutils lines carry no algorithm name.
+			m2.Algorithm = m.Algorithm
+		}
+		if !reflect.DeepEqual(m, m2) {
+			t.Errorf("style %d: round trip mismatch:\n%+v\n%+v", style, m, m2)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"zz  a.txt\n",
+		"bb1d6929e95937287fa37d129b756746\n",
+		"bb1d6929e95937287fa37d129b756746 a.txt\n",
+		"CMAC-AES (a.txt) bb1d\n",
+		"# tag-length: 8\nbb1d6929e95937287fa37d129b756746  a.txt\n",
+		"# tag-length: x\n",
+		"\\bb1d6929e95937287fa37d129b756746  a\\q\n",
+	} {
+		if _, err := Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("Parse(%q): expected error", in)
+		}
+	}
+}
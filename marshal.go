@@ -0,0 +1,194 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// The serialized state is
+//
+//	magic || size || check || x || buf || cursor
+//
+// with check the first checkSize bytes of a CMAC of a fixed block, which
+// ties the state to its key without revealing the subkeys.
+const (
+	marshalMagic = "cmac\x01"
+	checkSize    = 4
+)
+
+var stateCheckBlock = [16]byte{'c', 'm', 'a', 'c', ' ', 's', 't', 'a', 't', 'e', ' ', 'c', 'h', 'e', 'c', 'k'}
+
+// check returns the key check value of s. The working state is pooled so
+// that AppendBinary does not allocate.
+func (s *State) check() [checkSize]byte {
+	t := scratchPool.Get().(*scratch)
+	defer t.release()
+	t.State = State{c: s.c, size: s.size, k1: s.k1, k2: s.k2}
+	t.Write(stateCheckBlock[:s.size])
+	var v [checkSize]byte
+	copy(v[:], t.Sum(t.tag[:0]))
+	return v
+}
+
+func marshaledSize(size int) int {
+	return len(marshalMagic) + 1 + checkSize + 2*size + 1
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, so that a partially
+// written MAC can be saved and resumed later with UnmarshalBinary.
+//
+// The encoding holds neither the key nor the subkeys, but it must be
+// protected like key material all the same. The chaining value it holds is
+// a cipher output under the key, and whoever can substitute a state that
+// is later resumed chooses what the cipher is applied to, which is enough
+// to forge tags. The encoding is neither encrypted nor authenticated; store
+// it only where just the key holder can read and write it, or use
+// SealState, which encrypts and authenticates it.
+func (s *State) MarshalBinary() ([]byte, error) {
+	if s.c == nil {
+		return nil, errors.New("cmac: State not initialized")
+	}
+	return s.AppendBinary(make([]byte, 0, marshaledSize(s.size)))
+}
+
+// AppendBinary implements encoding.BinaryAppender. It appends the encoding
+// of MarshalBinary to b and returns the extended slice, without allocating
+// when b has enough capacity.
+func (s *State) AppendBinary(b []byte) ([]byte, error) {
+	if s.c == nil {
+		return nil, errors.New("cmac: State not initialized")
+	}
+	b = append(b, marshalMagic...)
+	b = append(b, byte(s.size))
+	check := s.check()
+	b = append(b, check[:]...)
+	b = append(b, s.x[:s.size]...)
+	b = append(b, s.buf[:s.size]...)
+	return append(b, byte(s.cursor)), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It restores a
+// state saved by MarshalBinary into s, which must already be initialized
+// with a cipher under the same key. An error is returned, and s is left
+// unchanged, if the state was saved under a different key or block size.
+func (s *State) UnmarshalBinary(b []byte) error {
+	if s.c == nil {
+		return errors.New("cmac: State not initialized")
+	}
+	if len(b) < len(marshalMagic) || string(b[:len(marshalMagic)]) != marshalMagic {
+		return errors.New("cmac: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize(s.size) || int(b[len(marshalMagic)]) != s.size {
+		return errors.New("cmac: invalid hash state size")
+	}
+	b = b[len(marshalMagic)+1:]
+	check := s.check()
+	if subtle.ConstantTimeCompare(b[:checkSize], check[:]) != 1 {
+		return errors.New("cmac: hash state from a different key")
+	}
+	b = b[checkSize:]
+	cursor := int(b[2*s.size])
+	if cursor > s.size {
+		return errors.New("cmac: invalid hash state")
+	}
+	copy(s.x[:], b[:s.size])
+	copy(s.buf[:], b[s.size:2*s.size])
+	s.cursor = cursor
+	return nil
+}
+
+// The limited hash of Policy.MaxMessageSize appends its byte count to the
+// state of the underlying hash, so that the limit holds across a resume.
+
+func (l *limited) MarshalBinary() ([]byte, error) {
+	return l.AppendBinary(nil)
+}
+
+func (l *limited) AppendBinary(b []byte) ([]byte, error) {
+	m, ok := l.Hash.(interface{ AppendBinary([]byte) ([]byte, error) })
+	if !ok {
+		return nil, errors.New("cmac: hash state cannot be marshaled")
+	}
+	b, err := m.AppendBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(l.n))
+	return append(b, n[:]...), nil
+}
+
+func (l *limited) UnmarshalBinary(b []byte) error {
+	u, ok := l.Hash.(interface{ UnmarshalBinary([]byte) error })
+	if !ok {
+		return errors.New("cmac: hash state cannot be unmarshaled")
+	}
+	if len(b) < 8 {
+		return errors.New("cmac: invalid hash state size")
+	}
+	n := int64(binary.BigEndian.Uint64(b[len(b)-8:]))
+	if n < 0 || n > l.max {
+		return errors.New("cmac: invalid hash state")
+	}
+	if err := u.UnmarshalBinary(b[:len(b)-8]); err != nil {
+		return err
+	}
+	l.n = n
+	return nil
+}
+
+// sealedStateData is the additional data of sealed states, which keeps
+// them apart from other messages sealed with the same AEAD.
+var sealedStateData = []byte("cmac sealed state")
+
+// SealState appends to dst the state of h, as MarshalBinary encodes it,
+// encrypted and authenticated with aead under a random nonce, and returns
+// the extended slice. aead should be keyed independently of the MAC, for
+// example with AES-GCM under a storage key. h must come from this package.
+func SealState(dst []byte, aead cipher.AEAD, h hash.Hash) ([]byte, error) {
+	m, ok := h.(interface{ AppendBinary([]byte) ([]byte, error) })
+	if !ok {
+		return nil, errors.New("cmac: hash state cannot be marshaled")
+	}
+	var buf [64]byte
+	state, err := m.AppendBinary(buf[:0])
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(state)
+
+	ns := aead.NonceSize()
+	ret, out := sliceForAppend(dst, ns+len(state)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, out[:ns]); err != nil {
+		return nil, err
+	}
+	aead.Seal(out[ns:ns], out[:ns], state, sealedStateData)
+	return ret, nil
+}
+
+// OpenState restores into h a state sealed by SealState with aead. h must
+// already be set up under the same MAC key, as for UnmarshalBinary. An
+// error is returned, and h is left unchanged, if the sealed state was
+// altered or sealed with a different AEAD.
+func OpenState(h hash.Hash, aead cipher.AEAD, sealed []byte) error {
+	u, ok := h.(interface{ UnmarshalBinary([]byte) error })
+	if !ok {
+		return errors.New("cmac: hash state cannot be unmarshaled")
+	}
+	ns := aead.NonceSize()
+	if len(sealed) < ns+aead.Overhead() {
+		return errors.New("cmac: invalid sealed hash state")
+	}
+	var buf [64]byte
+	state, err := aead.Open(buf[:0], sealed[:ns], sealed[ns:], sealedStateData)
+	if err != nil {
+		return errors.New("cmac: invalid sealed hash state")
+	}
+	defer wipe(state)
+	return u.UnmarshalBinary(state)
+}
@@ -0,0 +1,176 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	for _, tv := range nistvectors {
+		for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+			c, err := tv.cipher(tv.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for split := 0; split <= len(nistmsg); split++ {
+				h, err := newWithCipher(c, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h.Write(nistmsg[:split])
+				state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				h2, _ := newWithCipher(c, p)
+				if err := h2.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+					t.Fatal(err)
+				}
+				h.Write(nistmsg[split:])
+				h2.Write(nistmsg[split:])
+				if want, got := h.Sum(nil), h2.Sum(nil); !bytes.Equal(got, want) {
+					t.Fatalf("split %d: got %x, want %x", split, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	var s, other, tdea State
+	s.Init(mustCipher(t, nistvectors[0]))
+	other.Init(mustCipher(t, nistvectors[1]))
+	tdea.Init(mustCipher(t, nistvectors[3]))
+	s.Write(nistmsg[:20])
+	state, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.UnmarshalBinary(state); err == nil {
+		t.Error("accepted the state of another key")
+	}
+	if err := tdea.UnmarshalBinary(state); err == nil {
+		t.Error("accepted the state of another block size")
+	}
+	if err := s.UnmarshalBinary(state[1:]); err == nil {
+		t.Error("accepted a bad identifier")
+	}
+	if err := s.UnmarshalBinary(state[:len(state)-1]); err == nil {
+		t.Error("accepted a short state")
+	}
+	bad := append([]byte(nil), state...)
+	bad[len(bad)-1] = 17
+	if err := s.UnmarshalBinary(bad); err == nil {
+		t.Error("accepted a bad cursor")
+	}
+
+	var empty State
+	if _, err := empty.MarshalBinary(); err == nil {
+		t.Error("marshaled an uninitialized State")
+	}
+	if err := empty.UnmarshalBinary(state); err == nil {
+		t.Error("unmarshaled into an uninitialized State")
+	}
+
+	// The message size limit holds across a resume.
+	h, _ := newWithCipher(mustCipher(t, nistvectors[0]), &Policy{MaxMessageSize: 32})
+	h.Write(nistmsg[:30])
+	state, err = h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Reset()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Write(nistmsg[:3]); err == nil {
+		t.Error("limit not restored")
+	}
+}
+
+func mustCipher(t *testing.T, tv nisttest) cipher.Block {
+	t.Helper()
+	c, err := tv.cipher(tv.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAppendBinary(t *testing.T) {
+	var s State
+	s.Init(mustCipher(t, nistvectors[0]))
+	s.Write(nistmsg[:20])
+	want, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte("prefix")
+	got, err := s.AppendBinary(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], want) {
+		t.Errorf("got %x, want %x after the prefix", got, want)
+	}
+
+	buf := make([]byte, 0, 64)
+	if n := testing.AllocsPerRun(100, func() {
+		buf, _ = s.AppendBinary(buf[:0])
+	}); n > 0 {
+		t.Errorf("AppendBinary allocates %v times", n)
+	}
+}
+
+func TestSealState(t *testing.T) {
+	tv := nistvectors[0]
+	block, _ := aes.NewCipher(make([]byte, 32))
+	aead, _ := cipher.NewGCM(block)
+	for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+		h, _ := newAES(tv.key, p)
+		h.Write(nistmsg[:20])
+		sealed, err := SealState([]byte("prefix"), aead, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(sealed, []byte("prefix")) {
+			t.Fatal("SealState did not append to dst")
+		}
+		sealed = sealed[len("prefix"):]
+		plain, _ := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if bytes.Contains(sealed, plain[len(marshalMagic)+1+checkSize:]) {
+			t.Error("sealed state contains the chaining value")
+		}
+
+		h2, _ := newAES(tv.key, p)
+		if err := OpenState(h2, aead, sealed); err != nil {
+			t.Fatal(err)
+		}
+		h2.Write(nistmsg[20:])
+		if mac := h2.Sum(nil); !bytes.Equal(mac, tv.cases[3].mac) {
+			t.Errorf("expected: %x got %x", tv.cases[3].mac, mac)
+		}
+
+		for i := range sealed {
+			bad := append([]byte(nil), sealed...)
+			bad[i] ^= 1
+			h3, _ := newAES(tv.key, p)
+			h3.Write(nistmsg[:3])
+			want := h3.Sum(nil)
+			if err := OpenState(h3, aead, bad); err == nil {
+				t.Fatalf("altered byte %d accepted", i)
+			}
+			if !bytes.Equal(h3.Sum(nil), want) {
+				t.Fatal("failed OpenState changed the hash")
+			}
+		}
+		if err := OpenState(h2, aead, sealed[:aead.NonceSize()]); err == nil {
+			t.Error("truncated sealed state accepted")
+		}
+	}
+}
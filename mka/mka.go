@@ -0,0 +1,118 @@
+// Package mka implements the AES-CMAC based key hierarchy of the MACsec Key
+// Agreement protocol (IEEE 802.1X-2010 sections 6.2 and 9.8): the KDF, the
+// derivation of the CAK and CKN from an EAP MSK, of the KEK and ICK from
+// the CAK, and of SAKs by the key server, and the ICV of MKPDUs.
+package mka
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/joekir/cmac"
+	"github.com/joekir/cmac/kbkdf"
+)
+
+// ICVSize is the length of the default MKPDU ICV in bytes.
+const ICVSize = 16
+
+// MI is a member identifier.
+type MI [12]byte
+
+// KDF derives n bytes from key, which is 16 or 32 bytes long, as
+//
+//	AES-CMAC(key, [i]8 || label || 0x00 || context || [n*8]16)
+//
+// for i = 1, 2, ...
+func KDF(key []byte, label string, context []byte, n int) ([]byte, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, errors.New("mka: keys must be 16 or 32 bytes")
+	}
+	if n <= 0 || n*8 > 0xffff {
+		return nil, errors.New("mka: invalid derived key length")
+	}
+	fixed := make([]byte, 0, len(label)+1+len(context)+2)
+	fixed = append(append(append(fixed, label...), 0), context...)
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(n*8))
+	fixed = append(fixed, l[:]...)
+	return kbkdf.Counter(key, fixed, n, &kbkdf.Options{CounterBits: 8})
+}
+
+// macs returns the two MAC addresses numerically lower first.
+func macs(mac1, mac2 [6]byte) []byte {
+	if bytes.Compare(mac1[:], mac2[:]) > 0 {
+		mac1, mac2 = mac2, mac1
+	}
+	return append(mac1[:], mac2[:]...)
+}
+
+// CAK derives a CAK of n bytes (16 or 32) from the MSK of an EAP exchange
+// between the stations with MAC addresses mac1 and mac2, in either order.
+// The KDF is keyed with the first n bytes of the MSK.
+func CAK(msk []byte, mac1, mac2 [6]byte, n int) ([]byte, error) {
+	if len(msk) < n {
+		return nil, errors.New("mka: MSK too short")
+	}
+	return KDF(msk[:n], "IEEE8021 EAP CAK", macs(mac1, mac2), n)
+}
+
+// CKN derives a CKN of n bytes from the MSK and EAP Session-Id of an EAP
+// exchange. keyLen is the length of the CAK, which selects how much of the
+// MSK keys the KDF.
+func CKN(msk, sessionID []byte, mac1, mac2 [6]byte, keyLen, n int) ([]byte, error) {
+	if len(msk) < keyLen {
+		return nil, errors.New("mka: MSK too short")
+	}
+	return KDF(msk[:keyLen], "IEEE8021 EAP CKN", append(append([]byte(nil), sessionID...), macs(mac1, mac2)...), n)
+}
+
+// keyID is the first 16 bytes of the CKN, zero padded.
+func keyID(ckn []byte) []byte {
+	id := make([]byte, 16)
+	copy(id, ckn)
+	return id
+}
+
+// KEK derives the key encrypting key from the CAK and CKN. It has the
+// length of the CAK.
+func KEK(cak, ckn []byte) ([]byte, error) {
+	return KDF(cak, "IEEE8021 KEK", keyID(ckn), len(cak))
+}
+
+// ICK derives the ICV key from the CAK and CKN. It has the length of the
+// CAK.
+func ICK(cak, ckn []byte) ([]byte, error) {
+	return KDF(cak, "IEEE8021 ICK", keyID(ckn), len(cak))
+}
+
+// SAK derives a secure association key of n bytes (16 or 32) from the CAK,
+// a fresh key server nonce of n bytes, the MIs of the live participants
+// starting with the key server's, and the key number kn.
+func SAK(cak, nonce []byte, mis []MI, kn uint32, n int) ([]byte, error) {
+	if len(nonce) != n {
+		return nil, errors.New("mka: key server nonce must have the SAK length")
+	}
+	context := make([]byte, 0, len(nonce)+len(mis)*len(MI{})+4)
+	context = append(context, nonce...)
+	for _, mi := range mis {
+		context = append(context, mi[:]...)
+	}
+	var k [4]byte
+	binary.BigEndian.PutUint32(k[:], kn)
+	return KDF(cak, "IEEE8021 SAK", append(context, k[:]...), n)
+}
+
+// ICV computes the ICV of an MKPDU. frame holds the frame from the
+// destination address up to but excluding the ICV.
+func ICV(ick, frame []byte) ([ICVSize]byte, error) {
+	return cmac.Sum(ick, frame)
+}
+
+// VerifyICV reports whether icv is the ICV of frame, comparing in constant
+// time.
+func VerifyICV(ick, frame, icv []byte) bool {
+	expected, err := ICV(ick, frame)
+	return err == nil && subtle.ConstantTimeCompare(expected[:], icv) == 1
+}
@@ -0,0 +1,107 @@
+package mka
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	msk  = unhex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f" + "202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f")
+	mac1 = [6]byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	mac2 = [6]byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+)
+
+func TestKDF(t *testing.T) {
+	key := msk[:16]
+	out, err := KDF(key, "IEEE8021 ICK", []byte("ctx"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []byte
+	for i := byte(1); i <= 2; i++ {
+		h, _ := cmac.New(key)
+		h.Write([]byte{i})
+		h.Write([]byte("IEEE8021 ICK\x00ctx"))
+		h.Write([]byte{0x01, 0x00})
+		expected = h.Sum(expected)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %x got %x", expected, out)
+	}
+
+	if _, err := KDF(msk[:24], "x", nil, 16); err == nil {
+		t.Error("expected error for 24-byte key")
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	for _, n := range []int{16, 32} {
+		cak, err := CAK(msk, mac1, mac2, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The lower MAC address comes first whatever the argument order.
+		if c, _ := CAK(msk, mac2, mac1, n); !bytes.Equal(cak, c) {
+			t.Errorf("%d: CAK depends on argument order", n)
+		}
+		ctx := append(mac2[:], mac1[:]...)
+		if expected, _ := KDF(msk[:n], "IEEE8021 EAP CAK", ctx, n); !bytes.Equal(cak, expected) {
+			t.Errorf("%d: CAK mismatch", n)
+		}
+
+		ckn, _ := CKN(msk, []byte("session"), mac1, mac2, n, 32)
+		if expected, _ := KDF(msk[:n], "IEEE8021 EAP CKN", append([]byte("session"), ctx...), 32); !bytes.Equal(ckn, expected) {
+			t.Errorf("%d: CKN mismatch", n)
+		}
+
+		kek, _ := KEK(cak, ckn)
+		ick, _ := ICK(cak, ckn)
+		if expected, _ := KDF(cak, "IEEE8021 KEK", ckn[:16], n); !bytes.Equal(kek, expected) {
+			t.Errorf("%d: KEK mismatch", n)
+		}
+		if expected, _ := KDF(cak, "IEEE8021 ICK", ckn[:16], n); !bytes.Equal(ick, expected) {
+			t.Errorf("%d: ICK mismatch", n)
+		}
+
+		// A short CKN is zero padded to form the key identifier.
+		short, _ := KEK(cak, []byte{0xab})
+		if expected, _ := KDF(cak, "IEEE8021 KEK", append([]byte{0xab}, make([]byte, 15)...), n); !bytes.Equal(short, expected) {
+			t.Errorf("%d: KEK with short CKN mismatch", n)
+		}
+
+		nonce := bytes.Repeat([]byte{0x5a}, n)
+		mis := []MI{{1}, {2}}
+		sak, _ := SAK(cak, nonce, mis, 7, n)
+		sctx := append(append(append(append([]byte(nil), nonce...), mis[0][:]...), mis[1][:]...), 0, 0, 0, 7)
+		if expected, _ := KDF(cak, "IEEE8021 SAK", sctx, n); !bytes.Equal(sak, expected) {
+			t.Errorf("%d: SAK mismatch", n)
+		}
+	}
+}
+
+func TestICV(t *testing.T) {
+	ick := msk[:16]
+	frame := unhex("0180c2000003" + "020000000001" + "888e" + "0305" + "0000")
+	icv, err := ICV(ick, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyICV(ick, frame, icv[:]) {
+		t.Error("ICV does not verify")
+	}
+	frame[len(frame)-1] ^= 1
+	if VerifyICV(ick, frame, icv[:]) {
+		t.Error("modified frame verified")
+	}
+}
@@ -0,0 +1,50 @@
+// Package mobile exposes AES-CMAC and AES-SIV through an API that
+// gomobile can bind for Android and iOS:
+//
+//	gomobile bind -target=android github.com/joekir/cmac/mobile
+//
+// Only byte slices, booleans and errors cross the boundary.
+package mobile
+
+import (
+	"crypto/subtle"
+
+	"github.com/joekir/cmac"
+)
+
+// Sum returns the AES-CMAC of msg under key.
+func Sum(key, msg []byte) ([]byte, error) {
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Verify reports whether tag is the AES-CMAC of msg under key. Invalid
+// keys are reported as a mismatch.
+func Verify(key, msg, tag []byte) bool {
+	t, err := Sum(key, msg)
+	return err == nil && subtle.ConstantTimeCompare(t, tag) == 1
+}
+
+// SIVSeal encrypts and authenticates plaintext and ad with deterministic
+// AES-SIV. The key must be 32, 48 or 64 bytes long.
+func SIVSeal(key, plaintext, ad []byte) ([]byte, error) {
+	a, err := cmac.NewSIV(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	return a.Seal(nil, nil, plaintext, ad), nil
+}
+
+// SIVOpen reverses SIVSeal, returning an error if ciphertext or ad were
+// modified.
+func SIVOpen(key, ciphertext, ad []byte) ([]byte, error) {
+	a, err := cmac.NewSIV(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	return a.Open(nil, nil, ciphertext, ad)
+}
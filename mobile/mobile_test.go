@@ -0,0 +1,62 @@
+package mobile
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestSum(t *testing.T) {
+	// RFC 4493 example 2.
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172a")
+	tag := unhex("070a16b46b4d4144f79bdd9dd04a287c")
+
+	got, err := Sum(key, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, tag) {
+		t.Errorf("expected: %x got %x\n", tag, got)
+	}
+	if !Verify(key, msg, tag) {
+		t.Error("Verify: valid tag rejected")
+	}
+	if Verify(key, msg, tag[:15]) {
+		t.Error("Verify: truncated tag accepted")
+	}
+	if Verify(key[:15], msg, tag) {
+		t.Error("Verify: invalid key accepted")
+	}
+}
+
+func TestSIV(t *testing.T) {
+	// RFC 5297 appendix A.1.
+	key := unhex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad := unhex("101112131415161718191a1b1c1d1e1f2021222324252627")
+	pt := unhex("112233445566778899aabbccddee")
+	expected := unhex("85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	ct, err := SIVSeal(key, pt, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ct, expected) {
+		t.Errorf("expected: %x got %x\n", expected, ct)
+	}
+	out, err := SIVOpen(key, ct, ad)
+	if err != nil || !bytes.Equal(out, pt) {
+		t.Errorf("SIVOpen: got %x, %v", out, err)
+	}
+	if _, err := SIVOpen(key, ct, nil); err == nil {
+		t.Error("SIVOpen: wrong ad accepted")
+	}
+}
@@ -0,0 +1,94 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+)
+
+// multiChunk is the amount of data fed to each key in turn. It keeps the
+// chunk in the CPU cache while all keys process it.
+const multiChunk = 4 << 10
+
+// MultiMAC computes the AES-CMAC of one message under several keys in a
+// single pass over the data, for key rotation windows where both the old
+// and the new key are valid, or for tagging data for several recipients.
+type MultiMAC struct {
+	hs []hash.Hash
+}
+
+// NewMultiMAC returns a MultiMAC for the given keys, in order, subject to
+// the package Policy set with SetPolicy. At least one key is required.
+func NewMultiMAC(keys ...[]byte) (*MultiMAC, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("cmac: no keys")
+	}
+	m := &MultiMAC{hs: make([]hash.Hash, len(keys))}
+	for i, k := range keys {
+		h, err := New(k)
+		if err != nil {
+			return nil, err
+		}
+		m.hs[i] = h
+	}
+	return m, nil
+}
+
+// Len returns the number of keys.
+func (m *MultiMAC) Len() int { return len(m.hs) }
+
+// Write adds more data to the running MAC of every key. It only returns
+// an error if the message exceeds Policy.MaxMessageSize.
+func (m *MultiMAC) Write(b []byte) (int, error) {
+	total := len(b)
+	for len(b) > 0 {
+		n := len(b)
+		if n > multiChunk {
+			n = multiChunk
+		}
+		for _, h := range m.hs {
+			if _, err := h.Write(b[:n]); err != nil {
+				return total - len(b), err
+			}
+		}
+		b = b[n:]
+	}
+	return total, nil
+}
+
+// ReadFrom implements io.ReaderFrom, as for the hash returned by New.
+func (m *MultiMAC) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(m, r)
+}
+
+// Sums returns the MAC of the data written so far under each key, in the
+// order of the keys. It does not change the running state.
+func (m *MultiMAC) Sums() [][16]byte {
+	sums := make([][16]byte, len(m.hs))
+	for i, h := range m.hs {
+		h.Sum(sums[i][:0])
+	}
+	return sums
+}
+
+// Verify returns the index of the first key under which tag is the MAC of
+// the data written so far, or -1 if there is none. Every key is checked,
+// in constant time, whatever the result. Only full-length tags are
+// accepted.
+func (m *MultiMAC) Verify(tag []byte) int {
+	match := -1
+	var t [16]byte
+	for i := len(m.hs) - 1; i >= 0; i-- {
+		ok := subtle.ConstantTimeCompare(m.hs[i].Sum(t[:0]), tag)
+		match = subtle.ConstantTimeSelect(ok, i, match)
+	}
+	return match
+}
+
+// Reset resets the running MAC of every key.
+func (m *MultiMAC) Reset() {
+	for _, h := range m.hs {
+		h.Reset()
+	}
+}
@@ -0,0 +1,61 @@
+package cmac
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMultiMAC(t *testing.T) {
+	keys := [][]byte{nistvectors[0].key, nistvectors[1].key, nistvectors[2].key}
+	m, err := NewMultiMAC(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 3 {
+		t.Errorf("Len = %d", m.Len())
+	}
+	for _, i := range []int{3, 2} {
+		m.Reset()
+		if _, err := io.Copy(m, bytes.NewReader(nistmsg[:len(nistvectors[0].cases[i].msg)])); err != nil {
+			t.Fatal(err)
+		}
+		for k, sum := range m.Sums() {
+			if want := nistvectors[k].cases[i].mac; !bytes.Equal(sum[:], want) {
+				t.Errorf("key %d: got %x, want %x", k, sum, want)
+			}
+			if got := m.Verify(nistvectors[k].cases[i].mac); got != k {
+				t.Errorf("Verify matched key %d, want %d", got, k)
+			}
+		}
+		if got := m.Verify(make([]byte, 16)); got != -1 {
+			t.Errorf("Verify matched key %d for a bad tag", got)
+		}
+	}
+
+	// Data longer than a chunk gives the same tags as separate hashes.
+	data := make([]byte, 3*multiChunk+5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	m.Reset()
+	m.Write(data)
+	for k, sum := range m.Sums() {
+		if want, _ := Sum(keys[k], data); sum != want {
+			t.Errorf("key %d: got %x, want %x", k, sum, want)
+		}
+	}
+
+	// A duplicated key matches at its first position.
+	m, _ = NewMultiMAC(keys[0], keys[0])
+	if got := m.Verify(nistvectors[0].cases[0].mac); got != 0 {
+		t.Errorf("Verify matched key %d, want 0", got)
+	}
+
+	if _, err := NewMultiMAC(); err == nil {
+		t.Error("accepted no keys")
+	}
+	if _, err := NewMultiMAC(keys[0], nil); err == nil {
+		t.Error("accepted an empty key")
+	}
+}
@@ -0,0 +1,68 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// halve sets d to x multiplied by u⁻¹ in GF(2^n), where n is len(x)*8
+// and rb is the field's reduction constant; it is the inverse of dbl. d and
+// x may be the same slice.
+func halve(d, x []byte, rb byte) {
+	lsb := int(x[len(x)-1] & 1)
+	for i := len(x) - 1; i > 0; i-- {
+		d[i] = x[i]>>1 | x[i-1]<<7
+	}
+	d[0] = x[0]>>1 | byte(subtle.ConstantTimeSelect(lsb, 0x80, 0))
+	d[len(x)-1] ^= byte(subtle.ConstantTimeSelect(lsb, int(rb>>1), 0))
+}
+
+// NewOMAC2 returns a hash.Hash computing OMAC2 with AES, subject to the
+// package Policy set with SetPolicy.
+//
+// OMAC2 is the sibling of OMAC1, which is CMAC: it uses L·u⁻¹ in place of
+// L·u² for messages whose last block is partial, so tags of messages
+// made of full blocks are the same as CMAC's.
+func NewOMAC2(key []byte) (hash.Hash, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newOMAC2(c, p)
+}
+
+// NewOMAC2WithCipher returns a hash.Hash computing OMAC2 using the given
+// cipher.Block, which should have a block length of 8 or 16 bytes.
+func NewOMAC2WithCipher(c cipher.Block) (hash.Hash, error) {
+	return newOMAC2(c, currentPolicy())
+}
+
+func newOMAC2(c cipher.Block, p *Policy) (hash.Hash, error) {
+	m := &cmac{}
+	if err := m.Init(c); err != nil {
+		return nil, err
+	}
+	if err := p.checkBlockSize(m.size); err != nil {
+		return nil, err
+	}
+
+	// Recover L from K1 = L·u and replace K2 with L·u⁻¹.
+	rb := byte(_Rb128)
+	if m.size == 8 {
+		rb = _Rb64
+	}
+	k2 := m.k2[:m.size]
+	halve(k2, m.k1[:m.size], rb)
+	halve(k2, k2, rb)
+	return p.wrap(m), nil
+}
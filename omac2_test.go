@@ -0,0 +1,73 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/des"
+	"testing"
+)
+
+func TestHalve(t *testing.T) {
+	for i, tv := range nistvectors {
+		rb := byte(_Rb128)
+		if len(tv.c0) == 8 {
+			rb = _Rb64
+		}
+		out := make([]byte, len(tv.k1))
+		halve(out, tv.k1, rb)
+		if !bytes.Equal(out, tv.c0) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tv.c0, out)
+		}
+	}
+
+	x := unhex("7df76b0c1ab899b33e42f047b91b546f")
+	halve(x, x, _Rb128)
+	if expected := unhex("befbb5860d5c4cd99f217823dc8daa74"); !bytes.Equal(x, expected) {
+		t.Errorf("expected: %x got %x\n", expected, x)
+	}
+}
+
+func TestOMAC2(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	c, _ := aes.NewCipher(key)
+	h, err := NewOMAC2(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Full-block messages match CMAC.
+	for _, tc := range nistvectors[0].cases {
+		if len(tc.msg) == 0 || len(tc.msg)%16 != 0 {
+			continue
+		}
+		h.Reset()
+		h.Write(tc.msg)
+		if tag := h.Sum(nil); !bytes.Equal(tag, tc.mac) {
+			t.Errorf("len %d: expected %x got %x", len(tc.msg), tc.mac, tag)
+		}
+	}
+
+	// A partial last block is padded and masked with L·u⁻¹.
+	k2 := unhex("befbb5860d5c4cd99f217823dc8daa74")
+	for _, n := range []int{0, 1, 15, 17, 40} {
+		msg := nistmsg[:n]
+		padded := make([]byte, (n/16+1)*16)
+		copy(padded, msg)
+		padded[n] = 0x80
+		for i := range k2 {
+			padded[len(padded)-16+i] ^= k2[i]
+		}
+		expected := cbcLast(c, padded)
+
+		h.Reset()
+		h.Write(msg)
+		if tag := h.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("len %d: expected %x got %x", n, expected, tag)
+		}
+	}
+
+	d, _ := des.NewTripleDESCipher(nistvectors[3].key)
+	if _, err := NewOMAC2WithCipher(d); err != nil {
+		t.Error(err)
+	}
+}
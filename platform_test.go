@@ -0,0 +1,88 @@
+package cmac
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+// Byte-order sensitive helpers are checked against explicit values so
+// that they can't silently depend on the host's endianness.
+
+func TestDbl(t *testing.T) {
+	tests := []struct {
+		in, out []byte
+		rb      byte
+	}{
+		{[]byte{0x00, 0x00}, []byte{0x00, 0x00}, _Rb64},
+		{[]byte{0x00, 0x01}, []byte{0x00, 0x02}, _Rb64},
+		{[]byte{0x00, 0x80}, []byte{0x01, 0x00}, _Rb64},
+		{[]byte{0x40, 0x01, 0xff}, []byte{0x80, 0x03, 0xfe}, _Rb64},
+		{[]byte{0x80, 0x01, 0xff}, []byte{0x00, 0x03, 0xe5}, _Rb64},
+		{unhex("7df76b0c1ab899b33e42f047b91b546f"), unhex("fbeed618357133667c85e08f7236a8de"), _Rb128},
+		{unhex("fbeed618357133667c85e08f7236a8de"), unhex("f7ddac306ae266ccf90bc11ee46d513b"), _Rb128},
+	}
+
+	for i, tt := range tests {
+		out := make([]byte, len(tt.in))
+		dbl(out, tt.in, tt.rb)
+		if !bytes.Equal(out, tt.out) {
+			t.Errorf("tv[%d]: expected: %x got %x\n", i, tt.out, out)
+		}
+
+		in := append([]byte(nil), tt.in...)
+		dbl(in, in, tt.rb)
+		if !bytes.Equal(in, tt.out) {
+			t.Errorf("tv[%d]: in place: expected: %x got %x\n", i, tt.out, in)
+		}
+	}
+}
+
+func TestKDFEncoding(t *testing.T) {
+	// Computed with OpenSSL; three blocks exercise the counter and length encoding.
+	key := make([]byte, 16)
+	expected := unhex("7b2810024d3d595060086d1b259a17052f4d9c37bf3975b7c035d3779b2e699a5e")
+	out, err := kdf(key, []byte("L"), []byte("C"), len(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected: %x got %x\n", expected, out)
+	}
+}
+
+// TestCrossBuild builds and type-checks the module, tests included, for
+// big-endian and 32-bit targets, Windows, WebAssembly, builds without cgo,
+// and the purego build. Building links the commands, which catches
+// packages that lose their main function on some targets.
+func TestCrossBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping cross build in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	for _, target := range [][2]string{
+		{"linux", "s390x"}, {"linux", "ppc64"}, {"linux", "ppc64le"},
+		{"linux", "mips"}, {"linux", "386"}, {"linux", "arm"},
+		{"windows", "amd64"}, {"js", "wasm"}, {"wasip1", "wasm"},
+		{runtime.GOOS, runtime.GOARCH},
+	} {
+		for _, tool := range []string{"build", "vet"} {
+			cmd := exec.Command(gobin, tool, "./...")
+			cmd.Env = append(os.Environ(), "GOOS="+target[0], "GOARCH="+target[1], "CGO_ENABLED=0")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("go %s %s/%s: %v\n%s", tool, target[0], target[1], err, out)
+			}
+		}
+	}
+
+	cmd := exec.Command(gobin, "vet", "-tags", "purego", "./...")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("purego: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,254 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"io"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// pmac implements PMAC1 of Black and Rogaway with a 128-bit block cipher.
+// Every block but the last is encrypted independently under an offset, so
+// unlike CMAC the work can be split across cores.
+type pmac struct {
+	c cipher.Block
+	// l[i] is L·u^i and linv is L·u⁻¹, with L the encryption of zero.
+	l    [64][16]byte
+	linv [16]byte
+
+	offset, sum [16]byte
+	// buf holds the last block seen, which is only processed once it is
+	// known not to be the final one.
+	buf    [16]byte
+	cursor int
+	blocks uint64
+}
+
+// NewPMAC returns a hash.Hash computing PMAC with AES, subject to the
+// package Policy set with SetPolicy. See PMACSumReaderAt for computing
+// PMAC on several cores.
+func NewPMAC(key []byte) (hash.Hash, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newPMAC(c, p)
+}
+
+// NewPMACWithCipher returns a hash.Hash computing PMAC using the given
+// 128-bit block cipher.
+func NewPMACWithCipher(c cipher.Block) (hash.Hash, error) {
+	return newPMAC(c, currentPolicy())
+}
+
+func newPMAC(c cipher.Block, p *Policy) (hash.Hash, error) {
+	m, err := initPMAC(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkBlockSize(16); err != nil {
+		return nil, err
+	}
+	return p.wrap(m), nil
+}
+
+func initPMAC(c cipher.Block) (*pmac, error) {
+	if c == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cmac: PMAC requires a 128-bit block cipher")
+	}
+	m := &pmac{c: c}
+	c.Encrypt(m.l[0][:], m.l[0][:])
+	for i := 1; i < len(m.l); i++ {
+		dbl(m.l[i][:], m.l[i-1][:], _Rb128)
+	}
+	halve(m.linv[:], m.l[0][:], _Rb128)
+	return m, nil
+}
+
+func (m *pmac) Size() int      { return 16 }
+func (m *pmac) BlockSize() int { return 16 }
+
+func (m *pmac) Reset() {
+	m.offset, m.sum, m.buf = [16]byte{}, [16]byte{}, [16]byte{}
+	m.cursor, m.blocks = 0, 0
+}
+
+func (m *pmac) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if m.cursor == 16 {
+			m.process(m.buf[:])
+			m.cursor = 0
+		}
+		c := copy(m.buf[m.cursor:], b)
+		m.cursor += c
+		b = b[c:]
+	}
+	return n, nil
+}
+
+// process adds a block that is not the last one to the sum.
+func (m *pmac) process(b []byte) {
+	m.blocks++
+	xorBlock(m.offset[:], m.l[bits.TrailingZeros64(m.blocks)][:])
+	var t [16]byte
+	for i := range t {
+		t[i] = b[i] ^ m.offset[i]
+	}
+	m.c.Encrypt(t[:], t[:])
+	xorBlock(m.sum[:], t[:])
+}
+
+func (m *pmac) Sum(b []byte) []byte {
+	var tag [16]byte
+	m.finish(&tag, m.sum, m.buf[:m.cursor])
+	return append(b, tag[:]...)
+}
+
+// finish computes the tag from the sum over all blocks but the last one,
+// which is given in last.
+func (m *pmac) finish(tag *[16]byte, sum [16]byte, last []byte) {
+	xorBlock(sum[:], last)
+	if len(last) == 16 {
+		xorBlock(sum[:], m.linv[:])
+	} else {
+		sum[len(last)] ^= 0x80
+	}
+	m.c.Encrypt(tag[:], sum[:])
+}
+
+func xorBlock(d, s []byte) {
+	for i := range s {
+		d[i] ^= s[i]
+	}
+}
+
+// pmacChunk is the number of blocks each PMACSumReaderAt worker reads at a
+// time.
+const pmacChunk = 4096
+
+// PMACSumReaderAt computes the PMAC tag of the first size bytes of r using
+// c, which must be a 128-bit block cipher safe for concurrent use, such as
+// one from crypto/aes. Chunks of the input are read and processed by up to
+// workers goroutines, or GOMAXPROCS if workers is not positive.
+func PMACSumReaderAt(c cipher.Block, r io.ReaderAt, size int64, workers int) ([16]byte, error) {
+	var tag [16]byte
+	if size < 0 {
+		return tag, errors.New("cmac: negative size")
+	}
+	m, err := initPMAC(c)
+	if err != nil {
+		return tag, err
+	}
+	if p := currentPolicy(); p != nil {
+		if err := p.checkBlockSize(16); err != nil {
+			return tag, err
+		}
+		if p.MaxMessageSize > 0 && size > p.MaxMessageSize {
+			return tag, errors.New("cmac: message size limit exceeded")
+		}
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// All blocks but the last are summed in parallel; the last block is
+	// the final, possibly partial or empty, one.
+	var full int64
+	if size > 0 {
+		full = (size - 1) / 16
+	}
+	chunks := make(chan int64)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		sum   [16]byte
+		first error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, pmacChunk*16)
+			for start := range chunks {
+				n := full - start
+				if n > pmacChunk {
+					n = pmacChunk
+				}
+				s, err := m.sumChunk(r, buf[:n*16], start)
+				mu.Lock()
+				if err != nil && first == nil {
+					first = err
+				}
+				xorBlock(sum[:], s[:])
+				mu.Unlock()
+			}
+		}()
+	}
+	for start := int64(0); start < full; start += pmacChunk {
+		chunks <- start
+	}
+	close(chunks)
+	wg.Wait()
+	if first != nil {
+		return tag, first
+	}
+
+	last := make([]byte, size-full*16)
+	if err := readFullAt(r, last, full*16); err != nil {
+		return tag, err
+	}
+	m.finish(&tag, sum, last)
+	return tag, nil
+}
+
+// sumChunk returns the sum of the encrypted blocks read into buf from
+// block index start on.
+func (m *pmac) sumChunk(r io.ReaderAt, buf []byte, start int64) ([16]byte, error) {
+	var sum [16]byte
+	if err := readFullAt(r, buf, start*16); err != nil {
+		return sum, err
+	}
+
+	// The offset of block i, counting from 1, is the sum of l[j] for the
+	// bits j set in the Gray code of i.
+	var offset, t [16]byte
+	g := uint64(start) ^ uint64(start)>>1
+	for ; g != 0; g &= g - 1 {
+		xorBlock(offset[:], m.l[bits.TrailingZeros64(g)][:])
+	}
+	for i := 0; i < len(buf); i += 16 {
+		xorBlock(offset[:], m.l[bits.TrailingZeros64(uint64(start)+uint64(i/16)+1)][:])
+		for j := range t {
+			t[j] = buf[i+j] ^ offset[j]
+		}
+		m.c.Encrypt(t[:], t[:])
+		xorBlock(sum[:], t[:])
+	}
+	return sum, nil
+}
+
+func readFullAt(r io.ReaderAt, b []byte, off int64) error {
+	n, err := r.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
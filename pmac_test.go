@@ -0,0 +1,89 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+var pmacvectors = []struct {
+	msg []byte
+	tag string
+}{
+	{seq(0), "4399572cd6ea5341b8d35876a7098af7"},
+	{seq(3), "256ba5193c1b991b4df0c51f388a9e27"},
+	{seq(16), "ebbd822fa458daf6dfdad7c27da76338"},
+	{seq(20), "0412ca150bbf79058d8c75a58c993f55"},
+	{seq(32), "e97ac04e9e5e3399ce5355cd7407bc75"},
+	{seq(34), "5cba7d5eb24f7c86ccc54604e53d5512"},
+	{make([]byte, 1000), "c2c9fa1d9985f6f0d2aff915a0e8d910"},
+}
+
+// seq returns the bytes 0, 1, ..., n-1.
+func seq(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestPMAC(t *testing.T) {
+	// PMAC-AES-128 vectors from the PMAC reference implementation.
+	key := seq(16)
+	h, err := NewPMAC(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tv := range pmacvectors {
+		expected := unhex(tv.tag)
+		h.Reset()
+		h.Write(tv.msg)
+		if tag := h.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("tv[%d]: expected %x got %x", i, expected, tag)
+		}
+		// Byte at a time.
+		h.Reset()
+		for j := range tv.msg {
+			h.Write(tv.msg[j : j+1])
+		}
+		if tag := h.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("tv[%d]: bytewise: expected %x got %x", i, expected, tag)
+		}
+	}
+}
+
+func TestPMACSumReaderAt(t *testing.T) {
+	c, _ := aes.NewCipher(seq(16))
+	for i, tv := range pmacvectors {
+		tag, err := PMACSumReaderAt(c, bytes.NewReader(tv.msg), int64(len(tv.msg)), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := unhex(tv.tag); !bytes.Equal(tag[:], expected) {
+			t.Errorf("tv[%d]: expected %x got %x", i, expected, tag)
+		}
+	}
+
+	// Several chunks, with a partial last block.
+	msg := make([]byte, 5*pmacChunk*16+7)
+	for i := range msg {
+		msg[i] = byte(i * 31)
+	}
+	h, _ := NewPMACWithCipher(c)
+	for _, n := range []int{5 * pmacChunk * 16, len(msg)} {
+		h.Reset()
+		h.Write(msg[:n])
+		tag, err := PMACSumReaderAt(c, bytes.NewReader(msg), int64(n), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := h.Sum(nil); !bytes.Equal(tag[:], expected) {
+			t.Errorf("len %d: expected %x got %x", n, expected, tag)
+		}
+	}
+
+	if _, err := PMACSumReaderAt(c, bytes.NewReader(msg), int64(len(msg))+1, 3); err == nil {
+		t.Error("expected error for short input")
+	}
+}
@@ -0,0 +1,159 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+)
+
+// Policy restricts how CMAC instances may be constructed and used. A
+// Policy can be applied package-wide with SetPolicy, in which case it
+// governs New and NewWithCipher, or to individual constructions through its
+// New and NewWithCipher methods. The zero Policy allows everything.
+type Policy struct {
+	// KeySizes lists the AES key sizes, in bytes, accepted by New. An
+	// empty list allows all AES key sizes.
+	KeySizes []int
+
+	// BlockSizes lists the accepted cipher block sizes in bytes. An
+	// empty list allows both 8 and 16.
+	BlockSizes []int
+
+	// MinTagSize is the shortest tag, in bytes, that APIs producing or
+	// checking truncated tags will accept. Constructors in this package
+	// and its subpackages that choose a tag length check it through
+	// CheckTagSize.
+	MinTagSize int
+
+	// MaxMessageSize limits the number of bytes that may be written to a
+	// hash between resets. Once exceeded, Write returns an error. Zero
+	// means no limit.
+	MaxMessageSize int64
+
+	// FIPS restricts construction to 128-bit block ciphers and tags to
+	// at least 64 bits, following NIST SP800-38B, on top of the other
+	// settings.
+	FIPS bool
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   *Policy
+)
+
+// SetPolicy sets the Policy applied by New and NewWithCipher. A nil Policy
+// removes all restrictions. The Policy is copied, so later changes to p
+// have no effect.
+func SetPolicy(p *Policy) {
+	if p != nil {
+		c := *p
+		c.KeySizes = append([]int(nil), p.KeySizes...)
+		c.BlockSizes = append([]int(nil), p.BlockSizes...)
+		p = &c
+	}
+
+	policyMu.Lock()
+	policy = p
+	policyMu.Unlock()
+}
+
+func currentPolicy() *Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// New returns a hash.Hash computing AES-CMAC under the restrictions of p,
+// ignoring any package-wide Policy.
+func (p *Policy) New(key []byte) (hash.Hash, error) {
+	return newAES(key, p)
+}
+
+// NewWithCipher returns a hash.Hash computing CMAC with c under the
+// restrictions of p, ignoring any package-wide Policy.
+func (p *Policy) NewWithCipher(c cipher.Block) (hash.Hash, error) {
+	return newWithCipher(c, p)
+}
+
+func contains(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) checkKeySize(n int) error {
+	if p == nil || len(p.KeySizes) == 0 || contains(p.KeySizes, n) {
+		return nil
+	}
+	return fmt.Errorf("cmac: key size %d not allowed by policy", n)
+}
+
+func (p *Policy) checkBlockSize(n int) error {
+	if p == nil {
+		return nil
+	}
+	if p.FIPS && n != 16 {
+		return errors.New("cmac: 64-bit block ciphers not allowed in FIPS mode")
+	}
+	if len(p.BlockSizes) == 0 || contains(p.BlockSizes, n) {
+		return nil
+	}
+	return fmt.Errorf("cmac: block size %d not allowed by policy", n)
+}
+
+// CheckTagSize reports whether a tag of n bytes is allowed by p.
+func (p *Policy) CheckTagSize(n int) error {
+	if p == nil {
+		return nil
+	}
+	min := p.MinTagSize
+	if p.FIPS && min < 8 {
+		min = 8
+	}
+	if n < min {
+		return fmt.Errorf("cmac: tag size %d below policy minimum %d", n, min)
+	}
+	return nil
+}
+
+// CheckTagSize reports whether a tag of n bytes is allowed by the package
+// Policy set with SetPolicy. MACs truncating CMAC tags outside this package
+// should call it when the tag length is chosen.
+func CheckTagSize(n int) error {
+	return currentPolicy().CheckTagSize(n)
+}
+
+func (p *Policy) wrap(h hash.Hash) hash.Hash {
+	if p == nil || p.MaxMessageSize <= 0 {
+		return h
+	}
+	return &limited{Hash: h, max: p.MaxMessageSize}
+}
+
+// limited enforces Policy.MaxMessageSize.
+type limited struct {
+	hash.Hash
+	n, max int64
+}
+
+func (l *limited) Write(b []byte) (int, error) {
+	if int64(len(b)) > l.max-l.n {
+		return 0, errors.New("cmac: message size limit exceeded")
+	}
+	l.n += int64(len(b))
+	return l.Hash.Write(b)
+}
+
+func (l *limited) Verify(tag []byte) bool {
+	return verify(l.Hash, tag)
+}
+
+func (l *limited) Reset() {
+	l.n = 0
+	l.Hash.Reset()
+}
@@ -0,0 +1,79 @@
+package cmac
+
+import (
+	"crypto/des"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	p := &Policy{KeySizes: []int{32}}
+	if _, err := p.New(make([]byte, 16)); err == nil {
+		t.Error("expected error for disallowed key size")
+	}
+	if _, err := p.New(make([]byte, 32)); err != nil {
+		t.Errorf("allowed key size: %v", err)
+	}
+
+	c, err := des.NewTripleDESCipher(make([]byte, 24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = &Policy{FIPS: true}
+	if _, err := p.NewWithCipher(c); err == nil {
+		t.Error("expected error for TDEA in FIPS mode")
+	}
+	if err := p.CheckTagSize(4); err == nil {
+		t.Error("expected error for 32-bit tag in FIPS mode")
+	}
+	if err := p.CheckTagSize(8); err != nil {
+		t.Errorf("64-bit tag in FIPS mode: %v", err)
+	}
+	if _, err := (&Policy{BlockSizes: []int{8}}).NewWithCipher(c); err != nil {
+		t.Errorf("allowed block size: %v", err)
+	}
+
+	SetPolicy(&Policy{MinTagSize: 10})
+	defer SetPolicy(nil)
+	if CheckTagSize(9) == nil || CheckTagSize(10) != nil {
+		t.Error("CheckTagSize does not follow the package Policy")
+	}
+}
+
+func TestPolicyMaxMessageSize(t *testing.T) {
+	h, err := (&Policy{MaxMessageSize: 32}).New(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Write(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Write(make([]byte, 1)); err == nil {
+		t.Error("expected error past the message size limit")
+	}
+	h.Reset()
+	if _, err := h.Write(make([]byte, 1)); err != nil {
+		t.Errorf("write after reset: %v", err)
+	}
+}
+
+func TestSetPolicy(t *testing.T) {
+	p := &Policy{KeySizes: []int{16}}
+	SetPolicy(p)
+	defer SetPolicy(nil)
+
+	// Changes after SetPolicy have no effect.
+	p.KeySizes[0] = 24
+
+	if _, err := New(make([]byte, 24)); err == nil {
+		t.Error("expected error for disallowed key size")
+	}
+	if _, err := New(make([]byte, 16)); err != nil {
+		t.Errorf("allowed key size: %v", err)
+	}
+
+	// Policy methods ignore the package-wide policy.
+	if _, err := (&Policy{}).New(make([]byte, 24)); err != nil {
+		t.Errorf("explicit policy: %v", err)
+	}
+}
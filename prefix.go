@@ -0,0 +1,108 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Prefix holds the CMAC state after processing a constant message prefix,
+// such as a protocol magic, version and device ID, so that MACs of many
+// messages sharing it don't need to reprocess it. A Prefix is immutable
+// and safe for concurrent use.
+type Prefix struct {
+	s   State
+	p   *Policy
+	cbc cbcPool
+}
+
+// NewPrefix returns the AES-CMAC state for key after prefix, subject to
+// the package Policy set with SetPolicy. The Policy in effect at this call
+// applies to everything the Prefix computes; its MaxMessageSize limits the
+// prefix and, separately, the data following it.
+func NewPrefix(key, prefix []byte) (*Prefix, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return nil, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefix(c, prefix, p)
+}
+
+// NewPrefixWithCipher returns the CMAC state for c after prefix, as
+// NewPrefix.
+func NewPrefixWithCipher(c cipher.Block, prefix []byte) (*Prefix, error) {
+	return newPrefix(c, prefix, currentPolicy())
+}
+
+func newPrefix(c cipher.Block, prefix []byte, p *Policy) (*Prefix, error) {
+	if p != nil && p.MaxMessageSize > 0 && int64(len(prefix)) > p.MaxMessageSize {
+		return nil, errors.New("cmac: message size limit exceeded")
+	}
+	x := &Prefix{p: p}
+	if err := x.s.Init(c); err != nil {
+		return nil, err
+	}
+	if err := p.checkBlockSize(x.s.size); err != nil {
+		return nil, err
+	}
+	x.s.Write(prefix)
+	return x, nil
+}
+
+// New returns a hash.Hash continuing from the prefix. Its Reset method
+// returns it to the state right after the prefix.
+func (p *Prefix) New() hash.Hash {
+	return p.p.wrap(&prefixed{State: p.s, p: p})
+}
+
+// Sum appends the CMAC of the prefix followed by msg to b and returns the
+// resulting slice. It returns an error, and appends nothing, if msg
+// exceeds Policy.MaxMessageSize.
+func (p *Prefix) Sum(b, msg []byte) ([]byte, error) {
+	if p.p != nil && p.p.MaxMessageSize > 0 && int64(len(msg)) > p.p.MaxMessageSize {
+		return nil, errors.New("cmac: message size limit exceeded")
+	}
+	s := scratchPool.Get().(*scratch)
+	defer s.release()
+	s.State = p.s
+	cbc := p.cbc.get()
+	s.write(msg, &cbc)
+	p.cbc.put(cbc)
+	return s.Sum(b), nil
+}
+
+type prefixed struct {
+	State
+	p   *Prefix
+	cbc *cbcCache
+}
+
+func (m *prefixed) Write(b []byte) (int, error) {
+	return m.write(b, &m.cbc)
+}
+
+func (m *prefixed) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(m, r)
+}
+
+// Wipe is State.Wipe, also dropping the CBC encrypter of the hash.
+func (m *prefixed) Wipe() {
+	m.State.Wipe()
+	if m.cbc != nil {
+		m.cbc.wipe()
+		m.cbc = nil
+	}
+}
+
+func (m *prefixed) Reset() {
+	m.State = m.p.s
+}
@@ -0,0 +1,83 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/des"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	tv := nistvectors[0]
+	msg := tv.cases[3].msg
+
+	for _, n := range []int{0, 5, 16, 17, 40, 64} {
+		p, err := NewPrefix(tv.key, msg[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mac, err := p.Sum(nil, msg[n:]); err != nil || !bytes.Equal(mac, tv.cases[3].mac) {
+			t.Errorf("prefix %d: Sum: expected: %x got %x, %v\n", n, tv.cases[3].mac, mac, err)
+		}
+
+		h := p.New()
+		h.Write(msg[n:])
+		if mac := h.Sum(nil); !bytes.Equal(mac, tv.cases[3].mac) {
+			t.Errorf("prefix %d: New: expected: %x got %x\n", n, tv.cases[3].mac, mac)
+		}
+
+		// Reset returns to the prefix, and the Prefix itself is
+		// unaffected by writes to hashes derived from it.
+		h.Write([]byte("garbage"))
+		h.Reset()
+		h.Write(msg[n:])
+		if mac := h.Sum(nil); !bytes.Equal(mac, tv.cases[3].mac) {
+			t.Errorf("prefix %d: after Reset: expected: %x got %x\n", n, tv.cases[3].mac, mac)
+		}
+		if mac, _ := p.Sum(nil, msg[n:]); !bytes.Equal(mac, tv.cases[3].mac) {
+			t.Errorf("prefix %d: Prefix modified", n)
+		}
+	}
+}
+
+func TestPrefixPolicy(t *testing.T) {
+	tv := nistvectors[0]
+	defer SetPolicy(nil)
+
+	SetPolicy(&Policy{KeySizes: []int{32}})
+	if _, err := NewPrefix(tv.key, nil); err == nil {
+		t.Error("key size policy not enforced")
+	}
+	c, _ := des.NewTripleDESCipher(make([]byte, 24))
+	SetPolicy(&Policy{FIPS: true})
+	if _, err := NewPrefixWithCipher(c, nil); err == nil {
+		t.Error("block size policy not enforced")
+	}
+	if _, err := NewPrefix(nil, nil); err == nil {
+		t.Error("expected error for empty key")
+	}
+
+	SetPolicy(&Policy{MaxMessageSize: 16})
+	if _, err := NewPrefix(tv.key, nistmsg[:17]); err == nil {
+		t.Error("prefix over the message size limit accepted")
+	}
+	p, err := NewPrefix(tv.key, nistmsg[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetPolicy(nil)
+	if mac, err := p.Sum(nil, nistmsg[16:32]); err != nil || len(mac) != 16 {
+		t.Errorf("Sum within the limit: got %x, %v", mac, err)
+	}
+	if mac, err := p.Sum([]byte("dst"), nistmsg[16:33]); err == nil || mac != nil {
+		t.Errorf("Sum over the size limit: got %q, %v", mac, err)
+	}
+	h := p.New()
+	if _, err := h.Write(nistmsg[16:33]); err == nil {
+		t.Error("New ignored the message size limit")
+	}
+	h.Reset()
+	if _, err := h.Write(nistmsg[16:32]); err != nil {
+		t.Errorf("Write after Reset: %v", err)
+	}
+}
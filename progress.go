@@ -0,0 +1,116 @@
+package cmac
+
+import (
+	"hash"
+	"io"
+)
+
+// StreamOption configures SumReader, SumReaderContext, VerifyReaderContext
+// and SumFile. NewWriter, NewTagWriter and NewReader take them too but only
+// honor WithMaxSize and WithGzip; NewVerifier only honors WithMaxSize.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	every    int64
+	progress func(done, total int64)
+	maxSize  int64
+	gzip     bool
+}
+
+// defaultProgressInterval is the interval of WithProgress for a
+// non-positive every.
+const defaultProgressInterval = 1 << 20
+
+// WithProgress calls fn each time another every bytes have been MACed,
+// and once more at the end of the stream if its length is not a multiple
+// of every, so that CLIs can show progress and services can emit
+// heartbeats. done is the number of bytes MACed so far; total is the
+// length of the input when known, as for SumFile, and -1 otherwise. A
+// non-positive every means 1 MiB. fn runs on the calling goroutine and
+// should return quickly.
+func WithProgress(every int64, fn func(done, total int64)) StreamOption {
+	if every <= 0 {
+		every = defaultProgressInterval
+	}
+	return func(c *streamConfig) {
+		c.every, c.progress = every, fn
+	}
+}
+
+// WithMaxSize limits the stream to n bytes of data, on top of any
+// Policy.MaxMessageSize of the package Policy, so that a service can bound
+// the work done for a single untrusted stream. Past the limit, writes and
+// reads return an error instead of consuming more data. A non-positive n
+// means no limit.
+func WithMaxSize(n int64) StreamOption {
+	return func(c *streamConfig) {
+		c.maxSize = n
+	}
+}
+
+func newStreamConfig(opts []StreamOption) *streamConfig {
+	c := &streamConfig{}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// limit returns h wrapped to enforce the limit of WithMaxSize, if any.
+func (c *streamConfig) limit(h hash.Hash) hash.Hash {
+	return (&Policy{MaxMessageSize: c.maxSize}).wrap(h)
+}
+
+// writer returns w wrapped to report progress for an input of total bytes,
+// and a function to call once the input has been written.
+func (c *streamConfig) writer(w io.Writer, total int64) (io.Writer, func()) {
+	if c.progress == nil {
+		return w, func() {}
+	}
+	p := &progressWriter{w: w, c: c, total: total, next: c.every}
+	return p, p.finish
+}
+
+type progressWriter struct {
+	w           io.Writer
+	c           *streamConfig
+	done, total int64
+	// next is the byte count at which fn is called next.
+	next     int64
+	reported int64
+}
+
+// Write splits b at the reporting intervals so that fn sees every
+// boundary, even for a whole mapped file written at once.
+func (p *progressWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := len(b)
+		if int64(n) > p.next-p.done {
+			n = int(p.next - p.done)
+		}
+		m, err := p.w.Write(b[:n])
+		written += m
+		p.done += int64(m)
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+		if p.done == p.next {
+			p.report()
+			p.next += p.c.every
+		}
+	}
+	return written, nil
+}
+
+func (p *progressWriter) report() {
+	p.reported = p.done
+	p.c.progress(p.done, p.total)
+}
+
+func (p *progressWriter) finish() {
+	if p.reported != p.done || p.done == 0 {
+		p.report()
+	}
+}
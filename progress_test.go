@@ -0,0 +1,89 @@
+package cmac
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+type progressCall struct{ done, total int64 }
+
+func TestWithProgress(t *testing.T) {
+	key := nistvectors[0].key
+	var calls []progressCall
+	record := WithProgress(16, func(done, total int64) {
+		calls = append(calls, progressCall{done, total})
+	})
+
+	tag, err := SumReader(key, iotest.OneByteReader(bytes.NewReader(nistmsg[:40])), record)
+	if err != nil || !bytes.Equal(tag[:], nistvectors[0].cases[2].mac) {
+		t.Fatalf("got %x, %v", tag, err)
+	}
+	if want := []progressCall{{16, -1}, {32, -1}, {40, -1}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	calls = nil
+	SumReader(key, bytes.NewReader(nistmsg[:32]), record)
+	if want := []progressCall{{16, -1}, {32, -1}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	calls = nil
+	SumReader(key, bytes.NewReader(nil), record)
+	if want := []progressCall{{0, -1}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v for an empty stream, want %v", calls, want)
+	}
+}
+
+func TestSumFileProgress(t *testing.T) {
+	key := nistvectors[0].key
+	data := make([]byte, 2*mmapThreshold+1)
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []progressCall
+	tag, err := SumFile(key, path, WithProgress(mmapThreshold, func(done, total int64) {
+		calls = append(calls, progressCall{done, total})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := Sum(key, data); tag != want {
+		t.Errorf("got %x, want %x", tag, want)
+	}
+	n := int64(len(data))
+	if want := []progressCall{{mmapThreshold, n}, {2 * mmapThreshold, n}, {n, n}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestWithMaxSize(t *testing.T) {
+	tv := nistvectors[0]
+	if _, err := SumReader(tv.key, bytes.NewReader(nistmsg), WithMaxSize(63)); err == nil {
+		t.Error("SumReader ignored the size limit")
+	}
+	tag, err := SumReader(tv.key, iotest.OneByteReader(bytes.NewReader(nistmsg)), WithMaxSize(64))
+	if err != nil || !bytes.Equal(tag[:], tv.cases[3].mac) {
+		t.Errorf("SumReader at the limit: got %x, %v", tag, err)
+	}
+	if _, err := SumReader(tv.key, bytes.NewReader(nistmsg), WithMaxSize(0)); err != nil {
+		t.Errorf("zero limit: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, nistmsg, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SumFile(tv.key, path, WithMaxSize(63)); err == nil {
+		t.Error("SumFile ignored the size limit")
+	}
+	if tag, err := SumFile(tv.key, path, WithMaxSize(64)); err != nil || !bytes.Equal(tag[:], tv.cases[3].mac) {
+		t.Errorf("SumFile at the limit: got %x, %v", tag, err)
+	}
+}
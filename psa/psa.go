@@ -0,0 +1,223 @@
+// Package psa wraps CMAC in the shape of the ARM PSA Crypto MAC API
+// (psa_mac_sign_setup, psa_mac_update, psa_mac_sign_finish, ...), so code
+// ported from PSA-based firmware can keep its call structure.
+package psa
+
+import (
+	"crypto/subtle"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// Status is a PSA status code.
+type Status int32
+
+// PSA status codes returned by this package.
+const (
+	ErrorNotPermitted     Status = -133
+	ErrorNotSupported     Status = -134
+	ErrorInvalidArgument  Status = -135
+	ErrorBadState         Status = -137
+	ErrorBufferTooSmall   Status = -138
+	ErrorInvalidSignature Status = -149
+)
+
+func (s Status) Error() string {
+	switch s {
+	case ErrorNotPermitted:
+		return "psa: not permitted"
+	case ErrorNotSupported:
+		return "psa: not supported"
+	case ErrorInvalidArgument:
+		return "psa: invalid argument"
+	case ErrorBadState:
+		return "psa: bad state"
+	case ErrorBufferTooSmall:
+		return "psa: buffer too small"
+	case ErrorInvalidSignature:
+		return "psa: invalid signature"
+	default:
+		return "psa: unknown error"
+	}
+}
+
+// Algorithm is a PSA algorithm identifier (psa_algorithm_t).
+type Algorithm uint32
+
+// AlgCMAC is PSA_ALG_CMAC.
+const AlgCMAC Algorithm = 0x03c00200
+
+const (
+	macTruncationMask   = 0x003f0000
+	macTruncationOffset = 16
+)
+
+// TruncatedMAC returns the algorithm computing alg truncated to n bytes,
+// like PSA_ALG_TRUNCATED_MAC. An n of 0 selects the full-length MAC.
+func TruncatedMAC(alg Algorithm, n int) Algorithm {
+	return alg&^macTruncationMask | Algorithm(n)<<macTruncationOffset&macTruncationMask
+}
+
+// FullLengthMAC returns alg without any truncation, like
+// PSA_ALG_FULL_LENGTH_MAC.
+func FullLengthMAC(alg Algorithm) Algorithm {
+	return alg &^ macTruncationMask
+}
+
+// KeyType is a PSA key type (psa_key_type_t).
+type KeyType uint16
+
+// KeyTypeAES is PSA_KEY_TYPE_AES.
+const KeyTypeAES KeyType = 0x2400
+
+// Key is imported key material, standing in for a psa_key_id_t.
+type Key struct {
+	typ      KeyType
+	material []byte
+}
+
+// ImportKey imports raw key material of the given type, like
+// psa_import_key.
+func ImportKey(typ KeyType, data []byte) (*Key, error) {
+	if typ != KeyTypeAES {
+		return nil, ErrorNotSupported
+	}
+	switch len(data) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrorInvalidArgument
+	}
+
+	k := make([]byte, len(data))
+	copy(k, data)
+	return &Key{typ: typ, material: k}, nil
+}
+
+// Bits returns the key size in bits.
+func (k *Key) Bits() int {
+	return len(k.material) * 8
+}
+
+// MACLength returns the length in bytes of MACs computed by alg, like
+// PSA_MAC_LENGTH.
+func MACLength(typ KeyType, bits int, alg Algorithm) int {
+	if typ != KeyTypeAES || FullLengthMAC(alg) != AlgCMAC {
+		return 0
+	}
+	if n := int(alg&macTruncationMask) >> macTruncationOffset; n != 0 {
+		return n
+	}
+	return 16
+}
+
+// MACOperation is a multi-part MAC operation (psa_mac_operation_t). The
+// zero value is an inactive operation, ready for SignSetup or VerifySetup.
+type MACOperation struct {
+	h      hash.Hash
+	n      int
+	verify bool
+}
+
+func (op *MACOperation) setup(key *Key, alg Algorithm, verify bool) error {
+	if op.h != nil {
+		return ErrorBadState
+	}
+	if key == nil {
+		return ErrorInvalidArgument
+	}
+	if FullLengthMAC(alg) != AlgCMAC {
+		return ErrorNotSupported
+	}
+	n := MACLength(key.typ, key.Bits(), alg)
+	if n < 4 || n > 16 {
+		return ErrorInvalidArgument
+	}
+	if cmac.CheckTagSize(n) != nil {
+		return ErrorNotPermitted
+	}
+
+	h, err := cmac.New(key.material)
+	if err != nil {
+		return ErrorInvalidArgument
+	}
+	op.h, op.n, op.verify = h, n, verify
+	return nil
+}
+
+// SignSetup starts a MAC computation, like psa_mac_sign_setup.
+func (op *MACOperation) SignSetup(key *Key, alg Algorithm) error {
+	return op.setup(key, alg, false)
+}
+
+// VerifySetup starts a MAC verification, like psa_mac_verify_setup.
+func (op *MACOperation) VerifySetup(key *Key, alg Algorithm) error {
+	return op.setup(key, alg, true)
+}
+
+// Update adds input to the operation, like psa_mac_update.
+func (op *MACOperation) Update(input []byte) error {
+	if op.h == nil {
+		return ErrorBadState
+	}
+	op.h.Write(input)
+	return nil
+}
+
+// SignFinish writes the MAC to mac and returns its length, like
+// psa_mac_sign_finish. The operation is inactive afterwards.
+func (op *MACOperation) SignFinish(mac []byte) (int, error) {
+	if op.h == nil || op.verify {
+		return 0, ErrorBadState
+	}
+	if len(mac) < op.n {
+		op.Abort()
+		return 0, ErrorBufferTooSmall
+	}
+
+	n := copy(mac, op.h.Sum(nil)[:op.n])
+	op.Abort()
+	return n, nil
+}
+
+// VerifyFinish compares the computed MAC with mac in constant time, like
+// psa_mac_verify_finish. The operation is inactive afterwards.
+func (op *MACOperation) VerifyFinish(mac []byte) error {
+	if op.h == nil || !op.verify {
+		return ErrorBadState
+	}
+
+	sum := op.h.Sum(nil)[:op.n]
+	op.Abort()
+	if len(mac) != len(sum) || subtle.ConstantTimeCompare(sum, mac) != 1 {
+		return ErrorInvalidSignature
+	}
+	return nil
+}
+
+// Abort cancels the operation, like psa_mac_abort. Aborting an inactive
+// operation is not an error.
+func (op *MACOperation) Abort() error {
+	*op = MACOperation{}
+	return nil
+}
+
+// MACCompute computes the MAC of input in one call, like psa_mac_compute.
+func MACCompute(key *Key, alg Algorithm, input, mac []byte) (int, error) {
+	var op MACOperation
+	if err := op.SignSetup(key, alg); err != nil {
+		return 0, err
+	}
+	op.Update(input)
+	return op.SignFinish(mac)
+}
+
+// MACVerify verifies the MAC of input in one call, like psa_mac_verify.
+func MACVerify(key *Key, alg Algorithm, input, mac []byte) error {
+	var op MACOperation
+	if err := op.VerifySetup(key, alg); err != nil {
+		return err
+	}
+	op.Update(input)
+	return op.VerifyFinish(mac)
+}
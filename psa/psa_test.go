@@ -0,0 +1,119 @@
+package psa
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// RFC 4493 example 3.
+var (
+	key = unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg = unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	tag = unhex("dfa66747de9ae63030ca32611497c827")
+)
+
+func TestSign(t *testing.T) {
+	k, err := ImportKey(KeyTypeAES, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var op MACOperation
+	if err := op.SignSetup(k, AlgCMAC); err != nil {
+		t.Fatal(err)
+	}
+	op.Update(msg[:7])
+	op.Update(msg[7:])
+	mac := make([]byte, 32)
+	n, err := op.SignFinish(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mac[:n], tag) {
+		t.Errorf("expected: %x got %x\n", tag, mac[:n])
+	}
+
+	if err := op.Update(msg); err != ErrorBadState {
+		t.Errorf("Update after finish: expected %v got %v", ErrorBadState, err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	k, _ := ImportKey(KeyTypeAES, key)
+	alg := TruncatedMAC(AlgCMAC, 8)
+	if alg != 0x03c80200 {
+		t.Errorf("TruncatedMAC: got %#x", alg)
+	}
+	if MACLength(KeyTypeAES, 128, alg) != 8 {
+		t.Errorf("MACLength: got %d", MACLength(KeyTypeAES, 128, alg))
+	}
+
+	mac := make([]byte, 8)
+	n, err := MACCompute(k, alg, msg, mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 || !bytes.Equal(mac, tag[:8]) {
+		t.Errorf("expected: %x got %x\n", tag[:8], mac[:n])
+	}
+	if err := MACVerify(k, alg, msg, tag[:8]); err != nil {
+		t.Errorf("MACVerify: %v", err)
+	}
+	if err := MACVerify(k, alg, msg, tag); err != ErrorInvalidSignature {
+		t.Errorf("MACVerify full tag: expected %v got %v", ErrorInvalidSignature, err)
+	}
+	if _, err := MACCompute(k, TruncatedMAC(AlgCMAC, 2), msg, mac); err != ErrorInvalidArgument {
+		t.Errorf("short truncation: expected %v got %v", ErrorInvalidArgument, err)
+	}
+
+	cmac.SetPolicy(&cmac.Policy{MinTagSize: 12})
+	defer cmac.SetPolicy(nil)
+	if _, err := MACCompute(k, alg, msg, mac); err != ErrorNotPermitted {
+		t.Errorf("truncation below policy: expected %v got %v", ErrorNotPermitted, err)
+	}
+	if err := MACVerify(k, alg, msg, tag[:8]); err != ErrorNotPermitted {
+		t.Errorf("verify below policy: expected %v got %v", ErrorNotPermitted, err)
+	}
+	if _, err := MACCompute(k, AlgCMAC, msg, make([]byte, 16)); err != nil {
+		t.Errorf("full-length MAC under policy: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	k, _ := ImportKey(KeyTypeAES, key)
+
+	var op MACOperation
+	if err := op.VerifySetup(k, AlgCMAC); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.VerifySetup(k, AlgCMAC); err != ErrorBadState {
+		t.Errorf("double setup: expected %v got %v", ErrorBadState, err)
+	}
+	op.Update(msg)
+	if _, err := op.SignFinish(make([]byte, 16)); err != ErrorBadState {
+		t.Errorf("SignFinish on verify: expected %v got %v", ErrorBadState, err)
+	}
+	if err := op.VerifyFinish(tag); err != nil {
+		t.Errorf("VerifyFinish: %v", err)
+	}
+
+	bad := append([]byte(nil), tag...)
+	bad[0] ^= 1
+	if err := MACVerify(k, AlgCMAC, msg, bad); err != ErrorInvalidSignature {
+		t.Errorf("expected %v got %v", ErrorInvalidSignature, err)
+	}
+	if _, err := MACCompute(k, AlgCMAC, msg, make([]byte, 15)); err != ErrorBufferTooSmall {
+		t.Errorf("expected %v got %v", ErrorBufferTooSmall, err)
+	}
+}
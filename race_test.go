@@ -0,0 +1,7 @@
+//go:build race
+
+package cmac
+
+// The race detector drops pooled items at random, so allocation counts
+// are not meaningful under it.
+const raceEnabled = true
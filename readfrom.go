@@ -0,0 +1,107 @@
+package cmac
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"sync"
+)
+
+// readBufferSize is the size of the pooled buffers used to read streams.
+// It is a multiple of both block sizes.
+const readBufferSize = 64 << 10
+
+var readBufferPool = sync.Pool{New: func() interface{} { return new([readBufferSize]byte) }}
+
+// ReadFrom implements io.ReaderFrom, so that io.Copy(s, r) feeds r into
+// the MAC through a pooled buffer instead of allocating one per copy. It
+// reads until io.EOF, which is not returned, or the first error.
+func (s *State) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(s, r)
+}
+
+func (m *cmac) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(m, r)
+}
+
+func (l *limited) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(l, r)
+}
+
+// SumReader returns the AES-CMAC tag of everything read from r until
+// io.EOF, subject to the package Policy set with SetPolicy. The data is
+// read through a pooled buffer. On a read error, or if the message exceeds
+// Policy.MaxMessageSize, the error is returned instead of a tag.
+func SumReader(key []byte, r io.Reader, opts ...StreamOption) ([16]byte, error) {
+	return SumReaderContext(context.Background(), key, r, opts...)
+}
+
+// SumReaderContext is like SumReader but stops reading, and returns the
+// error of ctx, once ctx is done. ctx is checked before every read of up to
+// 64 KiB; a read that blocks is not interrupted, so slow network streams
+// should also have a read deadline.
+func SumReaderContext(ctx context.Context, key []byte, r io.Reader, opts ...StreamOption) ([16]byte, error) {
+	var tag [16]byte
+	h, err := New(key)
+	if err != nil {
+		return tag, err
+	}
+	c := newStreamConfig(opts)
+	if r, err = c.decompress(r); err != nil {
+		return tag, err
+	}
+	w, finish := c.writer(c.limit(h), -1)
+	if _, err := readFromContext(ctx, w, r); err != nil {
+		return tag, err
+	}
+	finish()
+	h.Sum(tag[:0])
+	return tag, nil
+}
+
+// VerifyReaderContext reads r like SumReaderContext and returns
+// ErrInvalidTag if tag is not the AES-CMAC tag of the data, using a
+// constant-time comparison. It returns the error of ctx if ctx is done
+// first.
+func VerifyReaderContext(ctx context.Context, key []byte, r io.Reader, tag []byte, opts ...StreamOption) error {
+	t, err := SumReaderContext(ctx, key, r, opts...)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(t[:], tag) != 1 {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// readFrom writes everything read from r to w, which must only fail on
+// the message size limit of a Policy.
+func readFrom(w io.Writer, r io.Reader) (int64, error) {
+	return readFromContext(context.Background(), w, r)
+}
+
+// readFromContext is readFrom, stopping once ctx is done.
+func readFromContext(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	buf := readBufferPool.Get().(*[readBufferSize]byte)
+	defer readBufferPool.Put(buf)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := r.Read(buf[:])
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return total, werr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFrom(t *testing.T) {
+	tv := nistvectors[0]
+	for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+		h, err := newAES(tv.key, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := h.(io.ReaderFrom); !ok {
+			t.Fatalf("%T does not implement io.ReaderFrom", h)
+		}
+		n, err := io.Copy(h, iotest.OneByteReader(bytes.NewReader(nistmsg[:40])))
+		if err != nil || n != 40 {
+			t.Fatalf("copied %d bytes: %v", n, err)
+		}
+		if !bytes.Equal(h.Sum(nil), tv.cases[2].mac) {
+			t.Error("wrong MAC")
+		}
+	}
+
+	// Read errors and the message size limit are reported.
+	h, _ := newAES(tv.key, nil)
+	fail := errors.New("read failed")
+	if _, err := h.(io.ReaderFrom).ReadFrom(iotest.ErrReader(fail)); err != fail {
+		t.Errorf("got %v, want the read error", err)
+	}
+	h, _ = newAES(tv.key, &Policy{MaxMessageSize: 16})
+	if _, err := h.(io.ReaderFrom).ReadFrom(bytes.NewReader(nistmsg)); err == nil {
+		t.Error("message size limit not enforced")
+	}
+}
+
+func TestReadFromAllocs(t *testing.T) {
+	h, _ := New(nistvectors[0].key)
+	r := bytes.NewReader(nil)
+	big := make([]byte, 1<<20)
+	rf := h.(io.ReaderFrom)
+	if n := testing.AllocsPerRun(10, func() {
+		r.Reset(big)
+		rf.ReadFrom(r)
+	}); n > 0 {
+		t.Errorf("ReadFrom allocates %v times", n)
+	}
+}
+
+func TestSumReader(t *testing.T) {
+	for _, tv := range nistvectors[:3] {
+		for _, c := range tv.cases {
+			tag, err := SumReader(tv.key, iotest.HalfReader(bytes.NewReader(c.msg)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(tag[:], c.mac) {
+				t.Errorf("%x: got %x, want %x", c.msg, tag, c.mac)
+			}
+		}
+	}
+
+	fail := errors.New("read failed")
+	if _, err := SumReader(nistvectors[0].key, iotest.ErrReader(fail)); err != fail {
+		t.Errorf("got %v, want the read error", err)
+	}
+	if _, err := SumReader(nil, bytes.NewReader(nistmsg)); err == nil {
+		t.Error("accepted an empty key")
+	}
+}
+
+// cancelReader cancels its context after the first read.
+type cancelReader struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(b []byte) (int, error) {
+	defer r.cancel()
+	return r.Reader.Read(b)
+}
+
+func TestSumReaderContext(t *testing.T) {
+	tv := nistvectors[0]
+	ctx := context.Background()
+	tag, err := SumReaderContext(ctx, tv.key, bytes.NewReader(nistmsg[:40]))
+	if err != nil || !bytes.Equal(tag[:], tv.cases[2].mac) {
+		t.Fatalf("got %x, %v", tag, err)
+	}
+	if err := VerifyReaderContext(ctx, tv.key, bytes.NewReader(nistmsg[:40]), tv.cases[2].mac); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyReaderContext(ctx, tv.key, bytes.NewReader(nistmsg[:41]), tv.cases[2].mac); err != ErrInvalidTag {
+		t.Errorf("got %v, want ErrInvalidTag", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &cancelReader{iotest.OneByteReader(bytes.NewReader(nistmsg)), cancel}
+	if _, err := SumReaderContext(ctx, tv.key, r); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if err := VerifyReaderContext(ctx, tv.key, bytes.NewReader(nistmsg[:40]), tv.cases[2].mac); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
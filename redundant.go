@@ -0,0 +1,82 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// ErrMismatch is returned when redundant implementations compute different
+// tags for the same message.
+var ErrMismatch = errors.New("cmac: redundant implementations disagree")
+
+// Redundant computes every tag with two independent MAC implementations,
+// for example the optimized one from New and the reference package, or a
+// local one and one backed by an HSM, and reports an error rather than a
+// tag when they disagree.
+//
+// Redundant is not a hash.Hash because Sum can fail.
+type Redundant struct {
+	a, b hash.Hash
+}
+
+// NewRedundant returns a Redundant over a and b, which must be keyed
+// identically and produce tags of the same size.
+func NewRedundant(a, b hash.Hash) (*Redundant, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("cmac: nil hash")
+	}
+	if a.Size() != b.Size() {
+		return nil, errors.New("cmac: redundant implementations differ in tag size")
+	}
+	return &Redundant{a: a, b: b}, nil
+}
+
+// Write adds p to the running MAC of both implementations.
+func (r *Redundant) Write(p []byte) (int, error) {
+	n, err := r.a.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if m, err := r.b.Write(p); err != nil || m != n {
+		if err == nil {
+			err = ErrMismatch
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+// Sum appends the tag to b and returns the result, or ErrMismatch if the
+// implementations computed different tags.
+func (r *Redundant) Sum(b []byte) ([]byte, error) {
+	out := r.a.Sum(b)
+	other := r.b.Sum(nil)
+	if subtle.ConstantTimeCompare(out[len(b):], other) != 1 {
+		return nil, ErrMismatch
+	}
+	return out, nil
+}
+
+// Verify reports whether tag is the tag of the message written so far. It
+// returns ErrMismatch if the implementations disagree and ErrInvalidTag
+// if they agree but tag is wrong.
+func (r *Redundant) Verify(tag []byte) error {
+	t, err := r.Sum(nil)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(t, tag) != 1 {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// Reset resets both implementations.
+func (r *Redundant) Reset() {
+	r.a.Reset()
+	r.b.Reset()
+}
+
+// Size returns the tag size in bytes.
+func (r *Redundant) Size() int { return r.a.Size() }
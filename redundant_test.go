@@ -0,0 +1,50 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/des"
+	"testing"
+)
+
+func TestRedundant(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	expected := unhex("070a16b46b4d4144f79bdd9dd04a287c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172a")
+
+	c, _ := aes.NewCipher(key)
+	a, _ := New(key)
+	b, _ := NewWithCipher(genericBlock{c})
+	r, err := NewRedundant(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write(msg)
+	tag, err := r.Sum(nil)
+	if err != nil || !bytes.Equal(tag, expected) {
+		t.Errorf("expected %x, got %x (%v)", expected, tag, err)
+	}
+	if err := r.Verify(expected); err != nil {
+		t.Error(err)
+	}
+	if err := r.Verify(make([]byte, 16)); err != ErrInvalidTag {
+		t.Errorf("expected %v, got %v", ErrInvalidTag, err)
+	}
+
+	// Implementations keyed differently disagree.
+	other, _ := New(make([]byte, 16))
+	r, _ = NewRedundant(a, other)
+	r.Write(msg)
+	if _, err := r.Sum(nil); err != ErrMismatch {
+		t.Errorf("expected %v, got %v", ErrMismatch, err)
+	}
+	if err := r.Verify(expected); err != ErrMismatch {
+		t.Errorf("expected %v, got %v", ErrMismatch, err)
+	}
+
+	dc, _ := des.NewTripleDESCipher(make([]byte, 24))
+	d, _ := NewWithCipher(dc)
+	if _, err := NewRedundant(a, d); err == nil {
+		t.Error("expected error for differing tag sizes")
+	}
+}
@@ -0,0 +1,164 @@
+// Package reference is a slow, straightforward implementation of CMAC that
+// follows the algorithms of RFC 4493 section 2 step by step. It buffers the
+// whole message and only computes the MAC on Sum.
+//
+// It has the same constructors as package cmac and is intended for
+// auditing and for cross-checking optimized implementations.
+package reference
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// generateSubkey implements RFC 4493 section 2.3.
+func generateSubkey(c cipher.Block) (k1, k2 []byte) {
+	rb := byte(0x87)
+	if c.BlockSize() == 8 {
+		rb = 0x1b
+	}
+
+	l := make([]byte, c.BlockSize())
+	c.Encrypt(l, l)
+
+	k1 = leftShift(l)
+	if msb(l) {
+		k1[len(k1)-1] ^= rb
+	}
+	k2 = leftShift(k1)
+	if msb(k1) {
+		k2[len(k2)-1] ^= rb
+	}
+	return k1, k2
+}
+
+func msb(x []byte) bool {
+	return x[0]&0x80 != 0
+}
+
+// leftShift returns x shifted left by one bit.
+func leftShift(x []byte) []byte {
+	out := make([]byte, len(x))
+	for i := range x {
+		out[i] = x[i] << 1
+		if i+1 < len(x) {
+			out[i] |= x[i+1] >> 7
+		}
+	}
+	return out
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// padding implements RFC 4493 section 2.4 padding(r): r followed by a
+// single one bit and as many zero bits as are needed to fill a block.
+func padding(r []byte, bs int) []byte {
+	out := make([]byte, bs)
+	copy(out, r)
+	out[len(r)] = 0x80
+	return out
+}
+
+// mac implements RFC 4493 section 2.4.
+func mac(c cipher.Block, m []byte) []byte {
+	bs := c.BlockSize()
+	k1, k2 := generateSubkey(c)
+
+	// Step 2-3.
+	n := (len(m) + bs - 1) / bs
+	var flag bool
+	if n == 0 {
+		n = 1
+		flag = false
+	} else {
+		flag = len(m)%bs == 0
+	}
+
+	// Step 4.
+	var mlast []byte
+	last := m[(n-1)*bs:]
+	if flag {
+		mlast = xor(last, k1)
+	} else {
+		mlast = xor(padding(last, bs), k2)
+	}
+
+	// Step 5-6.
+	x := make([]byte, bs)
+	for i := 0; i < n-1; i++ {
+		y := xor(x, m[i*bs:(i+1)*bs])
+		c.Encrypt(x, y)
+	}
+	y := xor(mlast, x)
+	t := make([]byte, bs)
+	c.Encrypt(t, y)
+
+	// Step 7.
+	return t
+}
+
+type digest struct {
+	c   cipher.Block
+	msg []byte
+}
+
+func (d *digest) Write(b []byte) (int, error) {
+	d.msg = append(d.msg, b...)
+	return len(b), nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	return append(b, mac(d.c, d.msg)...)
+}
+
+func (d *digest) Reset()         { d.msg = d.msg[:0] }
+func (d *digest) Size() int      { return d.c.BlockSize() }
+func (d *digest) BlockSize() int { return d.c.BlockSize() }
+
+// New returns a hash.Hash computing AES-CMAC.
+func New(key []byte) (hash.Hash, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithCipher(c)
+}
+
+// NewWithCipher returns a hash.Hash computing CMAC using the given
+// cipher.Block. The block cipher should have a block length of 8 or 16 bytes.
+func NewWithCipher(c cipher.Block) (hash.Hash, error) {
+	if c == nil {
+		return nil, errors.New("cmac: nil cipher")
+	}
+	switch c.BlockSize() {
+	case 8, 16:
+		return &digest{c: c}, nil
+	default:
+		return nil, errors.New("cmac: invalid blocksize")
+	}
+}
+
+// NewCrossChecked returns a cmac.Redundant that computes each AES-CMAC tag
+// with both package cmac and this reference implementation.
+func NewCrossChecked(key []byte) (*cmac.Redundant, error) {
+	a, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return cmac.NewRedundant(a, b)
+}
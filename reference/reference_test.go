@@ -0,0 +1,85 @@
+package reference
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/des"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestRFC4493(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	tests := []struct {
+		n   int
+		tag string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+
+	h, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		h.Reset()
+		h.Write(msg[:tt.n])
+		if tag := h.Sum(nil); !bytes.Equal(tag, unhex(tt.tag)) {
+			t.Errorf("len %d: expected: %s got %x\n", tt.n, tt.tag, tag)
+		}
+	}
+}
+
+// TestCrossCheck compares the reference with package cmac on random
+// inputs.
+func TestCrossCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, _ := aes.NewCipher(make([]byte, 16))
+	d, _ := des.NewTripleDESCipher(make([]byte, 24))
+
+	for i := 0; i < 200; i++ {
+		c := a
+		if i%2 == 1 {
+			c = d
+		}
+		msg := make([]byte, r.Intn(100))
+		r.Read(msg)
+
+		ref, _ := NewWithCipher(c)
+		opt, _ := cmac.NewWithCipher(c)
+		ref.Write(msg)
+		opt.Write(msg)
+		if x, y := ref.Sum(nil), opt.Sum(nil); !bytes.Equal(x, y) {
+			t.Fatalf("len %d: reference %x, cmac %x", len(msg), x, y)
+		}
+	}
+}
+
+func TestNewCrossChecked(t *testing.T) {
+	r, err := NewCrossChecked(unhex("2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := r.Sum(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := unhex("bb1d6929e95937287fa37d129b756746"); !bytes.Equal(tag, expected) {
+		t.Errorf("expected %x, got %x", expected, tag)
+	}
+}
@@ -0,0 +1,99 @@
+package reference
+
+import (
+	"bytes"
+	"crypto/aes"
+	"flag"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joekir/cmac"
+)
+
+var (
+	soak        = flag.Duration("soak", 0, "run TestSoak for this long")
+	soakWorkers = flag.Int("soak.workers", runtime.GOMAXPROCS(0), "number of TestSoak goroutines")
+)
+
+// TestSoak cross-checks package cmac against the reference from many
+// goroutines on randomized keys, messages and write patterns. Each worker
+// keeps reusing its hashes and a cipher shared with the other workers, so
+// state left behind by one computation or shared between goroutines shows
+// up as a mismatch. It only runs when -soak is set, e.g.
+//
+//	go test -race -run TestSoak ./reference -soak=10m
+func TestSoak(t *testing.T) {
+	if *soak <= 0 {
+		t.Skip("soak test disabled; set -soak to enable")
+	}
+
+	shared, _ := aes.NewCipher(make([]byte, 32))
+	deadline := time.Now().Add(*soak)
+	var (
+		wg     sync.WaitGroup
+		failed int32
+		count  int64
+	)
+	for w := 0; w < *soakWorkers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			var st cmac.State
+			st.Init(shared)
+			sh, _ := cmac.NewWithCipher(shared)
+			ref, _ := NewWithCipher(shared)
+
+			for atomic.LoadInt32(&failed) == 0 && time.Now().Before(deadline) {
+				key := make([]byte, 16+8*r.Intn(3))
+				r.Read(key)
+				msg := make([]byte, r.Intn(4096))
+				r.Read(msg)
+
+				// A fresh key through New and the reference.
+				h, _ := cmac.New(key)
+				href, _ := New(key)
+				write(r, h, msg)
+				href.Write(msg)
+				if x, y := href.Sum(nil), h.Sum(nil); !bytes.Equal(x, y) {
+					atomic.StoreInt32(&failed, 1)
+					t.Errorf("key %x, len %d: reference %x, cmac %x", key, len(msg), x, y)
+					return
+				}
+
+				// The long-lived hashes over the shared cipher.
+				ref.Reset()
+				sh.Reset()
+				st.Reset()
+				ref.Write(msg)
+				write(r, sh, msg)
+				write(r, &st, msg)
+				want := ref.Sum(nil)
+				if x, y := sh.Sum(nil), st.Sum(nil); !bytes.Equal(x, want) || !bytes.Equal(y, want) {
+					atomic.StoreInt32(&failed, 1)
+					t.Errorf("shared cipher, len %d: reference %x, cmac %x, state %x", len(msg), want, x, y)
+					return
+				}
+				atomic.AddInt64(&count, 1)
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+	wg.Wait()
+	t.Logf("%d iterations on %d workers", count, *soakWorkers)
+}
+
+// write writes msg to w in randomly sized pieces.
+func write(r *rand.Rand, w interface{ Write([]byte) (int, error) }, msg []byte) {
+	for len(msg) > 0 {
+		n := 1 + r.Intn(len(msg))
+		if r.Intn(2) == 0 && n > 33 {
+			n = 1 + r.Intn(33)
+		}
+		w.Write(msg[:n])
+		msg = msg[n:]
+	}
+}
@@ -0,0 +1,239 @@
+// Package replay records the sequence of Write, Sum and Reset calls made on
+// a hash.Hash and replays it against other implementations, to reproduce
+// and bisect streaming-state bugs.
+//
+// A recording always contains the size and a SHA-256 digest of every
+// write, and the tag returned by every Sum. The written data itself is
+// only kept when requested, so that traces taken in production need not
+// contain the messages.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kind identifies a hash.Hash method.
+type Kind byte
+
+// Recorded operations.
+const (
+	Write Kind = 'W'
+	Sum   Kind = 'S'
+	Reset Kind = 'R'
+)
+
+// Op is a single recorded operation.
+type Op struct {
+	Kind Kind
+
+	// Len is the number of bytes written for Write, and the length of
+	// the tag for Sum.
+	Len int
+
+	// Digest is the SHA-256 digest of the data for Write, and the tag
+	// itself for Sum.
+	Digest []byte
+
+	// Data is the written data, if it was recorded.
+	Data []byte
+}
+
+// Recorder is a hash.Hash that records every operation applied to it
+// before passing it on to the underlying hash.
+type Recorder struct {
+	h        hash.Hash
+	keepData bool
+	ops      []Op
+}
+
+// NewRecorder returns a Recorder wrapping h. If keepData is set, written
+// data is recorded in full.
+func NewRecorder(h hash.Hash, keepData bool) *Recorder {
+	return &Recorder{h: h, keepData: keepData}
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	d := sha256.Sum256(b)
+	op := Op{Kind: Write, Len: len(b), Digest: d[:]}
+	if r.keepData {
+		op.Data = append([]byte(nil), b...)
+	}
+	r.ops = append(r.ops, op)
+	return r.h.Write(b)
+}
+
+func (r *Recorder) Sum(b []byte) []byte {
+	n := len(b)
+	b = r.h.Sum(b)
+	tag := append([]byte(nil), b[n:]...)
+	r.ops = append(r.ops, Op{Kind: Sum, Len: len(tag), Digest: tag})
+	return b
+}
+
+func (r *Recorder) Reset() {
+	r.ops = append(r.ops, Op{Kind: Reset})
+	r.h.Reset()
+}
+
+func (r *Recorder) Size() int      { return r.h.Size() }
+func (r *Recorder) BlockSize() int { return r.h.BlockSize() }
+
+// Ops returns the operations recorded so far.
+func (r *Recorder) Ops() []Op {
+	return r.ops
+}
+
+// MismatchError reports the first operation whose outcome differed from
+// the recording.
+type MismatchError struct {
+	Index    int
+	Op       Op
+	Got      []byte
+	Expected []byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("replay: op %d (%c): expected %x got %x", e.Index, e.Op.Kind, e.Expected, e.Got)
+}
+
+// Replay applies ops to h and checks that every Sum returns the recorded
+// tag. The recording must contain the written data. The returned error is
+// a *MismatchError if h diverges from the recording.
+func Replay(ops []Op, h hash.Hash) error {
+	for i, op := range ops {
+		switch op.Kind {
+		case Write:
+			if op.Data == nil && op.Len > 0 {
+				return fmt.Errorf("replay: op %d: data not recorded", i)
+			}
+			h.Write(op.Data)
+		case Sum:
+			if tag := h.Sum(nil); !bytes.Equal(tag, op.Digest) {
+				return &MismatchError{Index: i, Op: op, Got: tag, Expected: op.Digest}
+			}
+		case Reset:
+			h.Reset()
+		default:
+			return fmt.Errorf("replay: op %d: unknown kind %q", i, op.Kind)
+		}
+	}
+	return nil
+}
+
+// Compare applies ops to both a and b and returns the index of the first
+// Sum on which they disagree, or -1 if they never do. Writes whose data was
+// not recorded are replayed with a deterministic filler of the recorded
+// size, so size-only recordings can still expose chunking bugs.
+func Compare(ops []Op, a, b hash.Hash) int {
+	for i, op := range ops {
+		switch op.Kind {
+		case Write:
+			d := op.Data
+			if d == nil {
+				d = filler(i, op.Len)
+			}
+			a.Write(d)
+			b.Write(d)
+		case Sum:
+			if !bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+				return i
+			}
+		case Reset:
+			a.Reset()
+			b.Reset()
+		}
+	}
+	return -1
+}
+
+func filler(seed, n int) []byte {
+	d := make([]byte, n)
+	for i := range d {
+		d[i] = byte(seed*31 + i)
+	}
+	return d
+}
+
+// Encode writes ops to w, one per line.
+func Encode(w io.Writer, ops []Op) error {
+	bw := bufio.NewWriter(w)
+	for _, op := range ops {
+		switch op.Kind {
+		case Write:
+			fmt.Fprintf(bw, "W %d %x", op.Len, op.Digest)
+			if op.Data != nil {
+				fmt.Fprintf(bw, " %x", op.Data)
+			}
+			bw.WriteByte('\n')
+		case Sum:
+			fmt.Fprintf(bw, "S %d %x\n", op.Len, op.Digest)
+		case Reset:
+			bw.WriteString("R\n")
+		default:
+			return fmt.Errorf("replay: unknown kind %q", op.Kind)
+		}
+	}
+	return bw.Flush()
+}
+
+// Decode reads ops written by Encode.
+func Decode(r io.Reader) ([]Op, error) {
+	var ops []Op
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	for line := 1; s.Scan(); line++ {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 {
+			continue
+		}
+
+		op, err := decodeOp(f)
+		if err != nil {
+			return nil, fmt.Errorf("replay: line %d: %v", line, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, s.Err()
+}
+
+func decodeOp(f []string) (Op, error) {
+	var op Op
+	if len(f[0]) != 1 {
+		return op, errors.New("invalid op")
+	}
+	op.Kind = Kind(f[0][0])
+
+	switch {
+	case op.Kind == Reset && len(f) == 1:
+		return op, nil
+	case op.Kind == Sum && len(f) == 3, op.Kind == Write && (len(f) == 3 || len(f) == 4):
+	default:
+		return op, errors.New("invalid op")
+	}
+
+	var err error
+	if op.Len, err = strconv.Atoi(f[1]); err != nil {
+		return op, err
+	}
+	if op.Digest, err = hex.DecodeString(f[2]); err != nil {
+		return op, err
+	}
+	if len(f) == 4 {
+		if op.Data, err = hex.DecodeString(f[3]); err != nil {
+			return op, err
+		}
+		if len(op.Data) != op.Len {
+			return op, errors.New("data length mismatch")
+		}
+	}
+	return op, nil
+}
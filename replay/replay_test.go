@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"bytes"
+	"hash"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+var key = make([]byte, 16)
+
+func newMAC(t *testing.T) hash.Hash {
+	h, err := cmac.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func record(t *testing.T, keepData bool) []Op {
+	r := NewRecorder(newMAC(t), keepData)
+	for _, n := range []int{0, 1, 15, 16, 17, 33} {
+		r.Write(make([]byte, n))
+		r.Sum(nil)
+	}
+	r.Reset()
+	r.Write([]byte("after reset"))
+	r.Sum(nil)
+	return r.Ops()
+}
+
+func TestReplay(t *testing.T) {
+	ops := record(t, true)
+	if len(ops) != 15 {
+		t.Fatalf("expected 15 ops, got %d", len(ops))
+	}
+	if err := Replay(ops, newMAC(t)); err != nil {
+		t.Error(err)
+	}
+
+	h, _ := cmac.New(make([]byte, 24))
+	err := Replay(ops, h)
+	if merr, ok := err.(*MismatchError); !ok || merr.Index != 1 {
+		t.Errorf("expected mismatch at op 1, got %v", err)
+	}
+
+	if err := Replay(record(t, false), newMAC(t)); err == nil {
+		t.Error("expected error replaying without data")
+	}
+}
+
+// brokenMAC forgets buffered data whenever a write ends on a block
+// boundary.
+type brokenMAC struct {
+	hash.Hash
+	buf []byte
+}
+
+func (b *brokenMAC) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf)%16 == 0 {
+		b.buf = b.buf[:0]
+	}
+	return len(p), nil
+}
+
+func (b *brokenMAC) Sum(p []byte) []byte {
+	b.Hash.Reset()
+	b.Hash.Write(b.buf)
+	return b.Hash.Sum(p)
+}
+
+func (b *brokenMAC) Reset() { b.buf = b.buf[:0] }
+
+func TestCompare(t *testing.T) {
+	ops := record(t, false)
+	if i := Compare(ops, newMAC(t), newMAC(t)); i != -1 {
+		t.Errorf("expected no divergence, got %d", i)
+	}
+	if i := Compare(ops, newMAC(t), &brokenMAC{Hash: newMAC(t)}); i != 5 {
+		t.Errorf("expected divergence at op 5, got %d", i)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		ops := record(t, keep)
+		var buf bytes.Buffer
+		if err := Encode(&buf, ops); err != nil {
+			t.Fatal(err)
+		}
+		dec, err := Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dec) != len(ops) {
+			t.Fatalf("expected %d ops, got %d", len(ops), len(dec))
+		}
+		for i := range ops {
+			if dec[i].Kind != ops[i].Kind || dec[i].Len != ops[i].Len ||
+				!bytes.Equal(dec[i].Digest, ops[i].Digest) || !bytes.Equal(dec[i].Data, ops[i].Data) {
+				t.Errorf("op %d: expected %+v got %+v", i, ops[i], dec[i])
+			}
+		}
+	}
+
+	if _, err := Decode(bytes.NewBufferString("X 1 00\n")); err == nil {
+		t.Error("expected error for unknown op")
+	}
+}
@@ -0,0 +1,180 @@
+// Package scp03 implements the AES-CMAC parts of GlobalPlatform Secure
+// Channel Protocol 03 (GlobalPlatform Card Specification Amendment D): the
+// session key and cryptogram derivation, and C-MAC and R-MAC chaining
+// over short command and response APDUs.
+//
+// Command and response data encryption with S-ENC is not implemented.
+package scp03
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// Derivation constants of Amendment D section 4.1.5.
+const (
+	CardCryptogram  = 0x00
+	HostCryptogram  = 0x01
+	CardChallenge   = 0x02
+	SessionENC      = 0x04
+	SessionMAC      = 0x06
+	SessionRMAC     = 0x07
+	ChallengeLength = 8
+)
+
+// MACSize is the length of a C-MAC or R-MAC in bytes.
+const MACSize = 8
+
+// KDF derives bits bits of output from key with the SP800-108 counter mode
+// KDF of Amendment D section 4.1.5. The derivation data of each block is
+//
+//	00*11 || constant || 00 || [bits]16 || [i]8 || context
+//
+// with the counter i starting at 1.
+func KDF(key []byte, constant byte, context []byte, bits int) ([]byte, error) {
+	if bits <= 0 || bits%8 != 0 || bits > 0xffff {
+		return nil, errors.New("scp03: invalid derived length")
+	}
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	n := bits / 8
+	if (n+h.Size()-1)/h.Size() > 0xff {
+		return nil, errors.New("scp03: invalid derived length")
+	}
+
+	var label [16]byte
+	label[11] = constant
+	binary.BigEndian.PutUint16(label[13:], uint16(bits))
+	out := make([]byte, 0, n+h.Size())
+	for i := 1; len(out) < n; i++ {
+		label[15] = byte(i)
+		h.Reset()
+		h.Write(label[:])
+		h.Write(context)
+		out = h.Sum(out)
+	}
+	return out[:n], nil
+}
+
+// SessionKeys are the keys of a secure channel session.
+type SessionKeys struct {
+	ENC, MAC, RMAC []byte
+}
+
+// DeriveSessionKeys derives the session keys from the static ENC and MAC
+// keys and the challenges exchanged by INITIALIZE UPDATE. Session keys
+// have the size of the static keys.
+func DeriveSessionKeys(enc, mac, hostChallenge, cardChallenge []byte) (*SessionKeys, error) {
+	context := challenges(hostChallenge, cardChallenge)
+	var k SessionKeys
+	var err error
+	if k.ENC, err = KDF(enc, SessionENC, context, len(enc)*8); err != nil {
+		return nil, err
+	}
+	if k.MAC, err = KDF(mac, SessionMAC, context, len(mac)*8); err != nil {
+		return nil, err
+	}
+	if k.RMAC, err = KDF(mac, SessionRMAC, context, len(mac)*8); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func challenges(host, card []byte) []byte {
+	return append(append(make([]byte, 0, len(host)+len(card)), host...), card...)
+}
+
+// Cryptogram returns the 8-byte card or host cryptogram, selected by
+// constant, computed with the S-MAC key.
+func Cryptogram(smac []byte, constant byte, hostChallenge, cardChallenge []byte) ([]byte, error) {
+	if constant != CardCryptogram && constant != HostCryptogram {
+		return nil, errors.New("scp03: invalid cryptogram constant")
+	}
+	return KDF(smac, constant, challenges(hostChallenge, cardChallenge), 64)
+}
+
+// Session keeps the MAC chaining value of a secure channel, so commands
+// must be wrapped and responses verified in the order they are exchanged.
+type Session struct {
+	cmac, rmac hash.Hash
+	chain      [16]byte
+}
+
+// NewSession returns a Session with an all-zero MAC chaining value, as
+// after EXTERNAL AUTHENTICATE.
+func NewSession(k *SessionKeys) (*Session, error) {
+	c, err := cmac.New(k.MAC)
+	if err != nil {
+		return nil, err
+	}
+	r, err := cmac.New(k.RMAC)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{cmac: c, rmac: r}, nil
+}
+
+// WrapCommand returns apdu, a short command APDU of case 1 to 4, with the
+// secure messaging bit set in its class byte, its Lc increased and the
+// C-MAC appended to its data. The C-MAC is computed over the MAC chaining
+// value and the modified header and data, excluding Le, and becomes the
+// new chaining value.
+func (s *Session) WrapCommand(apdu []byte) ([]byte, error) {
+	if len(apdu) < 4 {
+		return nil, errors.New("scp03: command APDU too short")
+	}
+	var data, le []byte
+	switch {
+	case len(apdu) == 4:
+	case len(apdu) == 5:
+		le = apdu[4:]
+	case apdu[4] != 0 && len(apdu) == 5+int(apdu[4]):
+		data = apdu[5:]
+	case apdu[4] != 0 && len(apdu) == 6+int(apdu[4]):
+		data, le = apdu[5:len(apdu)-1], apdu[len(apdu)-1:]
+	default:
+		return nil, errors.New("scp03: malformed or extended command APDU")
+	}
+	if len(data)+MACSize > 0xff {
+		return nil, errors.New("scp03: command data too long")
+	}
+
+	out := make([]byte, 0, 5+len(data)+MACSize+len(le))
+	out = append(out, apdu[0]|0x04, apdu[1], apdu[2], apdu[3], byte(len(data)+MACSize))
+	out = append(out, data...)
+
+	s.cmac.Reset()
+	s.cmac.Write(s.chain[:])
+	s.cmac.Write(out)
+	s.cmac.Sum(s.chain[:0])
+
+	out = append(out, s.chain[:MACSize]...)
+	return append(out, le...), nil
+}
+
+// VerifyResponse checks the R-MAC of resp, a response APDU made of data,
+// the R-MAC and the status word, against the current MAC chaining value.
+// It returns the response with the R-MAC removed.
+func (s *Session) VerifyResponse(resp []byte) ([]byte, error) {
+	if len(resp) < MACSize+2 {
+		return nil, errors.New("scp03: response APDU too short")
+	}
+	data := resp[:len(resp)-MACSize-2]
+	mac := resp[len(resp)-MACSize-2 : len(resp)-2]
+	sw := resp[len(resp)-2:]
+
+	s.rmac.Reset()
+	s.rmac.Write(s.chain[:])
+	s.rmac.Write(data)
+	s.rmac.Write(sw)
+	if subtle.ConstantTimeCompare(s.rmac.Sum(nil)[:MACSize], mac) != 1 {
+		return nil, errors.New("scp03: invalid R-MAC")
+	}
+	return append(append(make([]byte, 0, len(data)+2), data...), sw...), nil
+}
@@ -0,0 +1,112 @@
+package scp03
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	kenc = unhex("404142434445464748494a4b4c4d4e4f")
+	kmac = unhex("505152535455565758595a5b5c5d5e5f")
+	host = unhex("0011223344556677")
+	card = unhex("8899aabbccddeeff")
+)
+
+func TestKDF(t *testing.T) {
+	// A 256-bit output takes two blocks with counters 1 and 2.
+	out, err := KDF(kmac, SessionMAC, append(host, card...), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []byte
+	for i := byte(1); i <= 2; i++ {
+		h, _ := cmac.New(kmac)
+		h.Write(unhex("0000000000000000000000060001000" + string("0123456789"[i])))
+		h.Write(host)
+		h.Write(card)
+		expected = h.Sum(expected)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %x got %x", expected, out)
+	}
+
+	if _, err := KDF(kmac, SessionMAC, nil, 12); err == nil {
+		t.Error("expected error for partial byte length")
+	}
+}
+
+func TestSession(t *testing.T) {
+	keys, err := DeriveSessionKeys(kenc, kmac, host, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := KDF(kmac, SessionMAC, append(host, card...), 128); !bytes.Equal(keys.MAC, m) {
+		t.Error("S-MAC mismatch")
+	}
+	cc, _ := Cryptogram(keys.MAC, CardCryptogram, host, card)
+	hc, _ := Cryptogram(keys.MAC, HostCryptogram, host, card)
+	if len(cc) != 8 || len(hc) != 8 || bytes.Equal(cc, hc) {
+		t.Errorf("unexpected cryptograms %x and %x", cc, hc)
+	}
+
+	s, err := NewSession(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// EXTERNAL AUTHENTICATE with the host cryptogram.
+	apdu := append([]byte{0x80, 0x82, 0x01, 0x00, 0x08}, hc...)
+	wrapped, err := s.WrapCommand(apdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := cmac.New(keys.MAC)
+	h.Write(make([]byte, 16))
+	h.Write(append([]byte{0x84, 0x82, 0x01, 0x00, 0x10}, hc...))
+	chain := h.Sum(nil)
+	if expected := append(append([]byte{0x84, 0x82, 0x01, 0x00, 0x10}, hc...), chain[:8]...); !bytes.Equal(wrapped, expected) {
+		t.Errorf("expected %x got %x", expected, wrapped)
+	}
+
+	// The next command chains from the previous C-MAC and keeps Le.
+	wrapped, _ = s.WrapCommand([]byte{0x80, 0xca, 0x00, 0x66, 0x00})
+	h.Reset()
+	h.Write(chain)
+	h.Write([]byte{0x84, 0xca, 0x00, 0x66, 0x08})
+	chain = h.Sum(nil)
+	if expected := append(append([]byte{0x84, 0xca, 0x00, 0x66, 0x08}, chain[:8]...), 0x00); !bytes.Equal(wrapped, expected) {
+		t.Errorf("expected %x got %x", expected, wrapped)
+	}
+
+	// The response R-MAC is over the same chaining value.
+	r, _ := cmac.New(keys.RMAC)
+	r.Write(chain)
+	r.Write([]byte{0x66, 0x01, 0x90, 0x00})
+	resp := append(append([]byte{0x66, 0x01}, r.Sum(nil)[:8]...), 0x90, 0x00)
+	out, err := s.VerifyResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, []byte{0x66, 0x01, 0x90, 0x00}) {
+		t.Errorf("unexpected response %x", out)
+	}
+	resp[0] ^= 1
+	if _, err := s.VerifyResponse(resp); err == nil {
+		t.Error("modified response accepted")
+	}
+
+	if _, err := s.WrapCommand([]byte{0x80, 0xca, 0x00, 0x66, 0x05, 0x01}); err == nil {
+		t.Error("expected error for malformed APDU")
+	}
+}
@@ -0,0 +1,206 @@
+// Package secoc implements the CMAC based authentication of AUTOSAR Secure
+// Onboard Communication (SecOC): building the data to authenticate from
+// the data ID, the authentic I-PDU and the freshness value, packing the
+// truncated freshness value and MAC into the secured I-PDU, and
+// reconstructing the complete freshness value on the receiving side.
+package secoc
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"github.com/joekir/cmac"
+)
+
+// Errors returned by Verifier.Verify.
+var (
+	ErrInvalidMAC = errors.New("secoc: invalid MAC")
+	ErrFreshness  = errors.New("secoc: freshness value not newer than the last accepted one")
+)
+
+// Profile is a SecOC profile: the lengths in bits of the complete freshness
+// value, of its truncated form sent in the secured I-PDU, and of the
+// truncated MAC.
+type Profile struct {
+	FreshnessBits          int
+	TruncatedFreshnessBits int
+	MACBits                int
+}
+
+// Profiles of the AUTOSAR SecOC specification. The length of the complete
+// freshness value is left to the freshness value manager; these use 64
+// bits, and profile 2 uses none.
+var (
+	// Profile1 is "24Bit-CMAC-8BitFV".
+	Profile1 = Profile{FreshnessBits: 64, TruncatedFreshnessBits: 8, MACBits: 24}
+	// Profile2 is "24Bit-CMAC-No-FV".
+	Profile2 = Profile{MACBits: 24}
+	// Profile3 is "JASPAR", with a 4-bit truncated freshness value and a
+	// 28-bit MAC.
+	Profile3 = Profile{FreshnessBits: 64, TruncatedFreshnessBits: 4, MACBits: 28}
+)
+
+func (p Profile) check() error {
+	switch {
+	case p.FreshnessBits < 0 || p.FreshnessBits > 64 || p.FreshnessBits%8 != 0:
+		return errors.New("secoc: freshness value length must be a multiple of 8 up to 64 bits")
+	case p.TruncatedFreshnessBits < 0 || p.TruncatedFreshnessBits > p.FreshnessBits:
+		return errors.New("secoc: invalid truncated freshness value length")
+	case p.MACBits < 1 || p.MACBits > 128:
+		return errors.New("secoc: invalid MAC length")
+	}
+	return cmac.CheckTagSize(p.MACBits / 8)
+}
+
+// trailerSize is the number of bytes the truncated freshness value and
+// MAC take at the end of a secured I-PDU.
+func (p Profile) trailerSize() int {
+	return (p.TruncatedFreshnessBits + p.MACBits + 7) / 8
+}
+
+func (p Profile) mask() uint64 {
+	if p.FreshnessBits == 64 {
+		return ^uint64(0)
+	}
+	return 1<<uint(p.FreshnessBits) - 1
+}
+
+func (p Profile) truncMask() uint64 {
+	if p.TruncatedFreshnessBits == 64 {
+		return ^uint64(0)
+	}
+	return 1<<uint(p.TruncatedFreshnessBits) - 1
+}
+
+// authenticator computes the full MAC over Data ID || authentic I-PDU ||
+// complete freshness value, each most significant byte first.
+func (p Profile) authenticator(h hash.Hash, dataID uint16, pdu []byte, fv uint64) []byte {
+	var buf [10]byte
+	binary.BigEndian.PutUint16(buf[:], dataID)
+	binary.BigEndian.PutUint64(buf[2:], fv)
+	h.Reset()
+	h.Write(buf[:2])
+	h.Write(pdu)
+	h.Write(buf[10-p.FreshnessBits/8:])
+	return h.Sum(nil)
+}
+
+// trailer packs the truncated freshness value and the first MACBits bits
+// of mac, most significant bit first, padding with zero bits.
+func (p Profile) trailer(fv uint64, mac []byte) []byte {
+	t := make([]byte, p.trailerSize())
+	pos := 0
+	put := func(bit byte) {
+		t[pos/8] |= bit << (7 - uint(pos%8))
+		pos++
+	}
+	for i := p.TruncatedFreshnessBits - 1; i >= 0; i-- {
+		put(byte(fv >> uint(i) & 1))
+	}
+	for i := 0; i < p.MACBits; i++ {
+		put(mac[i/8] >> (7 - uint(i%8)) & 1)
+	}
+	return t
+}
+
+// Signer produces secured I-PDUs for one data ID.
+type Signer struct {
+	p      Profile
+	h      hash.Hash
+	dataID uint16
+}
+
+// NewSigner returns a Signer using AES-CMAC with key.
+func NewSigner(key []byte, p Profile, dataID uint16) (*Signer, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{p: p, h: h, dataID: dataID}, nil
+}
+
+// Secure returns the secured I-PDU for pdu with the complete freshness
+// value fv: pdu followed by the truncated freshness value and MAC.
+func (s *Signer) Secure(pdu []byte, fv uint64) ([]byte, error) {
+	if fv&^s.p.mask() != 0 {
+		return nil, errors.New("secoc: freshness value too long for profile")
+	}
+	mac := s.p.authenticator(s.h, s.dataID, pdu, fv)
+	return append(append([]byte(nil), pdu...), s.p.trailer(fv, mac)...), nil
+}
+
+// Verifier checks secured I-PDUs for one data ID and keeps the last
+// accepted freshness value. It is not safe for concurrent use.
+type Verifier struct {
+	p      Profile
+	h      hash.Hash
+	dataID uint16
+	latest uint64
+}
+
+// NewVerifier returns a Verifier using AES-CMAC with key, which accepts
+// freshness values greater than latest.
+func NewVerifier(key []byte, p Profile, dataID uint16, latest uint64) (*Verifier, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{p: p, h: h, dataID: dataID, latest: latest}, nil
+}
+
+// Latest returns the last accepted complete freshness value.
+func (v *Verifier) Latest() uint64 {
+	return v.latest
+}
+
+// Freshness reconstructs the complete freshness value from its truncated
+// form as SecOC does: the truncated bits replace the low bits of the last
+// accepted value, and the high part is incremented if that would not move
+// the value forward.
+func (v *Verifier) Freshness(truncated uint64) uint64 {
+	p := v.p
+	if p.TruncatedFreshnessBits == p.FreshnessBits {
+		return truncated
+	}
+	tm := p.truncMask()
+	fv := v.latest&^tm | truncated
+	if truncated <= v.latest&tm {
+		fv += tm + 1
+	}
+	return fv & p.mask()
+}
+
+// Verify checks a secured I-PDU and returns its authentic I-PDU. On
+// success the reconstructed freshness value becomes the latest one.
+func (v *Verifier) Verify(secured []byte) ([]byte, error) {
+	p := v.p
+	n := len(secured) - p.trailerSize()
+	if n < 0 {
+		return nil, errors.New("secoc: secured I-PDU too short")
+	}
+	pdu, trailer := secured[:n], secured[n:]
+
+	var truncated uint64
+	for i := 0; i < p.TruncatedFreshnessBits; i++ {
+		truncated = truncated<<1 | uint64(trailer[i/8]>>(7-uint(i%8))&1)
+	}
+	fv := v.Freshness(truncated)
+	if p.FreshnessBits > 0 && fv <= v.latest {
+		return nil, ErrFreshness
+	}
+
+	expected := p.trailer(fv, p.authenticator(v.h, v.dataID, pdu, fv))
+	if subtle.ConstantTimeCompare(expected, trailer) != 1 {
+		return nil, ErrInvalidMAC
+	}
+	v.latest = fv
+	return pdu, nil
+}
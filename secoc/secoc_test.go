@@ -0,0 +1,115 @@
+package secoc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var key = unhex("2b7e151628aed2a6abf7158809cf4f3c")
+
+func TestSecure(t *testing.T) {
+	pdu := unhex("0102030405")
+	tag, _ := cmac.Sum(key, unhex("0123"+"0102030405"+"00000000000001ab"))
+
+	s, err := NewSigner(key, Profile1, 0x0123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secured, _ := s.Secure(pdu, 0x1ab)
+	expected := append(append(pdu, 0xab), tag[:3]...)
+	if !bytes.Equal(secured, expected) {
+		t.Errorf("profile 1: expected %x got %x", expected, secured)
+	}
+
+	// Profile 3 packs 4 freshness bits and 28 MAC bits into 4 bytes.
+	s, _ = NewSigner(key, Profile3, 0x0123)
+	secured, _ = s.Secure(pdu, 0x1ab)
+	trailer := uint32(0xb)<<28 | (uint32(tag[0])<<24|uint32(tag[1])<<16|uint32(tag[2])<<8|uint32(tag[3]))>>4
+	expected = append(append([]byte(nil), pdu...), byte(trailer>>24), byte(trailer>>16), byte(trailer>>8), byte(trailer))
+	if !bytes.Equal(secured, expected) {
+		t.Errorf("profile 3: expected %x got %x", expected, secured)
+	}
+
+	// Profile 2 authenticates Data ID and payload only.
+	s, _ = NewSigner(key, Profile2, 0x0123)
+	secured, _ = s.Secure(pdu, 0)
+	tag, _ = cmac.Sum(key, unhex("0123"+"0102030405"))
+	if expected := append(append([]byte(nil), pdu...), tag[:3]...); !bytes.Equal(secured, expected) {
+		t.Errorf("profile 2: expected %x got %x", expected, secured)
+	}
+
+	if _, err := NewSigner(key, Profile{FreshnessBits: 12, MACBits: 24}, 1); err == nil {
+		t.Error("expected error for unaligned freshness value length")
+	}
+
+	// The MAC length is subject to the package Policy of cmac.
+	cmac.SetPolicy(&cmac.Policy{MinTagSize: 4})
+	defer cmac.SetPolicy(nil)
+	if _, err := NewSigner(key, Profile3, 1); err == nil {
+		t.Error("28-bit MAC accepted under a 32-bit minimum")
+	}
+	if _, err := NewVerifier(key, Profile3, 1, 0); err == nil {
+		t.Error("28-bit MAC accepted by NewVerifier under a 32-bit minimum")
+	}
+	if _, err := NewSigner(key, Profile{FreshnessBits: 64, TruncatedFreshnessBits: 8, MACBits: 32}, 1); err != nil {
+		t.Errorf("32-bit MAC: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, p := range []Profile{Profile1, Profile3} {
+		s, _ := NewSigner(key, p, 7)
+		v, _ := NewVerifier(key, p, 7, 0x0ff0)
+
+		// Values crossing the truncated part's wrap-around are
+		// reconstructed from the last accepted one.
+		for _, fv := range []uint64{0x0ff1, 0x0ffe, 0x1003, 0x1005} {
+			secured, _ := s.Secure([]byte("payload"), fv)
+			pdu, err := v.Verify(secured)
+			if err != nil {
+				t.Fatalf("%+v fv %x: %v", p, fv, err)
+			}
+			if string(pdu) != "payload" || v.Latest() != fv {
+				t.Errorf("%+v fv %x: got %q, latest %x", p, fv, pdu, v.Latest())
+			}
+		}
+
+		secured, _ := s.Secure([]byte("payload"), 0x1005)
+		if _, err := v.Verify(secured); err == nil {
+			t.Errorf("%+v: replay accepted", p)
+		}
+		secured, _ = s.Secure([]byte("payload"), 0x1006)
+		secured[0] ^= 1
+		if _, err := v.Verify(secured); err != ErrInvalidMAC {
+			t.Errorf("%+v: expected ErrInvalidMAC, got %v", p, err)
+		}
+		if v.Latest() != 0x1005 {
+			t.Errorf("%+v: latest moved on failure", p)
+		}
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	v, _ := NewVerifier(key, Profile1, 7, 0x12345)
+	for _, tt := range []struct{ truncated, fv uint64 }{
+		{0x46, 0x12346},
+		{0xff, 0x123ff},
+		{0x45, 0x12445},
+		{0x00, 0x12400},
+	} {
+		if fv := v.Freshness(tt.truncated); fv != tt.fv {
+			t.Errorf("truncated %x: expected %x got %x", tt.truncated, tt.fv, fv)
+		}
+	}
+}
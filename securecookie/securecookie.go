@@ -0,0 +1,188 @@
+// Package securecookie encodes and decodes authenticated cookie values
+// using AES-CMAC, optionally encrypting them with AES-SIV.
+//
+// An encoded value is the base64url encoding of an 8-byte big-endian
+// creation timestamp, the value and a 16-byte tag binding both to the
+// cookie name. With encryption, the value is sealed with AES-SIV using the
+// name and timestamp as additional data instead, and its synthetic IV
+// serves as the tag.
+//
+// Keys can be rotated by passing several: the first one encodes, and all
+// of them are tried when decoding.
+package securecookie
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"net/http"
+	"time"
+
+	"github.com/joekir/cmac"
+)
+
+// Errors returned when decoding.
+var (
+	ErrInvalid = errors.New("securecookie: invalid or unauthenticated value")
+	ErrExpired = errors.New("securecookie: value expired")
+)
+
+const (
+	tsSize  = 8
+	tagSize = 16
+)
+
+type codecKey struct {
+	mac hash.Hash
+	siv cipher.AEAD
+}
+
+// Codec encodes and decodes cookie values. A Codec is not safe for
+// concurrent use; create one per goroutine or guard it with a mutex.
+type Codec struct {
+	keys    []codecKey
+	encrypt bool
+	maxAge  time.Duration
+	now     func() time.Time
+}
+
+// Options configures a Codec.
+type Options struct {
+	// Encrypt additionally encrypts values with AES-SIV.
+	Encrypt bool
+	// MaxAge, if positive, rejects values created longer ago.
+	MaxAge time.Duration
+}
+
+// New returns a Codec using the given AES keys, newest first. Separate
+// CMAC and SIV keys are derived from each of them.
+func New(opts Options, keys ...[]byte) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("securecookie: no keys")
+	}
+
+	c := &Codec{encrypt: opts.Encrypt, maxAge: opts.MaxAge, now: time.Now}
+	for _, k := range keys {
+		h, err := cmac.NewKeyHierarchy(k)
+		if err != nil {
+			return nil, err
+		}
+
+		var ck codecKey
+		if opts.Encrypt {
+			s2v, err := h.Key("securecookie/siv/s2v")
+			if err != nil {
+				return nil, err
+			}
+			ctr, err := h.Key("securecookie/siv/ctr")
+			if err != nil {
+				return nil, err
+			}
+			if ck.siv, err = cmac.NewSIV(append(s2v, ctr...), 0); err != nil {
+				return nil, err
+			}
+		} else {
+			mk, err := h.Key("securecookie/cmac")
+			if err != nil {
+				return nil, err
+			}
+			if ck.mac, err = cmac.New(mk); err != nil {
+				return nil, err
+			}
+		}
+		c.keys = append(c.keys, ck)
+	}
+	return c, nil
+}
+
+func (k *codecKey) tag(name string, body []byte) []byte {
+	k.mac.Reset()
+	k.mac.Write([]byte(name))
+	k.mac.Write([]byte{0})
+	k.mac.Write(body)
+	return k.mac.Sum(nil)
+}
+
+func ad(name string, ts []byte) []byte {
+	return append(append([]byte(name), 0), ts...)
+}
+
+// Encode returns the encoded form of the value of cookie name.
+func (c *Codec) Encode(name string, value []byte) (string, error) {
+	k := &c.keys[0]
+	ts := make([]byte, tsSize)
+	binary.BigEndian.PutUint64(ts, uint64(c.now().Unix()))
+
+	var out []byte
+	if c.encrypt {
+		out = k.siv.Seal(ts, nil, value, ad(name, ts))
+	} else {
+		out = append(ts, value...)
+		out = append(out, k.tag(name, out)...)
+	}
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Decode authenticates an encoded value of cookie name and returns the
+// original value.
+func (c *Codec) Decode(name, encoded string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(b) < tsSize+tagSize {
+		return nil, ErrInvalid
+	}
+	ts := b[:tsSize]
+
+	var value []byte
+	ok := false
+	for i := range c.keys {
+		k := &c.keys[i]
+		if c.encrypt {
+			if v, err := k.siv.Open(nil, nil, b[tsSize:], ad(name, ts)); err == nil {
+				value, ok = v, true
+				break
+			}
+		} else {
+			n := len(b) - tagSize
+			if subtle.ConstantTimeCompare(k.tag(name, b[:n]), b[n:]) == 1 {
+				value, ok = b[tsSize:n], true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	if c.maxAge > 0 {
+		created := time.Unix(int64(binary.BigEndian.Uint64(ts)), 0)
+		if c.now().Sub(created) > c.maxAge {
+			return nil, ErrExpired
+		}
+	}
+	return value, nil
+}
+
+// SetCookie encodes value into cookie and adds it to w. All other cookie
+// attributes are used as given.
+func (c *Codec) SetCookie(w http.ResponseWriter, cookie *http.Cookie, value []byte) error {
+	v, err := c.Encode(cookie.Name, value)
+	if err != nil {
+		return err
+	}
+	ck := *cookie
+	ck.Value = v
+	http.SetCookie(w, &ck)
+	return nil
+}
+
+// Value decodes the named cookie of r.
+func (c *Codec) Value(r *http.Request, name string) ([]byte, error) {
+	ck, err := r.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decode(name, ck.Value)
+}
@@ -0,0 +1,110 @@
+package securecookie
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 16)
+	newKey = bytes.Repeat([]byte{2}, 16)
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		c, err := New(Options{Encrypt: encrypt}, newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		enc, err := c.Encode("session", []byte("user=42"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(enc, "=") {
+			t.Errorf("encoded value is padded: %s", enc)
+		}
+		v, err := c.Decode("session", enc)
+		if err != nil || string(v) != "user=42" {
+			t.Errorf("encrypt=%v: got %q, %v", encrypt, v, err)
+		}
+
+		if _, err := c.Decode("other", enc); err != ErrInvalid {
+			t.Errorf("encrypt=%v: wrong name: expected %v got %v", encrypt, ErrInvalid, err)
+		}
+		tampered := []byte(enc)
+		tampered[12] ^= 1
+		if _, err := c.Decode("session", string(tampered)); err != ErrInvalid {
+			t.Errorf("encrypt=%v: tampered: expected %v got %v", encrypt, ErrInvalid, err)
+		}
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		c, _ := New(Options{Encrypt: encrypt}, newKey)
+		enc, _ := c.Encode("session", []byte("secret-value"))
+		raw, _ := base64.RawURLEncoding.DecodeString(enc)
+		if bytes.Contains(raw, []byte("secret-value")) == encrypt {
+			t.Errorf("encrypt=%v: plaintext visible: %v", encrypt, !encrypt)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, _ := New(Options{}, oldKey)
+	enc, _ := old.Encode("session", []byte("v"))
+
+	rotated, err := New(Options{}, newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := rotated.Decode("session", enc); err != nil || string(v) != "v" {
+		t.Errorf("old key: got %q, %v", v, err)
+	}
+
+	fresh, _ := rotated.Encode("session", []byte("v"))
+	if _, err := old.Decode("session", fresh); err != ErrInvalid {
+		t.Errorf("new value decoded with old key only: %v", err)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	c, _ := New(Options{MaxAge: time.Hour}, newKey)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	enc, _ := c.Encode("session", []byte("v"))
+	now = now.Add(59 * time.Minute)
+	if _, err := c.Decode("session", enc); err != nil {
+		t.Errorf("fresh value: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Decode("session", enc); err != ErrExpired {
+		t.Errorf("expected %v got %v", ErrExpired, err)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	c, _ := New(Options{}, newKey)
+	w := httptest.NewRecorder()
+	if err := c.SetCookie(w, &http.Cookie{Name: "session", HttpOnly: true}, []byte("user=42")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, ck := range w.Result().Cookies() {
+		r.AddCookie(ck)
+	}
+	if v, err := c.Value(r, "session"); err != nil || string(v) != "user=42" {
+		t.Errorf("got %q, %v", v, err)
+	}
+	if _, err := c.Value(r, "missing"); err == nil {
+		t.Error("expected error for missing cookie")
+	}
+}
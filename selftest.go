@@ -0,0 +1,40 @@
+package cmac
+
+import "fmt"
+
+// SelfTest runs the cryptographic algorithm self-test of the package: a
+// known-answer test of CMAC with AES-128, AES-192, AES-256 and three-key
+// and two-key TDEA, using the examples of SP800-38B appendix D. It returns
+// an error naming the first configuration that does not produce the
+// expected tag, and nil if all of them do.
+//
+// SelfTest is meant to be called at startup or on demand by products
+// that need a callable self-test under FIPS 140-3 or Common Criteria. Unlike RunCompliance
+// it ignores the Policy, since the algorithm is tested rather than the
+// configuration.
+func SelfTest() error {
+	return selfTest(sp800vectors)
+}
+
+func selfTest(vectors []sp800vector) error {
+	msg := complianceHex(sp800msg)
+	for _, v := range vectors {
+		c, err := v.cipher(complianceHex(v.key))
+		if err != nil {
+			return fmt.Errorf("cmac: self test %s: %v", v.name, err)
+		}
+		var s State
+		if err := s.Init(c); err != nil {
+			return fmt.Errorf("cmac: self test %s: %v", v.name, err)
+		}
+		var t [16]byte
+		for i, n := range v.lens {
+			s.Reset()
+			s.Write(msg[:n])
+			if err := expectBytes(s.Sum(t[:0]), complianceHex(v.macs[i])); err != nil {
+				return fmt.Errorf("cmac: self test %s with %d-byte message failed: %v", v.name, n, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package cmac
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The self test ignores the policy.
+	SetPolicy(&Policy{BlockSizes: []int{16}})
+	defer SetPolicy(nil)
+	if err := SelfTest(); err != nil {
+		t.Errorf("under a restrictive policy: %v", err)
+	}
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	bad := append([]sp800vector(nil), sp800vectors...)
+	bad[3].macs = append([]string(nil), bad[3].macs...)
+	bad[3].macs[2] = "743ddbe0ce2dc2ee"
+	err := selfTest(bad)
+	if err == nil || !strings.Contains(err.Error(), "TDEA-3 with 20-byte message") {
+		t.Errorf("got %v", err)
+	}
+}
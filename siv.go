@@ -0,0 +1,243 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// siv implements AES-SIV as defined in RFC 5297.
+type siv struct {
+	mac       cipher.Block
+	ctr       cipher.Block
+	nonceSize int
+}
+
+// SIV is a cipher.AEAD implementing AES-SIV that can also authenticate a
+// vector of additional data strings, as RFC 5297 allows, rather than the
+// single one of cipher.AEAD.
+type SIV interface {
+	cipher.AEAD
+
+	// SealVector is like Seal but authenticates each element of
+	// additionalData as a separate S2V component, in order and before
+	// the nonce. An empty vector authenticates no additional data at
+	// all, which differs from Seal with empty additional data. It panics
+	// if there are more than MaxSIVComponents elements.
+	SealVector(dst, nonce, plaintext []byte, additionalData [][]byte) []byte
+
+	// OpenVector opens a ciphertext sealed by SealVector with the same
+	// additional data vector.
+	OpenVector(dst, nonce, ciphertext []byte, additionalData [][]byte) ([]byte, error)
+}
+
+// MaxSIVComponents is the largest number of additional data strings that
+// SealVector and OpenVector accept, counting the nonce if there is one.
+// RFC 5297 limits S2V to 127 components including the plaintext.
+const MaxSIVComponents = 126
+
+// NewSIV returns an SIV implementing AES-SIV (RFC 5297) with the
+// given key, which must be 32, 48 or 64 bytes long: the first half keys
+// S2V and the second half keys CTR mode. With a nonceSize of 0 the AEAD is
+// deterministic; otherwise the nonce is authenticated as the last header
+// component before the plaintext. The additional data is always
+// authenticated as the first component, even if empty.
+func NewSIV(key []byte, nonceSize int) (SIV, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, errors.New("cmac: invalid SIV key size")
+	}
+	if nonceSize < 0 {
+		return nil, errors.New("cmac: invalid SIV nonce size")
+	}
+
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &siv{mac: mac, ctr: ctr, nonceSize: nonceSize}, nil
+}
+
+func (s *siv) NonceSize() int { return s.nonceSize }
+func (s *siv) Overhead() int  { return aes.BlockSize }
+
+func (s *siv) components(nonce, additionalData []byte) [][]byte {
+	if len(nonce) != s.nonceSize {
+		panic("cmac: incorrect nonce length given to SIV")
+	}
+	if s.nonceSize == 0 {
+		return [][]byte{additionalData}
+	}
+	return [][]byte{additionalData, nonce}
+}
+
+func (s *siv) vectorComponents(nonce []byte, additionalData [][]byte) ([][]byte, bool) {
+	if len(nonce) != s.nonceSize {
+		panic("cmac: incorrect nonce length given to SIV")
+	}
+	header := additionalData
+	if s.nonceSize > 0 {
+		header = append(append(make([][]byte, 0, len(additionalData)+1), additionalData...), nonce)
+	}
+	return header, len(header) <= MaxSIVComponents
+}
+
+func (s *siv) SealVector(dst, nonce, plaintext []byte, additionalData [][]byte) []byte {
+	header, ok := s.vectorComponents(nonce, additionalData)
+	if !ok {
+		panic("cmac: too many SIV additional data components")
+	}
+	return s.seal(dst, plaintext, header)
+}
+
+func (s *siv) OpenVector(dst, nonce, ciphertext []byte, additionalData [][]byte) ([]byte, error) {
+	header, ok := s.vectorComponents(nonce, additionalData)
+	if !ok {
+		return nil, errors.New("cmac: too many SIV additional data components")
+	}
+	return s.open(dst, ciphertext, header)
+}
+
+func (s *siv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return s.seal(dst, plaintext, s.components(nonce, additionalData))
+}
+
+func (s *siv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return s.open(dst, ciphertext, s.components(nonce, additionalData))
+}
+
+func (s *siv) seal(dst, plaintext []byte, header [][]byte) []byte {
+	v := s2v(s.mac, header, plaintext)
+
+	// Move the plaintext into place first, so that encrypting it in place
+	// works when dst is plaintext[:0], and write the IV last.
+	ret, out := sliceForAppend(dst, len(v)+len(plaintext))
+	copy(out[len(v):], plaintext)
+	s.xorCTR(out[len(v):], out[len(v):], v[:])
+	copy(out, v[:])
+	return ret
+}
+
+func (s *siv) open(dst, ciphertext []byte, header [][]byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errors.New("cmac: SIV message authentication failed")
+	}
+	// Save the IV before out, which may be ciphertext[:0], overwrites it.
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext)
+	ciphertext = ciphertext[aes.BlockSize:]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	copy(out, ciphertext)
+	s.xorCTR(out, out, v[:])
+
+	expected := s2v(s.mac, header, out)
+	if subtle.ConstantTimeCompare(expected[:], v[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errors.New("cmac: SIV message authentication failed")
+	}
+	return ret, nil
+}
+
+// xorCTR XORs src with the CTR keystream for the synthetic IV v, after
+// clearing bits 31 and 63 of the counter as RFC 5297 requires.
+func (s *siv) xorCTR(dst, src, v []byte) {
+	var q [aes.BlockSize]byte
+	copy(q[:], v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(s.ctr, q[:]).XORKeyStream(dst, src)
+}
+
+// S2V computes the S2V construction of RFC 5297 section 2.4 over the given
+// strings with an AES-CMAC key of 16, 24 or 32 bytes. The last string is
+// treated as the final component, like the plaintext in SIV. At most
+// MaxSIVComponents+1 strings are allowed.
+func S2V(key []byte, vectors ...[]byte) ([16]byte, error) {
+	if len(vectors) > MaxSIVComponents+1 {
+		return [16]byte{}, errors.New("cmac: too many S2V components")
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	if len(vectors) == 0 {
+		var st State
+		st.Init(c)
+		one := [aes.BlockSize]byte{aes.BlockSize - 1: 1}
+		st.Write(one[:])
+		var v [aes.BlockSize]byte
+		st.Sum(v[:0])
+		return v, nil
+	}
+	n := len(vectors) - 1
+	return s2v(c, vectors[:n], vectors[n]), nil
+}
+
+// s2v computes S2V over the header components followed by the final
+// component last, using c as the CMAC cipher.
+func s2v(c cipher.Block, header [][]byte, last []byte) [aes.BlockSize]byte {
+	var st State
+	st.Init(c)
+
+	var d, t [aes.BlockSize]byte
+	st.Write(d[:])
+	st.Sum(d[:0])
+
+	for _, h := range header {
+		dbl(d[:], d[:], _Rb128)
+		st.Reset()
+		st.Write(h)
+		st.Sum(t[:0])
+		for i := range d {
+			d[i] ^= t[i]
+		}
+	}
+
+	st.Reset()
+	if len(last) >= aes.BlockSize {
+		n := len(last) - aes.BlockSize
+		st.Write(last[:n])
+		for i := range t {
+			t[i] = last[n+i] ^ d[i]
+		}
+		st.Write(t[:])
+	} else {
+		dbl(d[:], d[:], _Rb128)
+		t = [aes.BlockSize]byte{}
+		copy(t[:], last)
+		t[len(last)] = 0x80
+		for i := range t {
+			t[i] ^= d[i]
+		}
+		st.Write(t[:])
+	}
+
+	var v [aes.BlockSize]byte
+	st.Sum(v[:0])
+	return v
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns
+// a slice with the contents of the given slice followed by that many bytes
+// and a second slice that aliases into it and contains only the extra
+// bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,164 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSIV(t *testing.T) {
+	// RFC 5297 appendix A.1.
+	key := unhex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad := unhex("101112131415161718191a1b1c1d1e1f2021222324252627")
+	pt := unhex("112233445566778899aabbccddee")
+	expected := unhex("85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	a, err := NewSIV(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := a.Seal(nil, nil, pt, ad)
+	if !bytes.Equal(ct, expected) {
+		t.Errorf("Seal: expected: %x got %x\n", expected, ct)
+	}
+
+	out, err := a.Open(nil, nil, ct, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, pt) {
+		t.Errorf("Open: expected: %x got %x\n", pt, out)
+	}
+
+	ct[len(ct)-1] ^= 1
+	if _, err := a.Open(nil, nil, ct, ad); err == nil {
+		t.Error("Open: modified ciphertext accepted")
+	}
+	if _, err := a.Open(nil, nil, expected, ad[1:]); err == nil {
+		t.Error("Open: modified additional data accepted")
+	}
+}
+
+func TestSIVInPlace(t *testing.T) {
+	for _, nonceSize := range []int{0, 12} {
+		a, _ := NewSIV(make([]byte, 32), nonceSize)
+		nonce := make([]byte, nonceSize)
+		for _, n := range []int{0, 1, 15, 16, 17, 100} {
+			pt := seq(n)
+			want := a.Seal(nil, nonce, pt, []byte("ad"))
+
+			buf := make([]byte, n, n+a.Overhead())
+			copy(buf, pt)
+			ct := a.Seal(buf[:0], nonce, buf, []byte("ad"))
+			if !bytes.Equal(ct, want) {
+				t.Errorf("nonce %d, len %d: in-place Seal: expected %x got %x", nonceSize, n, want, ct)
+			}
+			out, err := a.Open(ct[:0], nonce, ct, []byte("ad"))
+			if err != nil || !bytes.Equal(out, pt) {
+				t.Errorf("nonce %d, len %d: in-place Open: got %x, %v", nonceSize, n, out, err)
+			}
+		}
+	}
+}
+
+func TestSIVNonce(t *testing.T) {
+	a, err := NewSIV(make([]byte, 64), 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, 12)
+	for _, n := range []int{0, 1, 15, 16, 17, 100} {
+		pt := make([]byte, n)
+		ct := a.Seal(nil, nonce, pt, []byte("ad"))
+		if len(ct) != n+a.Overhead() {
+			t.Errorf("len %d: ciphertext length %d", n, len(ct))
+		}
+		out, err := a.Open(nil, nonce, ct, []byte("ad"))
+		if err != nil || !bytes.Equal(out, pt) {
+			t.Errorf("len %d: round trip failed: %v", n, err)
+		}
+
+		nonce[0]++
+		if _, err := a.Open(nil, nonce, ct, []byte("ad")); err == nil {
+			t.Errorf("len %d: wrong nonce accepted", n)
+		}
+	}
+
+	if _, err := NewSIV(make([]byte, 16), 0); err == nil {
+		t.Error("expected error for 16-byte key")
+	}
+}
+
+func TestSIVVector(t *testing.T) {
+	// RFC 5297 appendix A.2, with the nonce as the last header component.
+	key := unhex("7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f")
+	ad := [][]byte{
+		unhex("00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100"),
+		unhex("102030405060708090a0"),
+	}
+	nonce := unhex("09f911029d74e35bd84156c5635688c0")
+	pt := unhex("7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553")
+	expected := unhex("7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d")
+
+	a, err := NewSIV(key, len(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := a.SealVector(nil, nonce, pt, ad)
+	if !bytes.Equal(ct, expected) {
+		t.Errorf("SealVector: expected: %x got %x\n", expected, ct)
+	}
+	out, err := a.OpenVector(nil, nonce, ct, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, pt) {
+		t.Errorf("OpenVector: expected: %x got %x\n", pt, out)
+	}
+	if _, err := a.OpenVector(nil, nonce, ct, ad[:1]); err == nil {
+		t.Error("OpenVector: missing component accepted")
+	}
+	if _, err := a.OpenVector(nil, nonce, ct, [][]byte{ad[1], ad[0]}); err == nil {
+		t.Error("OpenVector: reordered components accepted")
+	}
+
+	// A single component is the same as Seal.
+	if !bytes.Equal(a.SealVector(nil, nonce, pt, ad[:1]), a.Seal(nil, nonce, pt, ad[0])) {
+		t.Error("SealVector with one component differs from Seal")
+	}
+	if _, err := a.OpenVector(nil, nonce, ct, make([][]byte, MaxSIVComponents)); err == nil {
+		t.Error("OpenVector: too many components accepted")
+	}
+}
+
+func TestS2V(t *testing.T) {
+	// RFC 5297 appendix A.1: the synthetic IV is S2V over the header and
+	// plaintext with the first half of the key.
+	key := unhex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0")
+	ad := unhex("101112131415161718191a1b1c1d1e1f2021222324252627")
+	pt := unhex("112233445566778899aabbccddee")
+	expected := unhex("85632d07c6e8f37f950acd320a2ecc93")
+
+	v, err := S2V(key, ad, pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v[:], expected) {
+		t.Errorf("expected: %x got %x\n", expected, v)
+	}
+
+	// With no strings S2V is the MAC of a one-valued block.
+	v, _ = S2V(key)
+	h, _ := New(key)
+	h.Write(unhex("00000000000000000000000000000001"))
+	if !bytes.Equal(v[:], h.Sum(nil)) {
+		t.Errorf("empty S2V: got %x", v)
+	}
+
+	if _, err := S2V(key[:5], pt); err == nil {
+		t.Error("expected error for invalid key")
+	}
+	if _, err := S2V(key, make([][]byte, MaxSIVComponents+2)...); err == nil {
+		t.Error("expected error for too many components")
+	}
+}
@@ -0,0 +1,192 @@
+package cmac
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+)
+
+// tagWriter MACs everything written through it and hands the tag to emit
+// on Close.
+type tagWriter struct {
+	w io.Writer
+	// zw, under WithGzip, compresses into the underlying writer; w is zw
+	// then.
+	zw     *gzip.Writer
+	h      hash.Hash
+	emit   func(tag []byte) error
+	closed bool
+	// n counts the bytes written, which WithMaxSize limits to max if it
+	// is positive.
+	n, max int64
+}
+
+// NewWriter returns a WriteCloser that writes to w and MACs everything
+// written with AES-CMAC under key. Close appends the 16-byte tag to w and
+// then closes w. NewReader strips and checks the tag again. With
+// WithMaxSize, writes beyond the limit fail without reaching w. With
+// WithGzip, the data is gzip-compressed on its way to w, and the tag
+// follows the compressed stream.
+func NewWriter(w io.WriteCloser, key []byte, opts ...StreamOption) (io.WriteCloser, error) {
+	return newTagWriter(w, key, opts, func(tag []byte) error {
+		if _, err := w.Write(tag); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// NewTagWriter is like NewWriter but passes the tag to fn on Close instead
+// of appending it, for formats that carry the tag elsewhere, such as in a
+// header or a separate file. It does not close w.
+func NewTagWriter(w io.Writer, key []byte, fn func(tag []byte) error, opts ...StreamOption) (io.WriteCloser, error) {
+	if fn == nil {
+		return nil, errors.New("cmac: nil tag function")
+	}
+	return newTagWriter(w, key, opts, fn)
+}
+
+func newTagWriter(w io.Writer, key []byte, opts []StreamOption, emit func([]byte) error) (*tagWriter, error) {
+	if w == nil {
+		return nil, errors.New("cmac: nil writer")
+	}
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	c := newStreamConfig(opts)
+	t := &tagWriter{w: w, h: h, emit: emit, max: c.maxSize}
+	if c.gzip {
+		t.zw = gzip.NewWriter(w)
+		t.w = t.zw
+	}
+	return t, nil
+}
+
+func (t *tagWriter) Write(b []byte) (int, error) {
+	if t.closed {
+		return 0, errors.New("cmac: write to closed writer")
+	}
+	if t.max > 0 && int64(len(b)) > t.max-t.n {
+		return 0, errors.New("cmac: message size limit exceeded")
+	}
+	// MAC exactly what reached w, so that the tag matches the output even
+	// after a short write.
+	n, err := t.w.Write(b)
+	t.n += int64(n)
+	if _, herr := t.h.Write(b[:n]); herr != nil && err == nil {
+		err = herr
+	}
+	return n, err
+}
+
+func (t *tagWriter) Close() error {
+	if t.closed {
+		return errors.New("cmac: writer already closed")
+	}
+	t.closed = true
+	if t.zw != nil {
+		if err := t.zw.Close(); err != nil {
+			return err
+		}
+	}
+	return t.emit(t.h.Sum(nil))
+}
+
+// tagReader returns the data of a stream but its last 16 bytes, which are
+// the tag checked at the end.
+type tagReader struct {
+	r        io.Reader
+	h        hash.Hash
+	data     []byte
+	off, end int
+	eof      bool
+	err      error
+}
+
+// NewReader returns a Reader that reads the output of NewWriter from r:
+// the data without the trailing 16-byte AES-CMAC tag under key, or its
+// decompressed content with WithGzip. Instead of
+// io.EOF, the final Read returns ErrInvalidTag if the tag is missing or
+// does not match.
+//
+// The data is returned before the tag can be checked, so callers must
+// discard everything read, or roll back anything done with it, unless the
+// reader ends with io.EOF. With WithMaxSize, Read returns an error instead
+// of data past the limit.
+func NewReader(r io.Reader, key []byte, opts ...StreamOption) (io.Reader, error) {
+	if r == nil {
+		return nil, errors.New("cmac: nil reader")
+	}
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	c := newStreamConfig(opts)
+	h = c.limit(h)
+	if c.gzip {
+		t := &tagReader{r: r, data: make([]byte, 16+32<<10)}
+		return &gzipTagReader{t: t, h: h}, nil
+	}
+	return &tagReader{r: r, h: h, data: make([]byte, 16+32<<10)}, nil
+}
+
+// tag returns the trailing tag once Read has returned io.EOF, and nil
+// before.
+func (t *tagReader) tag() []byte {
+	if t.err != io.EOF {
+		return nil
+	}
+	return t.data[t.off:t.end]
+}
+
+func (t *tagReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, t.err
+	}
+	for {
+		// Everything but the last 16 bytes seen is data.
+		if avail := t.end - t.off - 16; avail > 0 {
+			if avail > len(p) {
+				avail = len(p)
+			}
+			// MAC the data before handing it out, so that nothing past a
+			// size limit is returned.
+			chunk := t.data[t.off : t.off+avail]
+			if t.h != nil {
+				if _, err := t.h.Write(chunk); err != nil {
+					t.err, t.off = err, t.end
+					return 0, err
+				}
+			}
+			t.off += copy(p, chunk)
+			return avail, nil
+		}
+		if t.err != nil {
+			return 0, t.err
+		}
+		if t.eof {
+			t.err = io.EOF
+			var sum [16]byte
+			if t.end-t.off != 16 {
+				t.err = ErrInvalidTag
+			} else if t.h != nil && subtle.ConstantTimeCompare(t.h.Sum(sum[:0]), t.data[t.off:t.end]) != 1 {
+				t.err = ErrInvalidTag
+			}
+			return 0, t.err
+		}
+
+		n := copy(t.data, t.data[t.off:t.end])
+		t.off, t.end = 0, n
+		n, err := t.r.Read(t.data[t.end:])
+		t.end += n
+		if err == io.EOF {
+			t.eof = true
+		} else if err != nil {
+			t.err = err
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestWriterReader(t *testing.T) {
+	tv := nistvectors[0]
+	for _, c := range tv.cases {
+		var out closeBuffer
+		w, err := NewWriter(&out, tv.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(w, iotest.HalfReader(bytes.NewReader(c.msg))); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !out.closed {
+			t.Error("underlying writer not closed")
+		}
+		if want := append(append([]byte(nil), c.msg...), c.mac...); !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("got %x, want %x", out.Bytes(), want)
+		}
+		if _, err := w.Write([]byte{0}); err == nil {
+			t.Error("write after Close succeeded")
+		}
+
+		r, err := NewReader(bytes.NewReader(out.Bytes()), tv.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := iotest.TestReader(r, c.msg); err != nil {
+			t.Errorf("%d bytes: %v", len(c.msg), err)
+		}
+		r, _ = NewReader(iotest.OneByteReader(bytes.NewReader(out.Bytes())), tv.key)
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, c.msg) {
+			t.Errorf("one byte at a time: got %x, %v", got, err)
+		}
+	}
+}
+
+func TestReaderInvalidTag(t *testing.T) {
+	tv := nistvectors[0]
+	sealed := append(append([]byte(nil), nistmsg[:40]...), tv.cases[2].mac...)
+	for name, b := range map[string][]byte{
+		"tampered":  append(append([]byte(nil), sealed[:len(sealed)-1]...), sealed[len(sealed)-1]^1),
+		"truncated": sealed[:len(sealed)-1],
+		"short":     sealed[:10],
+		"empty":     nil,
+	} {
+		r, _ := NewReader(bytes.NewReader(b), tv.key)
+		if _, err := io.ReadAll(r); err != ErrInvalidTag {
+			t.Errorf("%s: got %v, want ErrInvalidTag", name, err)
+		}
+	}
+
+	fail := errors.New("read failed")
+	r, _ := NewReader(iotest.ErrReader(fail), tv.key)
+	if _, err := io.ReadAll(r); err != fail {
+		t.Errorf("got %v, want the read error", err)
+	}
+}
+
+func TestTagWriter(t *testing.T) {
+	tv := nistvectors[0]
+	var out bytes.Buffer
+	var tag []byte
+	w, err := NewTagWriter(&out, tv.key, func(b []byte) error {
+		tag = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(nistmsg[:40])
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tag, tv.cases[2].mac) || !bytes.Equal(out.Bytes(), nistmsg[:40]) {
+		t.Errorf("got tag %x and output %x", tag, out.Bytes())
+	}
+	if err := w.Close(); err == nil {
+		t.Error("second Close succeeded")
+	}
+
+	if _, err := NewTagWriter(&out, tv.key, nil); err == nil {
+		t.Error("accepted a nil tag function")
+	}
+}
+
+func TestStreamMaxSize(t *testing.T) {
+	tv := nistvectors[0]
+	var out closeBuffer
+	w, _ := NewWriter(&out, tv.key, WithMaxSize(64))
+	if _, err := w.Write(nistmsg[:40]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(nistmsg[:25]); err == nil {
+		t.Error("write past the size limit accepted")
+	}
+	if out.Len() != 40 {
+		t.Errorf("rejected write reached the writer: %d bytes", out.Len())
+	}
+	w.Write(nistmsg[40:])
+	w.Close()
+
+	r, _ := NewReader(bytes.NewReader(out.Bytes()), tv.key, WithMaxSize(64))
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, nistmsg) {
+		t.Errorf("at the limit: got %x, %v", got, err)
+	}
+	r, _ = NewReader(bytes.NewReader(out.Bytes()), tv.key, WithMaxSize(63))
+	got, err := io.ReadAll(r)
+	if err == nil || err == ErrInvalidTag {
+		t.Errorf("past the limit: got %v, want the size error", err)
+	}
+	if len(got) > 63 {
+		t.Errorf("reader returned %d bytes past a 63-byte limit", len(got))
+	}
+	if _, err := r.Read(make([]byte, 1)); err == nil {
+		t.Error("reader recovered after the size limit")
+	}
+
+	tw, _ := NewTagWriter(io.Discard, tv.key, func([]byte) error { return nil }, WithMaxSize(1))
+	if _, err := tw.Write(nistmsg[:2]); err == nil {
+		t.Error("NewTagWriter ignored the size limit")
+	}
+}
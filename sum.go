@@ -0,0 +1,203 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// Sum returns the AES-CMAC tag of msg under key, subject to the package
+// Policy set with SetPolicy. It is a shorthand for New, Write and Sum for
+// callers computing a single tag.
+//
+// Sum keeps the setup of the last few keys it was given, so it does not
+// allocate for a key it has recently seen. The cache finds keys by a CMAC
+// under a random per-process key, never by the key itself, and wipes the
+// subkeys of an entry when it is evicted; the AES key schedule inside the
+// evicted cipher is left to the garbage collector. Callers that must
+// control how long key material lives should use a Keyed, or New and
+// Close, instead.
+func Sum(key, msg []byte) ([16]byte, error) {
+	p := currentPolicy()
+	if len(key) == 0 {
+		return [16]byte{}, errors.New("cmac: empty key")
+	}
+	if err := p.checkKeySize(len(key)); err != nil {
+		return [16]byte{}, err
+	}
+	return keys.sum(key, msg, p)
+}
+
+// SumWithCipher returns the CMAC tag of msg using c, subject to the package
+// Policy set with SetPolicy. For ciphers with an 8-byte block only the
+// first 8 bytes of the result are used; the rest is zero.
+//
+// SumWithCipher does not allocate. It keeps nothing of c once it returns,
+// so it processes msg block by block; callers MACing long messages with a
+// long-lived cipher get the faster multi-block path from NewKeyedWithCipher.
+func SumWithCipher(c cipher.Block, msg []byte) ([16]byte, error) {
+	return sum(c, msg, 0, currentPolicy())
+}
+
+// scratch is the working state of the one-shot functions. Anything handed
+// to a cipher escapes, so keeping it on the stack would cost allocations on
+// every call; instead it is pooled.
+type scratch struct {
+	State
+	tag [16]byte
+}
+
+var scratchPool = sync.Pool{New: func() interface{} { return new(scratch) }}
+
+// release clears s and returns it to the pool.
+func (s *scratch) release() {
+	*s = scratch{}
+	scratchPool.Put(s)
+}
+
+// cbcPool hands out CBC caches over the cipher of one long-lived owner,
+// such as a Keyed, so that its one-shot sums don't allocate. The pooled
+// caches only ever hold the cipher of their owner, never one passed to a
+// single call.
+type cbcPool struct {
+	p sync.Pool
+}
+
+// get returns a cache, or nil for blocks to allocate one.
+func (p *cbcPool) get() *cbcCache {
+	c, _ := p.p.Get().(*cbcCache)
+	return c
+}
+
+// put clears the ciphertext and chaining value left in c and pools it.
+func (p *cbcPool) put(c *cbcCache) {
+	if c != nil {
+		c.reset()
+		p.p.Put(c)
+	}
+}
+
+// sum computes the tag of msg. If bits is not zero, only the bits most
+// significant bits of the last byte of msg are part of the message.
+func sum(c cipher.Block, msg []byte, bits int, p *Policy) ([16]byte, error) {
+	var tag [16]byte
+	if p != nil && p.MaxMessageSize > 0 && int64(len(msg)) > p.MaxMessageSize {
+		return tag, errors.New("cmac: message size limit exceeded")
+	}
+
+	s := scratchPool.Get().(*scratch)
+	defer s.release()
+	if err := s.Init(c); err != nil {
+		return tag, err
+	}
+	if err := p.checkBlockSize(s.size); err != nil {
+		return tag, err
+	}
+	if bits == 0 {
+		s.Write(msg)
+		copy(tag[:], s.Sum(s.tag[:0]))
+	} else {
+		s.Write(msg[:len(msg)-1])
+		copy(tag[:], s.SumBits(s.tag[:0], msg[len(msg)-1], bits))
+	}
+	return tag, nil
+}
+
+// keyCacheSize is the number of keys Sum and Verify keep the setup of.
+const keyCacheSize = 64
+
+// keyCache is a direct-mapped cache of Keyed values for the keys passed
+// to Sum. Sums over a cached Keyed run under the read lock, so an entry is
+// never wiped while in use.
+type keyCache struct {
+	once    sync.Once
+	digest  cipher.Block
+	mu      sync.RWMutex
+	entries [keyCacheSize]keyCacheEntry
+}
+
+type keyCacheEntry struct {
+	digest [16]byte
+	k      *Keyed
+}
+
+var keys keyCache
+
+// init sets up the cipher keying the digests. If the system has no
+// randomness to spare the cache stays disabled.
+func (c *keyCache) init() {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return
+	}
+	c.digest, _ = aes.NewCipher(key[:])
+}
+
+// slot returns the digest of key and the entry it maps to.
+func (c *keyCache) slot(key []byte) ([16]byte, *keyCacheEntry, error) {
+	d, err := sum(c.digest, key, 0, nil)
+	if err != nil {
+		return d, nil, err
+	}
+	return d, &c.entries[binary.LittleEndian.Uint32(d[:])%keyCacheSize], nil
+}
+
+// sum computes the tag of msg under key through the cache. key has been
+// checked against p.
+func (c *keyCache) sum(key, msg []byte, p *Policy) ([16]byte, error) {
+	c.once.Do(c.init)
+	if c.digest == nil {
+		ac, err := aes.NewCipher(key)
+		if err != nil {
+			return [16]byte{}, err
+		}
+		return sum(ac, msg, 0, p)
+	}
+	d, e, err := c.slot(key)
+	if err != nil {
+		return d, err
+	}
+
+	c.mu.RLock()
+	if e.k != nil && e.k.p == p && subtle.ConstantTimeCompare(e.digest[:], d[:]) == 1 {
+		tag, err := e.k.Sum(msg)
+		c.mu.RUnlock()
+		return tag, err
+	}
+	c.mu.RUnlock()
+
+	ac, err := aes.NewCipher(key)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	k, err := newKeyed(ac, p)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	tag, err := k.Sum(msg)
+	c.mu.Lock()
+	if e.k != nil {
+		e.k.wipe()
+	}
+	e.digest, e.k = d, k
+	c.mu.Unlock()
+	return tag, err
+}
+
+// Verify reports whether tag is the AES-CMAC tag of msg under key, using a
+// constant-time comparison. Only full-length tags are accepted, and an
+// invalid key or a message refused by the package Policy always fails.
+func Verify(key, msg, tag []byte) bool {
+	t, err := Sum(key, msg)
+	return err == nil && subtle.ConstantTimeCompare(t[:], tag) == 1
+}
+
+// VerifyWithCipher is like Verify for CMAC using c.
+func VerifyWithCipher(c cipher.Block, msg, tag []byte) bool {
+	t, err := SumWithCipher(c, msg)
+	return err == nil && subtle.ConstantTimeCompare(t[:c.BlockSize()], tag) == 1
+}
@@ -0,0 +1,160 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	for i, tv := range nistvectors {
+		c, _ := tv.cipher(tv.key)
+		for j, tc := range tv.cases {
+			tag, err := SumWithCipher(c, tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tag[:len(tc.mac)]; string(got) != string(tc.mac) {
+				t.Errorf("tv[%d] case[%d]: expected %x got %x", i, j, tc.mac, got)
+			}
+			if tv.cipher == nil || len(tc.mac) != 16 {
+				continue
+			}
+			if tag, _ := Sum(tv.key, tc.msg); string(tag[:]) != string(tc.mac) {
+				t.Errorf("tv[%d] case[%d]: Sum expected %x got %x", i, j, tc.mac, tag)
+			}
+		}
+	}
+
+	if _, err := Sum(nil, nil); err == nil {
+		t.Error("expected error for empty key")
+	}
+	SetPolicy(&Policy{MaxMessageSize: 10})
+	defer SetPolicy(nil)
+	if _, err := Sum(make([]byte, 16), make([]byte, 11)); err == nil {
+		t.Error("expected error for message over the policy limit")
+	}
+}
+
+func TestSumAllocs(t *testing.T) {
+	c, _ := aes.NewCipher(make([]byte, 16))
+	msg := make([]byte, 100)
+	if n := testing.AllocsPerRun(100, func() { SumWithCipher(c, msg) }); n != 0 {
+		t.Errorf("SumWithCipher allocates %v times", n)
+	}
+	if raceEnabled {
+		return
+	}
+	key := nistvectors[0].key
+	tag, _ := Sum(key, msg)
+	if n := testing.AllocsPerRun(100, func() { Sum(key, msg) }); n != 0 {
+		t.Errorf("Sum allocates %v times for a cached key", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { Verify(key, msg, tag[:]) }); n != 0 {
+		t.Errorf("Verify allocates %v times for a cached key", n)
+	}
+}
+
+func TestSumKeyCache(t *testing.T) {
+	key := nistvectors[0].key
+	want := nistvectors[0].cases[1].mac
+	Sum(key, nistmsg[:16])
+	e := keys.lookup(key)
+	if e == nil {
+		t.Fatal("key not cached")
+	}
+	k := e.k
+
+	// Fill the cache until the entry is evicted; its subkeys must be wiped.
+	other := make([]byte, 16)
+	for i := 0; keys.lookup(key) != nil; i++ {
+		binary.BigEndian.PutUint64(other, uint64(i))
+		Sum(other, nil)
+	}
+	if k.s.c != nil || k.s.k1 != [16]byte{} || k.s.k2 != [16]byte{} {
+		t.Error("evicted entry not wiped")
+	}
+	if tag, _ := Sum(key, nistmsg[:16]); string(tag[:]) != string(want) {
+		t.Errorf("Sum after eviction: got %x, want %x", tag, want)
+	}
+
+	// An entry made under another Policy is not reused.
+	SetPolicy(&Policy{MaxMessageSize: 10})
+	if _, err := Sum(key, nistmsg[:16]); err == nil {
+		t.Error("cached entry bypassed the policy")
+	}
+	SetPolicy(nil)
+	if tag, _ := Sum(key, nistmsg[:16]); string(tag[:]) != string(want) {
+		t.Errorf("Sum after policy change: got %x, want %x", tag, want)
+	}
+}
+
+// lookup returns the entry caching key, or nil.
+func (c *keyCache) lookup(key []byte) *keyCacheEntry {
+	d, e, err := c.slot(key)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err != nil || e.k == nil || e.digest != d {
+		return nil
+	}
+	return e
+}
+
+func BenchmarkSum64(b *testing.B) {
+	key, msg := make([]byte, 16), make([]byte, 64)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Sum(key, msg)
+		}
+	})
+}
+
+func BenchmarkVerify64(b *testing.B) {
+	key, msg := make([]byte, 16), make([]byte, 64)
+	tag, _ := Sum(key, msg)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Verify(key, msg, tag[:])
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	key := unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex("6bc1bee22e409f96e93d7e117393172a")
+	tag := unhex("070a16b46b4d4144f79bdd9dd04a287c")
+
+	if !Verify(key, msg, tag) {
+		t.Error("valid tag rejected")
+	}
+	if Verify(key, msg[1:], tag) || Verify(key, msg, tag[:8]) || Verify(key[1:], msg, tag) {
+		t.Error("invalid tag accepted")
+	}
+	c, _ := aes.NewCipher(key)
+	if !VerifyWithCipher(c, msg, tag) {
+		t.Error("VerifyWithCipher: valid tag rejected")
+	}
+
+	type verifier interface{ Verify([]byte) bool }
+	h, _ := New(key)
+	h.Write(msg)
+	if !h.(verifier).Verify(tag) || !h.(verifier).Verify(tag) {
+		t.Error("Verify method: valid tag rejected")
+	}
+	h.Write(msg)
+	if h.(verifier).Verify(tag) {
+		t.Error("Verify method: invalid tag accepted")
+	}
+
+	SetPolicy(&Policy{MaxMessageSize: 100})
+	defer SetPolicy(nil)
+	h, _ = New(key)
+	h.Write(msg)
+	if !h.(verifier).Verify(tag) {
+		t.Error("Verify method under policy: valid tag rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package cmac
+
+// Sum16 returns the current MAC as an array, for use as a map key or
+// without slicing. It panics unless the cipher has a 16-byte block. Like
+// Sum, it does not change the underlying state.
+//
+// Hashes returned by New and NewWithCipher have the methods of this file,
+// which can be reached with a type assertion such as to
+// interface{ Sum16() [16]byte }.
+func (s *State) Sum16() [16]byte {
+	var t [16]byte
+	s.SumInto(&t)
+	return t
+}
+
+// SumInto writes the current MAC to dst. It panics unless the cipher has a
+// 16-byte block.
+func (s *State) SumInto(dst *[16]byte) {
+	if s.size != 16 {
+		panic("cmac: SumInto requires a 128-bit block cipher")
+	}
+	s.Sum(dst[:0])
+}
+
+// Sum8 returns the current MAC as an array. It panics unless the cipher
+// has an 8-byte block, such as TDEA.
+func (s *State) Sum8() [8]byte {
+	var t [8]byte
+	s.SumInto8(&t)
+	return t
+}
+
+// SumInto8 writes the current MAC to dst. It panics unless the cipher has
+// an 8-byte block.
+func (s *State) SumInto8(dst *[8]byte) {
+	if s.size != 8 {
+		panic("cmac: SumInto8 requires a 64-bit block cipher")
+	}
+	s.Sum(dst[:0])
+}
+
+// typedSums is the interface of the methods above.
+type typedSums interface {
+	Sum16() [16]byte
+	SumInto(dst *[16]byte)
+	Sum8() [8]byte
+	SumInto8(dst *[8]byte)
+}
+
+// The limited hash of Policy.MaxMessageSize forwards the typed sums to the
+// hash it wraps. They panic if that hash is not CMAC.
+
+func (l *limited) Sum16() [16]byte       { return l.Hash.(typedSums).Sum16() }
+func (l *limited) SumInto(dst *[16]byte) { l.Hash.(typedSums).SumInto(dst) }
+func (l *limited) Sum8() [8]byte         { return l.Hash.(typedSums).Sum8() }
+func (l *limited) SumInto8(dst *[8]byte) { l.Hash.(typedSums).SumInto8(dst) }
@@ -0,0 +1,54 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSumArrays(t *testing.T) {
+	for _, tv := range nistvectors {
+		for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+			h, err := newWithCipher(mustCipher(t, tv), p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Write(tv.cases[2].msg)
+			s := h.(typedSums)
+			want := tv.cases[2].mac
+
+			if len(want) == 16 {
+				sum := s.Sum16()
+				var into [16]byte
+				s.SumInto(&into)
+				if !bytes.Equal(sum[:], want) || into != sum {
+					t.Errorf("got %x and %x, want %x", sum, into, want)
+				}
+				expectPanic(t, "Sum8", func() { s.Sum8() })
+			} else {
+				sum := s.Sum8()
+				var into [8]byte
+				s.SumInto8(&into)
+				if !bytes.Equal(sum[:], want) || into != sum {
+					t.Errorf("got %x and %x, want %x", sum, into, want)
+				}
+				expectPanic(t, "Sum16", func() { s.Sum16() })
+			}
+		}
+	}
+
+	var s State
+	s.Init(mustCipher(t, nistvectors[0]))
+	if n := testing.AllocsPerRun(100, func() { s.Sum16() }); n != 0 {
+		t.Errorf("Sum16 allocates %v times", n)
+	}
+}
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", name)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,65 @@
+package cmac
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapThreshold is the size from which SumFile maps files into memory
+// rather than reading them.
+const mmapThreshold = 1 << 20
+
+// SumFile returns the AES-CMAC tag of the contents of the file at path,
+// subject to the package Policy set with SetPolicy. On platforms with mmap,
+// regular files of 1 MiB or more are mapped into memory and MACed in
+// place; other files, and files that cannot be mapped, are read through a
+// pooled buffer as by SumReader. With WithGzip, the file is decompressed
+// and read, never mapped, and its length is unknown to WithProgress.
+//
+// A mapped file must not be truncated while SumFile runs. If it is, an
+// error is returned instead of crashing the program.
+func SumFile(key []byte, path string, opts ...StreamOption) ([16]byte, error) {
+	var tag [16]byte
+	h, err := New(key)
+	if err != nil {
+		return tag, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return tag, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return tag, err
+	}
+	c := newStreamConfig(opts)
+	total := int64(-1)
+	if fi.Mode().IsRegular() && !c.gzip {
+		total = fi.Size()
+	}
+	if c.maxSize > 0 && total > c.maxSize {
+		return tag, errors.New("cmac: message size limit exceeded")
+	}
+	w, finish := c.writer(c.limit(h), total)
+
+	mapped := false
+	if total >= mmapThreshold {
+		if mapped, err = writeMapped(w, f, total); err != nil {
+			return tag, err
+		}
+	}
+	if !mapped {
+		r, err := c.decompress(f)
+		if err != nil {
+			return tag, err
+		}
+		if _, err := readFrom(w, r); err != nil {
+			return tag, err
+		}
+	}
+	finish()
+	h.Sum(tag[:0])
+	return tag, nil
+}
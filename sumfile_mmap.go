@@ -0,0 +1,39 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cmac
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"golang.org/x/sys/unix"
+)
+
+// writeMapped maps the first size bytes of f and writes them to w. It
+// reports false, and writes nothing, if the file cannot be mapped.
+func writeMapped(w io.Writer, f *os.File, size int64) (ok bool, err error) {
+	if int64(int(size)) != size {
+		return false, nil
+	}
+	b, err := unix.Mmap(int(f.Fd()), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return false, nil
+	}
+	defer unix.Munmap(b)
+
+	// Reading past the end of a file truncated under us raises SIGBUS;
+	// turn it into an error.
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if _, fault := r.(interface{ Addr() uintptr }); !fault {
+				panic(r)
+			}
+			err = errors.New("cmac: file changed while being read")
+		}
+	}()
+	_, err = w.Write(b)
+	return true, err
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package cmac
+
+import (
+	"io"
+	"os"
+)
+
+// writeMapped reports false: files are always read on this platform.
+func writeMapped(w io.Writer, f *os.File, size int64) (bool, error) {
+	return false, nil
+}
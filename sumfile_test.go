@@ -0,0 +1,44 @@
+package cmac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSumFile(t *testing.T) {
+	key := nistvectors[0].key
+	dir := t.TempDir()
+	for _, n := range []int{0, 40, mmapThreshold - 1, mmapThreshold, 3*mmapThreshold + 5} {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		path := filepath.Join(dir, "f")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := SumFile(key, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := Sum(key, data)
+		if got != want {
+			t.Errorf("%d bytes: got %x, want %x", n, got, want)
+		}
+	}
+
+	if _, err := SumFile(key, filepath.Join(dir, "missing")); err == nil {
+		t.Error("no error for a missing file")
+	}
+	if _, err := SumFile(nil, filepath.Join(dir, "f")); err == nil {
+		t.Error("accepted an empty key")
+	}
+
+	// The message size limit also holds for mapped files.
+	SetPolicy(&Policy{MaxMessageSize: mmapThreshold})
+	defer SetPolicy(nil)
+	if _, err := SumFile(key, filepath.Join(dir, "f")); err == nil {
+		t.Error("message size limit not enforced")
+	}
+}
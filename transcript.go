@@ -0,0 +1,112 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"fmt"
+	"strings"
+)
+
+// TranscriptBlock records the processing of one message block.
+type TranscriptBlock struct {
+	// Message is the message block, after padding for the last block.
+	Message []byte
+	// Input is the block passed to the cipher: Message XOR the previous
+	// chaining value, also XORed with K1 or K2 for the last block.
+	Input []byte
+	// Output is the chaining value produced by the cipher.
+	Output []byte
+}
+
+// Transcript is a step-by-step record of a CMAC computation, for debugging
+// mismatches against other implementations.
+type Transcript struct {
+	L      []byte // encryption of the zero block
+	K1, K2 []byte
+	Blocks []TranscriptBlock
+	// Padded reports whether the last block was padded, selecting K2
+	// rather than K1.
+	Padded bool
+	Tag    []byte
+}
+
+// Explain computes the CMAC of msg with c and returns a transcript of all
+// intermediate values. It is slow and keeps key-derived material in memory,
+// so it is meant for debugging only.
+func Explain(c cipher.Block, msg []byte) (*Transcript, error) {
+	var s State
+	if err := s.Init(c); err != nil {
+		return nil, err
+	}
+	bs := s.size
+
+	t := &Transcript{
+		L:  make([]byte, bs),
+		K1: append([]byte(nil), s.k1[:bs]...),
+		K2: append([]byte(nil), s.k2[:bs]...),
+	}
+	c.Encrypt(t.L, t.L)
+
+	n := (len(msg) + bs - 1) / bs
+	if n == 0 {
+		n = 1
+	}
+	t.Padded = len(msg) == 0 || len(msg)%bs != 0
+
+	x := make([]byte, bs)
+	for i := 0; i < n; i++ {
+		b := TranscriptBlock{Message: make([]byte, bs), Input: make([]byte, bs), Output: make([]byte, bs)}
+
+		m := copy(b.Message, msg[i*bs:])
+		last := i == n-1
+		if last && t.Padded {
+			b.Message[m] = 0x80
+		}
+
+		for j := range b.Input {
+			b.Input[j] = b.Message[j] ^ x[j]
+		}
+		if last {
+			k := t.K1
+			if t.Padded {
+				k = t.K2
+			}
+			for j := range b.Input {
+				b.Input[j] ^= k[j]
+			}
+		}
+
+		c.Encrypt(b.Output, b.Input)
+		copy(x, b.Output)
+		t.Blocks = append(t.Blocks, b)
+	}
+
+	t.Tag = x
+	return t, nil
+}
+
+// FirstMismatch compares the chaining values of t with those produced by
+// another implementation and returns the index of the first block whose
+// output differs, or -1 if all given values match. A missing value counts
+// as a mismatch.
+func (t *Transcript) FirstMismatch(outputs [][]byte) int {
+	for i, b := range t.Blocks {
+		if i >= len(outputs) || !bytes.Equal(b.Output, outputs[i]) {
+			return i
+		}
+	}
+	if len(outputs) > len(t.Blocks) {
+		return len(t.Blocks)
+	}
+	return -1
+}
+
+func (t *Transcript) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "L   %x\nK1  %x\nK2  %x\n", t.L, t.K1, t.K2)
+	for i, blk := range t.Blocks {
+		fmt.Fprintf(&b, "M%-2d %x\nI%-2d %x\nC%-2d %x\n", i+1, blk.Message, i+1, blk.Input, i+1, blk.Output)
+	}
+	fmt.Fprintf(&b, "T   %x\n", t.Tag)
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	for i, tv := range nistvectors {
+		c, err := tv.cipher(tv.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, tc := range tv.cases {
+			tr, err := Explain(c, tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(tr.L, tv.c0) || !bytes.Equal(tr.K1, tv.k1) || !bytes.Equal(tr.K2, tv.k2) {
+				t.Errorf("tv[%d,%d]: bad subkeys", i, j)
+			}
+			if !bytes.Equal(tr.Tag, tc.mac) {
+				t.Errorf("tv[%d,%d]: expected: %x got %x\n", i, j, tc.mac, tr.Tag)
+			}
+			if tr.Padded != (len(tc.msg)%c.BlockSize() != 0 || len(tc.msg) == 0) {
+				t.Errorf("tv[%d,%d]: Padded = %v", i, j, tr.Padded)
+			}
+		}
+	}
+}
+
+func TestFirstMismatch(t *testing.T) {
+	tv := nistvectors[0]
+	c, _ := tv.cipher(tv.key)
+	tr, _ := Explain(c, tv.cases[3].msg)
+	if len(tr.Blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(tr.Blocks))
+	}
+
+	var outputs [][]byte
+	for _, b := range tr.Blocks {
+		outputs = append(outputs, append([]byte(nil), b.Output...))
+	}
+	if i := tr.FirstMismatch(outputs); i != -1 {
+		t.Errorf("expected no mismatch, got %d", i)
+	}
+	outputs[2][0] ^= 1
+	if i := tr.FirstMismatch(outputs); i != 2 {
+		t.Errorf("expected mismatch at 2, got %d", i)
+	}
+	if i := tr.FirstMismatch(outputs[:1]); i != 1 {
+		t.Errorf("expected mismatch at 1, got %d", i)
+	}
+	if tr.String() == "" {
+		t.Error("empty String")
+	}
+}
@@ -0,0 +1,41 @@
+package cmac
+
+import "encoding/hex"
+
+// UUID is an RFC 9562 UUID.
+type UUID [16]byte
+
+// NewUUID returns a version 8 UUID derived from data by AES-CMAC under the
+// namespace key. The same key and data always give the same UUID, while
+// without the key UUIDs can neither be predicted nor linked to their data.
+// Six bits of the tag are replaced by the version and variant, leaving 122
+// bits of the MAC.
+func NewUUID(key, data []byte) (UUID, error) {
+	var u UUID
+
+	h, err := New(key)
+	if err != nil {
+		return u, err
+	}
+	h.Write(data)
+	h.Sum(u[:0])
+
+	u[6] = u[6]&0x0f | 0x80
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}
+
+// String returns u in the canonical 8-4-4-4-12 hexadecimal form.
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
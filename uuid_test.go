@@ -0,0 +1,36 @@
+package cmac
+
+import (
+	"testing"
+)
+
+func TestNewUUID(t *testing.T) {
+	key := make([]byte, 16)
+
+	// RFC 4493 example 1: the tag of the empty message is
+	// bb1d6929e95937287fa37d129b756746.
+	u, err := NewUUID(unhex("2b7e151628aed2a6abf7158809cf4f3c"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := u.String(); s != "bb1d6929-e959-8728-bfa3-7d129b756746" {
+		t.Errorf("got %s", s)
+	}
+
+	a, _ := NewUUID(key, []byte("device-1"))
+	b, _ := NewUUID(key, []byte("device-1"))
+	c, _ := NewUUID(key, []byte("device-2"))
+	if a != b {
+		t.Error("UUIDs for equal data differ")
+	}
+	if a == c {
+		t.Error("UUIDs for different data are equal")
+	}
+	if a[6]>>4 != 8 || a[8]>>6 != 2 {
+		t.Errorf("bad version or variant: %s", a)
+	}
+
+	if _, err := NewUUID(key[:5], nil); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
@@ -0,0 +1,61 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Verifier checks a stream against an expected AES-CMAC tag. The data is
+// written to it like to a hash.Hash, and Verify compares the tag in
+// constant time at the end, so that callers never handle the computed tag
+// themselves.
+type Verifier struct {
+	h   hash.Hash
+	tag [16]byte
+}
+
+// NewVerifier returns a Verifier for the AES-CMAC tag expected under key,
+// subject to the package Policy set with SetPolicy. Only full-length,
+// 16-byte tags are accepted. WithMaxSize bounds the data it accepts
+// between resets.
+func NewVerifier(key, expectedTag []byte, opts ...StreamOption) (*Verifier, error) {
+	if len(expectedTag) != 16 {
+		return nil, errors.New("cmac: invalid tag length")
+	}
+	h, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verifier{h: newStreamConfig(opts).limit(h)}
+	copy(v.tag[:], expectedTag)
+	return v, nil
+}
+
+// Write adds more data to the running MAC. It only returns an error if the
+// message exceeds Policy.MaxMessageSize or the limit of WithMaxSize.
+func (v *Verifier) Write(b []byte) (int, error) {
+	return v.h.Write(b)
+}
+
+// ReadFrom implements io.ReaderFrom, as for the hash returned by New.
+func (v *Verifier) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(v.h, r)
+}
+
+// Verify returns ErrInvalidTag unless the expected tag is the MAC of the
+// data written so far. It does not change the running MAC, so it may be
+// called again after more data has been written.
+func (v *Verifier) Verify() error {
+	var t [16]byte
+	if subtle.ConstantTimeCompare(v.h.Sum(t[:0]), v.tag[:]) != 1 {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// Reset starts over with no data written, expecting the same tag.
+func (v *Verifier) Reset() {
+	v.h.Reset()
+}
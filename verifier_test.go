@@ -0,0 +1,63 @@
+package cmac
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestVerifier(t *testing.T) {
+	tv := nistvectors[0]
+	v, err := NewVerifier(tv.key, tv.cases[3].mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(); err != ErrInvalidTag {
+		t.Errorf("empty stream: got %v, want ErrInvalidTag", err)
+	}
+	v.Write(nistmsg[:40])
+	if _, err := io.Copy(v, bytes.NewReader(nistmsg[40:])); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(); err != nil {
+		t.Error(err)
+	}
+	v.Write([]byte{0})
+	if err := v.Verify(); err != ErrInvalidTag {
+		t.Errorf("extra data: got %v, want ErrInvalidTag", err)
+	}
+
+	v.Reset()
+	v.Write(nistmsg)
+	if err := v.Verify(); err != nil {
+		t.Errorf("after Reset: %v", err)
+	}
+
+	if _, err := NewVerifier(tv.key, tv.cases[3].mac[:8]); err == nil {
+		t.Error("accepted a truncated tag")
+	}
+	if _, err := NewVerifier(nil, tv.cases[3].mac); err == nil {
+		t.Error("accepted an empty key")
+	}
+}
+
+func TestVerifierMaxSize(t *testing.T) {
+	tv := nistvectors[0]
+	v, err := NewVerifier(tv.key, tv.cases[3].mac, WithMaxSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Write(nistmsg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Write([]byte{0}); err == nil {
+		t.Error("write past the size limit accepted")
+	}
+	if err := v.Verify(); err != nil {
+		t.Errorf("rejected write changed the MAC: %v", err)
+	}
+	v.Reset()
+	if _, err := io.Copy(v, bytes.NewReader(append(nistmsg, 0))); err == nil {
+		t.Error("ReadFrom past the size limit accepted")
+	}
+}
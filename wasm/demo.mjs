@@ -0,0 +1,27 @@
+// Verifies a CMAC tag with the js/wasm build. Run from this directory with
+//
+//	GOOS=js GOARCH=wasm go build -o cmac.wasm .
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//	node demo.mjs
+//
+// In a browser, load wasm_exec.js with a script tag and fetch cmac.wasm
+// with WebAssembly.instantiateStreaming instead.
+import { readFile } from "node:fs/promises";
+import "./wasm_exec.js";
+
+const hex = (s) => Uint8Array.from(s.match(/../g), (b) => parseInt(b, 16));
+
+const go = new Go();
+const { instance } = await WebAssembly.instantiate(
+  await readFile("cmac.wasm"),
+  go.importObject,
+);
+go.run(instance);
+
+// RFC 4493 example 2.
+const key = hex("2b7e151628aed2a6abf7158809cf4f3c");
+const msg = hex("6bc1bee22e409f96e93d7e117393172a");
+const tag = cmac.sum(key, msg);
+
+console.log(Buffer.from(tag).toString("hex"));
+console.log(cmac.verify(key, msg, hex("070a16b46b4d4144f79bdd9dd04a287c")));
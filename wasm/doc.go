@@ -0,0 +1,20 @@
+//go:build (js && wasm) || wasip1
+
+// Command wasm exports the cmac package to WebAssembly hosts.
+//
+// For browsers and Node.js, build with
+//
+//	GOOS=js GOARCH=wasm go build -o cmac.wasm ./wasm
+//
+// and load it with the wasm_exec.js glue shipped with Go. It installs a
+// global cmac object with sum(key, msg) returning the tag as a Uint8Array
+// and verify(key, msg, tag) returning a boolean; demo.mjs shows its use.
+//
+// For WASI hosts, build a reactor module with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o cmac.wasm ./wasm
+//
+// which exports cmac_alloc and cmac_free to manage buffers in the module's
+// memory, and cmac_sum and cmac_verify taking pointer and length pairs. The
+// module's _initialize export must be called first.
+package main
@@ -0,0 +1,54 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"crypto/subtle"
+	"syscall/js"
+
+	"github.com/joekir/cmac"
+)
+
+func bytesOf(v js.Value) []byte {
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+func sum(key, msg []byte) ([]byte, error) {
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func main() {
+	js.Global().Set("cmac", map[string]interface{}{
+		"sum": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if len(args) != 2 {
+				panic("cmac.sum: expected key and message")
+			}
+			tag, err := sum(bytesOf(args[0]), bytesOf(args[1]))
+			if err != nil {
+				panic(err.Error())
+			}
+			out := js.Global().Get("Uint8Array").New(len(tag))
+			js.CopyBytesToJS(out, tag)
+			return out
+		}),
+		"verify": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if len(args) != 3 {
+				panic("cmac.verify: expected key, message and tag")
+			}
+			tag, err := sum(bytesOf(args[0]), bytesOf(args[1]))
+			if err != nil {
+				panic(err.Error())
+			}
+			return subtle.ConstantTimeCompare(tag, bytesOf(args[2])) == 1
+		}),
+	})
+
+	select {}
+}
@@ -0,0 +1,73 @@
+//go:build wasip1
+
+package main
+
+import (
+	"crypto/subtle"
+	"unsafe"
+
+	"github.com/joekir/cmac"
+)
+
+// allocs keeps buffers handed out by cmac_alloc reachable until
+// cmac_free.
+var allocs = map[unsafe.Pointer][]byte{}
+
+//go:wasmexport cmac_alloc
+func alloc(n int32) unsafe.Pointer {
+	if n <= 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	p := unsafe.Pointer(&b[0])
+	allocs[p] = b
+	return p
+}
+
+//go:wasmexport cmac_free
+func free(p unsafe.Pointer) {
+	delete(allocs, p)
+}
+
+func bytesOf(p unsafe.Pointer, n int32) []byte {
+	if n <= 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(p), n)
+}
+
+func sum(key, msg []byte) ([]byte, bool) {
+	h, err := cmac.New(key)
+	if err != nil {
+		return nil, false
+	}
+	h.Write(msg)
+	return h.Sum(nil), true
+}
+
+// cmac_sum writes the 16-byte tag to tag and returns 0, or -1 if the key
+// is invalid.
+//
+//go:wasmexport cmac_sum
+func wasmSum(key unsafe.Pointer, keylen int32, msg unsafe.Pointer, msglen int32, tag unsafe.Pointer) int32 {
+	t, ok := sum(bytesOf(key, keylen), bytesOf(msg, msglen))
+	if !ok {
+		return -1
+	}
+	copy(bytesOf(tag, 16), t)
+	return 0
+}
+
+// cmac_verify returns 1 if the tag is valid, 0 if it isn't and -1 if the
+// key is invalid.
+//
+//go:wasmexport cmac_verify
+func wasmVerify(key unsafe.Pointer, keylen int32, msg unsafe.Pointer, msglen int32, tag unsafe.Pointer, taglen int32) int32 {
+	t, ok := sum(bytesOf(key, keylen), bytesOf(msg, msglen))
+	if !ok {
+		return -1
+	}
+	return int32(subtle.ConstantTimeCompare(t, bytesOf(tag, taglen)))
+}
+
+func main() {}
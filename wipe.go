@@ -0,0 +1,67 @@
+package cmac
+
+import "runtime"
+
+// Wipe zeroes the subkeys, the chaining value and the buffered input of s
+// and drops its reference to the cipher, so that no key-derived material
+// remains in s once it returns. s must be initialized with Init again
+// before further use.
+//
+// Wipe cannot erase the key schedule held by the cipher.Block itself, nor
+// copies of s made earlier; callers that need those gone must arrange it
+// with their cipher implementation.
+func (s *State) Wipe() {
+	wipe(s.k1[:])
+	wipe(s.k2[:])
+	wipe(s.buf[:])
+	wipe(s.x[:])
+	wipe(s.out[:])
+	s.c, s.size, s.cursor = nil, 0, 0
+	runtime.KeepAlive(s)
+}
+
+// wipe zeroes b. It is not inlined, and b is kept alive until it returns,
+// so that the compiler cannot drop the stores as dead.
+//
+//go:noinline
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// Wipe is State.Wipe, also wiping and dropping the CBC encrypter of the
+// hash, which holds the cipher and the last ciphertext blocks.
+func (m *cmac) Wipe() {
+	m.State.Wipe()
+	if m.cbc != nil {
+		m.cbc.wipe()
+		m.cbc = nil
+	}
+}
+
+// Close wipes the state of the hash as Wipe does. Hashes returned by New,
+// NewWithCipher and NewWithCipherFactory can be closed through a type
+// assertion to io.Closer; they must not be used afterwards. It never
+// returns an error.
+func (m *cmac) Close() error {
+	m.Wipe()
+	m.factory = nil
+	return nil
+}
+
+func (l *limited) Wipe() {
+	if w, ok := l.Hash.(interface{ Wipe() }); ok {
+		w.Wipe()
+	}
+	l.n = 0
+}
+
+func (l *limited) Close() error {
+	if c, ok := l.Hash.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	l.Wipe()
+	return nil
+}
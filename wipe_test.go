@@ -0,0 +1,60 @@
+package cmac
+
+import (
+	"io"
+	"testing"
+)
+
+func TestWipe(t *testing.T) {
+	tv := nistvectors[0]
+	c, err := tv.cipher(tv.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s State
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(nistmsg[:40])
+	s.Wipe()
+	if s != (State{}) {
+		t.Errorf("state not wiped: %+v", s)
+	}
+
+	// A wiped State can be initialized again.
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(nistmsg[:40])
+	if !s.Verify(tv.cases[2].mac) {
+		t.Error("reinitialized State computes the wrong MAC")
+	}
+}
+
+func TestClose(t *testing.T) {
+	for _, p := range []*Policy{nil, {MaxMessageSize: 1 << 20}} {
+		h, err := newAES(nistvectors[0].key, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(nistmsg)
+		c, ok := h.(io.Closer)
+		if !ok {
+			t.Fatalf("%T does not implement io.Closer", h)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		m, ok := h.(*cmac)
+		if l, isLimited := h.(*limited); isLimited {
+			m, ok = l.Hash.(*cmac)
+		}
+		if !ok {
+			t.Fatalf("unexpected hash type %T", h)
+		}
+		if m.State != (State{}) || m.factory != nil {
+			t.Errorf("hash not wiped: %+v", m.State)
+		}
+	}
+}
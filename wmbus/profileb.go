@@ -0,0 +1,67 @@
+package wmbus
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// filler is the idle filler byte of mode 7, which also forms the two
+// verification bytes at the start of the decrypted application data.
+const filler = 0x2f
+
+// Encrypt encrypts the application data of a mode 7 telegram with AES-CBC
+// under Kenc and an all-zero IV. The two 2Fh verification bytes are
+// prepended and the data is padded to a whole number of blocks with 2Fh
+// idle fillers.
+func Encrypt(kenc, plaintext []byte) ([]byte, error) {
+	c, err := aes.NewCipher(kenc)
+	if err != nil {
+		return nil, err
+	}
+	n := (len(plaintext) + 2 + aes.BlockSize - 1) / aes.BlockSize * aes.BlockSize
+	b := make([]byte, n)
+	b[0], b[1] = filler, filler
+	copy(b[2:], plaintext)
+	for i := 2 + len(plaintext); i < n; i++ {
+		b[i] = filler
+	}
+	cipher.NewCBCEncrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(b, b)
+	return b, nil
+}
+
+// Decrypt decrypts mode 7 application data and checks the verification
+// bytes, which it removes. Trailing idle fillers are left in place since
+// they can't be told apart from data; the application layer ignores them.
+func Decrypt(kenc, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("wmbus: encrypted data is not a whole number of blocks")
+	}
+	c, err := aes.NewCipher(kenc)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(b, ciphertext)
+	if b[0] != filler || b[1] != filler {
+		return nil, errors.New("wmbus: wrong key or corrupted data")
+	}
+	return b[2:], nil
+}
+
+// Open authenticates and decrypts a mode 7 telegram with the persistent
+// meter key: it derives the ephemeral keys for the message counter and
+// meter ID, verifies the MAC over afl and the transport layer header tpl,
+// which holds the CI field and configuration up to the encrypted data, and
+// decrypts enc.
+func Open(key []byte, d Direction, counter uint32, id, afl, tpl, enc, mac []byte) ([]byte, error) {
+	ks, err := DeriveKeys(key, d, counter, id)
+	if err != nil {
+		return nil, err
+	}
+	payload := append(append(make([]byte, 0, len(tpl)+len(enc)), tpl...), enc...)
+	if !VerifyFrameMAC(ks.MAC[:], afl, payload, mac) {
+		return nil, errors.New("wmbus: invalid MAC")
+	}
+	return Decrypt(ks.Enc[:], enc)
+}
@@ -0,0 +1,51 @@
+package wmbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	ks, _ := DeriveKeys(meterKey, FromMeter, 1, meterID)
+	data := unhex("0c1427048502046d32371f1502")
+
+	enc, err := Encrypt(ks.Enc[:], data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) != 16 {
+		t.Fatalf("expected one block, got %d bytes", len(enc))
+	}
+	dec, err := Decrypt(ks.Enc[:], enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(dec, data) || dec[len(dec)-1] != 0x2f {
+		t.Errorf("unexpected plaintext %x", dec)
+	}
+
+	if _, err := Decrypt(ks.MAC[:], enc); err == nil {
+		t.Error("decryption with the wrong key passed the verification bytes")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ks, _ := DeriveKeys(meterKey, FromMeter, 7, meterID)
+	afl := unhex("2507000000")
+	tpl := unhex("7a01000025")
+	data := unhex("0c1427048502046d32371f1502")
+	enc, _ := Encrypt(ks.Enc[:], data)
+	mac, _ := FrameMAC(ks.MAC[:], afl, append(append([]byte(nil), tpl...), enc...))
+
+	dec, err := Open(meterKey, FromMeter, 7, meterID, afl, tpl, enc, mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(dec, data) {
+		t.Errorf("unexpected plaintext %x", dec)
+	}
+
+	if _, err := Open(meterKey, FromMeter, 8, meterID, afl, tpl, enc, mac); err == nil {
+		t.Error("telegram accepted with the wrong message counter")
+	}
+}
@@ -0,0 +1,80 @@
+package wmbus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/joekir/cmac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	meterKey = unhex("000102030405060708090a0b0c0d0e0f")
+	meterID  = unhex("78563412")
+)
+
+func TestDeriveKeys(t *testing.T) {
+	ks, err := DeriveKeys(meterKey, FromMeter, 1, meterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Kmac is the CMAC of the explicit derivation input.
+	h, _ := cmac.New(meterKey)
+	h.Write(unhex("01010000007856341207070707070707"))
+	if expected := h.Sum(nil); !bytes.Equal(ks.MAC[:], expected) {
+		t.Errorf("Kmac: expected: %x got %x\n", expected, ks.MAC)
+	}
+
+	h.Reset()
+	h.Write(unhex("00010000007856341207070707070707"))
+	if expected := h.Sum(nil); !bytes.Equal(ks.Enc[:], expected) {
+		t.Errorf("Kenc: expected: %x got %x\n", expected, ks.Enc)
+	}
+
+	other, _ := DeriveKeys(meterKey, ToMeter, 1, meterID)
+	if other.Enc == ks.Enc || other.MAC == ks.MAC {
+		t.Error("keys for both directions are equal")
+	}
+	next, _ := DeriveKeys(meterKey, FromMeter, 2, meterID)
+	if next.Enc == ks.Enc || next.MAC == ks.MAC {
+		t.Error("keys for consecutive counters are equal")
+	}
+
+	if _, err := DeriveKeys(meterKey, FromMeter, 1, meterID[:3]); err == nil {
+		t.Error("expected error for short meter ID")
+	}
+	if _, err := DeriveKeys(meterKey[:8], FromMeter, 1, meterID); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestFrameMAC(t *testing.T) {
+	ks, _ := DeriveKeys(meterKey, FromMeter, 1, meterID)
+	afl := unhex("2501000000")
+	payload := unhex("7a0100002585d5caf6cca123f8a9f3da5c90a1d8")
+
+	mac, err := FrameMAC(ks.MAC[:], afl, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mac) != MACSize {
+		t.Fatalf("MAC length %d", len(mac))
+	}
+	if !VerifyFrameMAC(ks.MAC[:], afl, payload, mac) {
+		t.Error("valid MAC rejected")
+	}
+
+	payload[len(payload)-1] ^= 1
+	if VerifyFrameMAC(ks.MAC[:], afl, payload, mac) {
+		t.Error("MAC over modified payload accepted")
+	}
+}
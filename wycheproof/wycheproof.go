@@ -0,0 +1,138 @@
+// Package wycheproof loads the AES-CMAC test vectors of Project Wycheproof
+// (testvectors/aes_cmac_test.json and the v1 layout of the same file) and
+// runs them against package cmac. Besides regular vectors the corpus has
+// invalid key sizes, truncated tags and modified tags, so it exercises the
+// rejection paths that other vector sets leave out.
+//
+// The vector file is not bundled; point Load at a checkout of
+// github.com/google/wycheproof.
+package wycheproof
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joekir/cmac"
+)
+
+// Results of a test case.
+const (
+	Valid      = "valid"
+	Invalid    = "invalid"
+	Acceptable = "acceptable"
+)
+
+// HexBytes is a byte string hex encoded in JSON.
+type HexBytes []byte
+
+// UnmarshalJSON decodes a hex string.
+func (h *HexBytes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = d
+	return nil
+}
+
+// Test is one test case.
+type Test struct {
+	TcID    int      `json:"tcId"`
+	Comment string   `json:"comment"`
+	Key     HexBytes `json:"key"`
+	Msg     HexBytes `json:"msg"`
+	Tag     HexBytes `json:"tag"`
+	Result  string   `json:"result"`
+	Flags   []string `json:"flags"`
+}
+
+// TestGroup is a group of test cases with the same key and tag size in
+// bits.
+type TestGroup struct {
+	KeySize int    `json:"keySize"`
+	TagSize int    `json:"tagSize"`
+	Type    string `json:"type"`
+	Tests   []Test `json:"tests"`
+}
+
+// File is a parsed vector file.
+type File struct {
+	Algorithm        string `json:"algorithm"`
+	GeneratorVersion string `json:"generatorVersion"`
+	NumberOfTests    int    `json:"numberOfTests"`
+	// Notes describes the flags; its layout differs between versions.
+	Notes      json.RawMessage `json:"notes"`
+	TestGroups []TestGroup     `json:"testGroups"`
+}
+
+// Parse decodes an AES-CMAC vector file.
+func Parse(r io.Reader) (*File, error) {
+	f := new(File)
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, err
+	}
+	if f.Algorithm != "AES-CMAC" {
+		return nil, fmt.Errorf("wycheproof: unexpected algorithm %q", f.Algorithm)
+	}
+	return f, nil
+}
+
+// Load parses the vector file at path.
+func Load(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// Check runs t, a test of a group with the given tag size in bits. Valid
+// tests must produce the tag, invalid tests must be rejected, whether by
+// refusing the key or by a tag mismatch, and acceptable tests may go
+// either way.
+func (t *Test) Check(tagSize int) error {
+	ok := t.verify(tagSize)
+	switch {
+	case t.Result == Valid && !ok:
+		return fmt.Errorf("wycheproof: test %d (%s): valid tag rejected", t.TcID, t.Comment)
+	case t.Result == Invalid && ok:
+		return fmt.Errorf("wycheproof: test %d (%s): invalid tag accepted", t.TcID, t.Comment)
+	case t.Result != Valid && t.Result != Invalid && t.Result != Acceptable:
+		return fmt.Errorf("wycheproof: test %d: unknown result %q", t.TcID, t.Result)
+	}
+	return nil
+}
+
+func (t *Test) verify(tagSize int) bool {
+	n := tagSize / 8
+	if tagSize%8 != 0 || n < 1 || n > 16 || len(t.Tag) != n {
+		return false
+	}
+	tag, err := cmac.Sum(t.Key, t.Msg)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(tag[:n], t.Tag) == 1
+}
+
+// Check runs every test of f and returns the failures.
+func (f *File) Check() []error {
+	var errs []error
+	for _, g := range f.TestGroups {
+		for i := range g.Tests {
+			if err := g.Tests[i].Check(g.TagSize); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,79 @@
+package wycheproof
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A file in the layout of aes_cmac_test.json, with tests modelled on its
+// kinds of cases.
+const sample = `{
+  "algorithm": "AES-CMAC",
+  "generatorVersion": "0.8r12",
+  "numberOfTests": 5,
+  "notes": {},
+  "testGroups": [
+    {"keySize": 128, "tagSize": 128, "type": "MacTest", "tests": [
+      {"tcId": 1, "comment": "empty message", "key": "2b7e151628aed2a6abf7158809cf4f3c", "msg": "",
+       "tag": "bb1d6929e95937287fa37d129b756746", "result": "valid", "flags": []},
+      {"tcId": 2, "comment": "Flipped bit 0 in tag", "key": "2b7e151628aed2a6abf7158809cf4f3c", "msg": "",
+       "tag": "ba1d6929e95937287fa37d129b756746", "result": "invalid", "flags": []}
+    ]},
+    {"keySize": 128, "tagSize": 64, "type": "MacTest", "tests": [
+      {"tcId": 3, "comment": "truncated tag", "key": "2b7e151628aed2a6abf7158809cf4f3c", "msg": "6bc1bee22e409f96e93d7e117393172a",
+       "tag": "070a16b46b4d4144", "result": "valid", "flags": []}
+    ]},
+    {"keySize": 64, "tagSize": 128, "type": "MacTest", "tests": [
+      {"tcId": 4, "comment": "invalid key size", "key": "0001020304050607", "msg": "",
+       "tag": "bb1d6929e95937287fa37d129b756746", "result": "invalid", "flags": []}
+    ]},
+    {"keySize": 128, "tagSize": 128, "type": "MacTest", "tests": [
+      {"tcId": 5, "comment": "tag of the wrong length", "key": "2b7e151628aed2a6abf7158809cf4f3c", "msg": "",
+       "tag": "bb1d6929e95937287fa37d129b7567", "result": "invalid", "flags": []}
+    ]}
+  ]
+}`
+
+func TestSample(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.TestGroups) != 4 || f.NumberOfTests != 5 {
+		t.Fatalf("unexpected file %+v", f)
+	}
+	for _, err := range f.Check() {
+		t.Error(err)
+	}
+
+	// A runner that accepted everything would be caught.
+	f.TestGroups[0].Tests[1].Result = Valid
+	if errs := f.Check(); len(errs) != 1 {
+		t.Errorf("expected one failure, got %v", errs)
+	}
+
+	if _, err := Parse(strings.NewReader(`{"algorithm": "HMACSHA256"}`)); err == nil {
+		t.Error("expected error for another algorithm")
+	}
+	if _, err := Parse(strings.NewReader(`{"algorithm": "AES-CMAC", "testGroups": [{"tests": [{"key": "zz"}]}]}`)); err == nil {
+		t.Error("expected error for bad hex")
+	}
+}
+
+// TestCorpus runs testdata/aes_cmac_test.json if it has been copied from a
+// Wycheproof checkout.
+func TestCorpus(t *testing.T) {
+	path := filepath.Join("testdata", "aes_cmac_test.json")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("no Wycheproof vectors in testdata")
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range f.Check() {
+		t.Error(err)
+	}
+}
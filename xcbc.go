@@ -0,0 +1,56 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"errors"
+	"hash"
+)
+
+// NewXCBC returns a hash.Hash computing AES-XCBC-MAC as specified for
+// IPsec in RFC 3566, with a 128-bit key, subject to the package Policy set
+// with SetPolicy.
+//
+// XCBC is the predecessor of CMAC and differs only in how its keys are
+// derived: the message is chained under E(K, 0x01...) and the last block
+// is masked with E(K, 0x02...) or E(K, 0x03...) instead of L·u or L·u².
+func NewXCBC(key []byte) (hash.Hash, error) {
+	if len(key) != 16 {
+		return nil, errors.New("cmac: AES-XCBC-MAC requires a 128-bit key")
+	}
+	p := currentPolicy()
+	if err := p.checkKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var k [3][16]byte
+	for i := range k {
+		for j := range k[i] {
+			k[i][j] = byte(i + 1)
+		}
+		c.Encrypt(k[i][:], k[i][:])
+	}
+	k1, err := aes.NewCipher(k[0][:])
+	if err != nil {
+		return nil, err
+	}
+
+	m := &cmac{State: State{c: k1, size: 16, k1: k[1], k2: k[2]}}
+	return p.wrap(m), nil
+}
+
+// NewXCBC96 returns a hash.Hash computing AES-XCBC-MAC-96 (RFC 3566):
+// AES-XCBC-MAC truncated to its first 96 bits.
+func NewXCBC96(key []byte) (hash.Hash, error) {
+	if err := currentPolicy().CheckTagSize(Size96); err != nil {
+		return nil, err
+	}
+	h, err := NewXCBC(key)
+	if err != nil {
+		return nil, err
+	}
+	return &truncated{Hash: h, size: Size96}, nil
+}
@@ -0,0 +1,53 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXCBC(t *testing.T) {
+	// RFC 3566 section 4.6.
+	key := unhex("000102030405060708090a0b0c0d0e0f")
+	msg := make([]byte, 34)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	tests := []struct {
+		msg []byte
+		tag string
+	}{
+		{msg[:0], "75f0251d528ac01c4573dfd584d79f29"},
+		{msg[:3], "5b376580ae2f19afe7219ceef172756f"},
+		{msg[:16], "d2a246fa349b68a79998a4394ff7a263"},
+		{msg[:20], "47f51b4564966215b8985c63055ed308"},
+		{msg[:32], "f54f0ec8d2b9f3d36807734bd5283fd4"},
+		{msg[:34], "becbb3bccdb518a30677d5481fb6b4d8"},
+		{make([]byte, 1000), "f0dafee895db30253761103b5d84528f"},
+	}
+
+	h, err := NewXCBC(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h96, err := NewXCBC96(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		expected := unhex(tt.tag)
+		h.Reset()
+		h.Write(tt.msg)
+		if tag := h.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("tv[%d]: expected %x got %x", i, expected, tag)
+		}
+		h96.Reset()
+		h96.Write(tt.msg)
+		if tag := h96.Sum(nil); !bytes.Equal(tag, expected[:Size96]) {
+			t.Errorf("tv[%d]: XCBC-96 expected %x got %x", i, expected[:Size96], tag)
+		}
+	}
+
+	if _, err := NewXCBC(make([]byte, 24)); err == nil {
+		t.Error("expected error for 192-bit key")
+	}
+}
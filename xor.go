@@ -0,0 +1,16 @@
+package cmac
+
+import "encoding/binary"
+
+// xor16 and xor8 set dst to dst XOR src a word at a time. The byte order
+// doesn't matter as long as loads and stores agree; little-endian compiles
+// to plain loads on the common platforms.
+
+func xor16(dst, src *[16]byte) {
+	binary.LittleEndian.PutUint64(dst[:8], binary.LittleEndian.Uint64(dst[:8])^binary.LittleEndian.Uint64(src[:8]))
+	binary.LittleEndian.PutUint64(dst[8:], binary.LittleEndian.Uint64(dst[8:])^binary.LittleEndian.Uint64(src[8:]))
+}
+
+func xor8(dst, src *[8]byte) {
+	binary.LittleEndian.PutUint64(dst[:], binary.LittleEndian.Uint64(dst[:])^binary.LittleEndian.Uint64(src[:]))
+}